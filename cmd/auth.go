@@ -0,0 +1,370 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fynxlabs/ontap/internal/pkg/cache"
+	"github.com/fynxlabs/ontap/internal/pkg/config"
+	"github.com/fynxlabs/ontap/internal/pkg/exitcode"
+	"github.com/fynxlabs/ontap/internal/pkg/http"
+	"github.com/fynxlabs/ontap/internal/pkg/openapi"
+	"github.com/spf13/cobra"
+)
+
+// loginTimeout is how long to wait for the user to complete a browser login
+const loginTimeout = 5 * time.Minute
+
+var (
+	// authCmd represents the auth command
+	authCmd = &cobra.Command{
+		Use:   "auth",
+		Short: "Manage OAuth2 login sessions",
+		Long: `Log in to APIs that use OAuth2, and show or end the stored sessions.
+The API must have an oauth2 section in the config with a client_id.`,
+	}
+
+	// authLoginCmd represents the auth login command
+	authLoginCmd = &cobra.Command{
+		Use:   "login <api-name>",
+		Short: "Log in to an API",
+		Long: `Log in to an API with the OAuth2 authorization code flow (with PKCE) in a browser,
+or with the device authorization flow on machines without a browser.
+The tokens are stored and used for the API's commands until you log out.
+
+Examples:
+  # Log in with a browser
+  ontap auth login my-api
+
+  # Log in on a headless machine
+  ontap auth login my-api --device`,
+		Args: cobra.ExactArgs(1),
+		RunE: runAuthLogin,
+	}
+
+	// authStatusCmd represents the auth status command
+	authStatusCmd = &cobra.Command{
+		Use:   "status [api-name]",
+		Short: "Show stored sessions",
+		Long: `Show the stored OAuth2 sessions for one or all APIs.
+
+Examples:
+  # Show all sessions
+  ontap auth status
+
+  # Show the sessions of an API
+  ontap auth status my-api`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runAuthStatus,
+	}
+
+	// authLogoutCmd represents the auth logout command
+	authLogoutCmd = &cobra.Command{
+		Use:   "logout <api-name>",
+		Short: "Log out of an API",
+		Long: `Revoke and delete the stored OAuth2 sessions of an API.
+Tokens are revoked when a revocation_url is configured.
+
+Examples:
+  # Log out of an API
+  ontap auth logout my-api`,
+		Args: cobra.ExactArgs(1),
+		RunE: runAuthLogout,
+	}
+)
+
+func init() {
+	authLoginCmd.Flags().Bool("device", false, "Use the device authorization flow instead of a browser")
+	authLoginCmd.Flags().Bool("no-browser", false, "Print the login URL instead of opening a browser")
+	authLoginCmd.Flags().String("scheme", "", "Security scheme to log in for (default: the first oauth2 scheme in the spec)")
+
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(authLogoutCmd)
+	rootCmd.AddCommand(authCmd)
+}
+
+// runAuthLogin logs in to an API
+func runAuthLogin(cmd *cobra.Command, args []string) error {
+	apiName := args[0]
+	apiConfig, err := loadAPIConfig(apiName)
+	if err != nil {
+		return err
+	}
+
+	device, _ := cmd.Flags().GetBool("device")
+	noBrowser, _ := cmd.Flags().GetBool("no-browser")
+	schemeName, _ := cmd.Flags().GetString("scheme")
+
+	// Get the OAuth2 settings
+	oauth2, err := oauth2Config(&apiContext{name: apiName, config: apiConfig})
+	if err != nil {
+		return &exitcode.Error{Code: exitcode.Config, Err: err}
+	}
+	if oauth2 == nil {
+		return &exitcode.Error{Code: exitcode.Config, Err: fmt.Errorf("API %s has no oauth2 config", apiName)}
+	}
+	if !http.IsLoginGrant(oauth2.Grant) {
+		return &exitcode.Error{Code: exitcode.Config, Err: fmt.Errorf("API %s uses the %s grant, which needs no login; set grant to authorization_code or device_code", apiName, oauth2.Grant)}
+	}
+
+	// Take the endpoints the config doesn't set from the spec
+	scheme, err := findOAuth2Scheme(apiName, apiConfig, schemeName)
+	if err != nil {
+		return &exitcode.Error{Code: exitcode.Config, Err: err}
+	}
+	settings := oauth2.WithScheme(scheme, nil)
+	if settings.TokenURL == "" {
+		return &exitcode.Error{Code: exitcode.Config, Err: fmt.Errorf("no token URL in the spec; set token_url in the oauth2 config")}
+	}
+
+	provider := http.NewOAuth2Provider(settings, http.NewFileTokenStore(""), nil)
+
+	// Stop waiting for the user on Ctrl-C
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	var token *http.Token
+	if device || settings.Grant == http.GrantDeviceCode {
+		token, err = provider.LoginWithDevice(ctx, func(auth *http.DeviceAuthorization) {
+			fmt.Fprintf(os.Stderr, "To log in, open %s and enter the code %s\n", auth.VerificationURI, auth.UserCode)
+			if auth.VerificationURIComplete != "" {
+				fmt.Fprintf(os.Stderr, "Or open %s\n", auth.VerificationURIComplete)
+			}
+		})
+	} else {
+		ctx, cancel := context.WithTimeout(ctx, loginTimeout)
+		defer cancel()
+
+		token, err = provider.LoginWithBrowser(ctx, func(authURL string) error {
+			if noBrowser {
+				fmt.Fprintf(os.Stderr, "Open this URL in your browser to log in:\n\n  %s\n\n", authURL)
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "Opening the login page in your browser. If it doesn't open, visit:\n\n  %s\n\n", authURL)
+			if err := openBrowser(authURL); err != nil {
+				log.Debug("Failed to open browser", "error", err)
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to log in: %w", err)
+	}
+
+	if token.ExpiresAt.IsZero() {
+		log.Info("Logged in", "api", apiName)
+	} else {
+		log.Info("Logged in", "api", apiName, "expires", token.ExpiresAt.Local().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// runAuthStatus shows the stored sessions
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Get the APIs to show
+	var names []string
+	if len(args) > 0 {
+		if _, ok := cfg.APIs[args[0]]; !ok {
+			return fmt.Errorf("API not found: %s", args[0])
+		}
+		names = []string{args[0]}
+	} else {
+		for name := range cfg.APIs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	store := http.NewFileTokenStore("")
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	found := false
+	for _, name := range names {
+		keys, err := store.List(name)
+		if err != nil {
+			return err
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			token, err := store.Load(name, key)
+			if err != nil || token == nil {
+				log.Warn("Failed to read stored session", "api", name, "session", key, "error", err)
+				continue
+			}
+
+			if !found {
+				fmt.Fprintln(w, "API\tSESSION\tSTATUS\tEXPIRES\tSCOPE")
+				found = true
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, key, sessionStatus(token), sessionExpiry(token), token.Scope)
+		}
+	}
+
+	if !found {
+		log.Info("No stored sessions")
+		return nil
+	}
+	return w.Flush()
+}
+
+// runAuthLogout revokes and deletes the stored sessions of an API
+func runAuthLogout(cmd *cobra.Command, args []string) error {
+	apiName := args[0]
+	apiConfig, err := loadAPIConfig(apiName)
+	if err != nil {
+		return err
+	}
+
+	store := http.NewFileTokenStore("")
+	keys, err := store.List(apiName)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		log.Info("No stored sessions", "api", apiName)
+		return nil
+	}
+
+	// Tokens can only be revoked with the OAuth2 settings of the API
+	var provider *http.OAuth2Provider
+	oauth2, err := oauth2Config(&apiContext{name: apiName, config: apiConfig})
+	if err != nil {
+		log.Warn("Failed to get the oauth2 config, tokens will not be revoked", "error", err)
+	}
+	if oauth2 != nil {
+		provider = http.NewOAuth2Provider(*oauth2, nil, nil)
+	}
+
+	for _, key := range keys {
+		token, err := store.Load(apiName, key)
+		if err != nil {
+			log.Warn("Failed to read stored session", "session", key, "error", err)
+		}
+		if provider != nil && token != nil {
+			if err := provider.Revoke(token); err != nil {
+				log.Warn("Failed to revoke token", "session", key, "error", err)
+			}
+		}
+		if err := store.Delete(apiName, key); err != nil {
+			return err
+		}
+	}
+
+	log.Info("Logged out", "api", apiName, "sessions", len(keys))
+	return nil
+}
+
+// loadAPIConfig loads the config of an API, with its active environment applied
+func loadAPIConfig(apiName string) (config.APIConfig, error) {
+	apiConfig, err := loadRawAPIConfig(apiName)
+	if err != nil {
+		return config.APIConfig{}, err
+	}
+
+	apiConfig, _, err = resolveEnvironment(apiName, apiConfig, loadState())
+	if err != nil {
+		return config.APIConfig{}, &exitcode.Error{Code: exitcode.Config, Err: err}
+	}
+
+	return apiConfig, nil
+}
+
+// findOAuth2Scheme returns the named oauth2 security scheme of an API's spec, or its
+// first oauth2 scheme. The spec is optional when the endpoints are configured, so a
+// spec that fails to load only yields a warning.
+func findOAuth2Scheme(apiName string, apiConfig config.APIConfig, schemeName string) (*openapi.SecurityScheme, error) {
+	// Get the cache TTL
+	ttl := apiConfig.CacheTTL.Duration
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+
+	cacheManager, err := cache.NewLibOpenAPICacheManager("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache manager: %w", err)
+	}
+
+	index, err := loadOpenAPISpec(cacheManager, apiConfig.APISpec, cache.SpecOptions{
+		TTL:          ttl,
+		StaleIfError: apiConfig.StaleIfError,
+	})
+	if err != nil {
+		if schemeName != "" {
+			return nil, fmt.Errorf("failed to load spec for API %s: %w", apiName, err)
+		}
+		log.Warn("Failed to load spec, using the oauth2 config only", "api", apiName, "error", err)
+		return nil, nil
+	}
+
+	if schemeName != "" {
+		scheme, ok := index.SecuritySchemes[schemeName]
+		if !ok || scheme == nil || !strings.EqualFold(scheme.Type, "oauth2") {
+			return nil, fmt.Errorf("no oauth2 security scheme named %s in the spec", schemeName)
+		}
+		return scheme, nil
+	}
+
+	// Sort the scheme names so the same scheme is picked every time
+	names := make([]string, 0, len(index.SecuritySchemes))
+	for name := range index.SecuritySchemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if scheme := index.SecuritySchemes[name]; scheme != nil && strings.EqualFold(scheme.Type, "oauth2") {
+			return scheme, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// sessionStatus returns the status of a stored token
+func sessionStatus(token *http.Token) string {
+	switch {
+	case token.Valid():
+		return "valid"
+	case token.RefreshToken != "":
+		return "expired (refreshable)"
+	default:
+		return "expired"
+	}
+}
+
+// sessionExpiry returns when a stored token expires
+func sessionExpiry(token *http.Token) string {
+	if token.ExpiresAt.IsZero() {
+		return "-"
+	}
+	return token.ExpiresAt.Local().Format(time.RFC3339)
+}
+
+// openBrowser opens a URL in the default browser
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
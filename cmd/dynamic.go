@@ -2,26 +2,53 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	nethttp "net/http"
 	"net/url"
 	"os"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/fynxlabs/ontap/internal/pkg/cache"
 	"github.com/fynxlabs/ontap/internal/pkg/config"
+	"github.com/fynxlabs/ontap/internal/pkg/exitcode"
 	"github.com/fynxlabs/ontap/internal/pkg/http"
 	"github.com/fynxlabs/ontap/internal/pkg/openapi"
 	"github.com/fynxlabs/ontap/internal/pkg/output"
+	"github.com/fynxlabs/ontap/internal/pkg/secrets"
 	"github.com/fynxlabs/ontap/internal/pkg/utils"
-	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/fynxlabs/ontap/internal/pkg/validation"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-// generateDynamicCommands generates dynamic commands for all APIs
-func generateDynamicCommands() error {
+// apiAnnotation is the command annotation that marks a placeholder command for an API
+const apiAnnotation = "ontap.api"
+
+// apiContext holds the API an endpoint command belongs to
+type apiContext struct {
+	// name is the name of the API in the config
+	name string
+
+	// config is the config of the API, with the active environment applied
+	config config.APIConfig
+
+	// env is the name of the active environment, if any
+	env string
+
+	// index is the spec index of the API
+	index *openapi.SpecIndex
+}
+
+// generateDynamicCommands registers a placeholder command for every configured API
+// and builds the full command tree only for the API that is being invoked
+func generateDynamicCommands(args []string) error {
 	// Get the config file path
 	configFile := viper.ConfigFileUsed()
 	if configFile == "" {
@@ -51,68 +78,175 @@ func generateDynamicCommands() error {
 		return nil
 	}
 
-	// Create a cache manager with proper error handling
-	cacheManager, err := cache.NewLibOpenAPICacheManager("")
-	if err != nil {
-		log.Error("Failed to create cache manager", "error", err)
-		return fmt.Errorf("failed to create cache manager: %w", err)
+	// Sort the API names so the placeholders are registered in a stable order
+	names := make([]string, 0, len(cfg.APIs))
+	for name := range cfg.APIs {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	// Add a command for each API
-	for name, apiConfig := range cfg.APIs {
-		log.Debug("Adding command for API", "name", name, "url", apiConfig.URL)
+	// Load the environments selected with 'ontap env use' once for all APIs
+	var state *config.State
+	if slices.ContainsFunc(names, func(name string) bool { return len(cfg.APIs[name].Environments) > 0 }) {
+		state = loadState()
+	}
 
-		// Create a new command
-		apiCmd := &cobra.Command{
-			Use:   name,
-			Short: fmt.Sprintf("Commands for %s API", name),
-			Long:  fmt.Sprintf("Commands for %s API at %s", name, apiConfig.URL),
+	// Add a placeholder command for each API, with its active environment applied
+	apiConfigs := make(map[string]config.APIConfig, len(names))
+	envs := make(map[string]string, len(names))
+	envErrs := make(map[string]error)
+	for _, name := range names {
+		apiConfigs[name], envs[name], envErrs[name] = resolveEnvironment(name, cfg.APIs[name], state)
+		rootCmd.AddCommand(newAPIPlaceholderCommand(name, apiConfigs[name], envs[name]))
+	}
+
+	// Find the API being invoked, if any
+	apiCmd := findInvokedAPICommand(args)
+	if apiCmd == nil {
+		return nil
+	}
+
+	// An unknown environment is reported when the API command is run
+	name := apiCmd.Annotations[apiAnnotation]
+	if err := envErrs[name]; err != nil {
+		apiCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return &exitcode.Error{Code: exitcode.Config, Err: err}
 		}
+		return nil
+	}
 
-		// Add the command to the root command
-		rootCmd.AddCommand(apiCmd)
+	// Load the command tree for the invoked API only
+	api := &apiContext{name: name, config: apiConfigs[name], env: envs[name]}
+	if err := loadAPICommands(apiCmd, api); err != nil {
+		log.Error("Failed to generate commands for API", "api", name, "error", err)
 
-		// Add dynamic commands for the API
-		if err := generateDynamicAPICommands(apiCmd, name, apiConfig, cacheManager); err != nil {
-			log.Error("Failed to generate commands for API", "api", name, "error", err)
-			continue
+		// Surface the error when the API command itself is run
+		apiCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return &exitcode.Error{Code: exitcode.Config, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// newAPIPlaceholderCommand creates a lightweight command for an API whose
+// subcommands are only generated when the API is invoked
+func newAPIPlaceholderCommand(name string, apiConfig config.APIConfig, env string) *cobra.Command {
+	log.Debug("Adding command for API", "name", name, "url", apiConfig.URL, "env", env)
+
+	long := fmt.Sprintf("Commands for %s API", name)
+	if apiConfig.URL != "" {
+		long += fmt.Sprintf(" at %s", apiConfig.URL)
+	}
+	if env != "" {
+		long += fmt.Sprintf(" (environment: %s)", env)
+	}
+	if len(apiConfig.Environments) > 0 {
+		long += fmt.Sprintf("\n\nEnvironments: %s (select with --env)", strings.Join(apiConfig.EnvironmentNames(), ", "))
+	}
+
+	return &cobra.Command{
+		Use:   name,
+		Short: fmt.Sprintf("Commands for %s API", name),
+		Long:  long,
+		Annotations: map[string]string{
+			apiAnnotation: name,
+		},
+	}
+}
+
+// loadState loads the state holding the environments selected with 'ontap env use'.
+// A state that can't be loaded is logged and ignored.
+func loadState() *config.State {
+	state, err := config.LoadState("")
+	if err != nil {
+		log.Warn("Failed to load state", "error", err)
+		return nil
+	}
+	return state
+}
+
+// resolveEnvironment applies the active environment of an API to its config. The
+// environment is selected by --env or ONTAP_ENV, then in the state with 'ontap env
+// use', then by the API's default_env. APIs without environments ignore the selection.
+func resolveEnvironment(apiName string, apiConfig config.APIConfig, state *config.State) (config.APIConfig, string, error) {
+	if len(apiConfig.Environments) == 0 {
+		return apiConfig, "", nil
+	}
+
+	flag, _ := rootCmd.PersistentFlags().GetString("env")
+	env := apiConfig.ActiveEnvironment(apiName, flag, state)
+
+	resolved, err := apiConfig.WithEnvironment(env)
+	if err != nil {
+		return apiConfig, env, fmt.Errorf("API %s: %w", apiName, err)
+	}
+
+	return resolved, env, nil
+}
+
+// findInvokedAPICommand returns the placeholder command of the API targeted by
+// the command line, including help and shell completion requests for it
+func findInvokedAPICommand(args []string) *cobra.Command {
+	// Skip the help and completion commands so the API after them is found
+	for i, arg := range args {
+		if arg == "help" || arg == cobra.ShellCompRequestCmd || arg == cobra.ShellCompNoDescRequestCmd {
+			args = append(args[:i:i], args[i+1:]...)
+			break
+		}
+	}
+
+	// The error is ignored since unknown commands are reported by cobra later
+	cmd, _, _ := rootCmd.Find(args)
+	for ; cmd != nil; cmd = cmd.Parent() {
+		if _, ok := cmd.Annotations[apiAnnotation]; ok {
+			return cmd
 		}
 	}
 
 	return nil
 }
 
+// loadAPICommands loads the spec for an API and adds its commands
+func loadAPICommands(apiCmd *cobra.Command, api *apiContext) error {
+	// Create a cache manager with proper error handling
+	cacheManager, err := cache.NewLibOpenAPICacheManager("")
+	if err != nil {
+		return fmt.Errorf("failed to create cache manager: %w", err)
+	}
+
+	// Add dynamic commands for the API
+	return generateDynamicAPICommands(apiCmd, api, cacheManager)
+}
+
 // generateDynamicAPICommands generates dynamic commands for an API
-func generateDynamicAPICommands(cmd *cobra.Command, apiName string, apiConfig config.APIConfig, cacheManager *cache.LibOpenAPICacheManager) error {
+func generateDynamicAPICommands(cmd *cobra.Command, api *apiContext, cacheManager *cache.LibOpenAPICacheManager) error {
+	apiConfig := api.config
+
 	// Get the cache TTL
 	ttl := apiConfig.CacheTTL.Duration
 	if ttl == 0 {
 		ttl = 24 * time.Hour
 	}
 
-	// Load the OpenAPI spec with proper error handling
-	spec, err := loadOpenAPISpec(cacheManager, apiConfig.APISpec, ttl)
+	// Load the OpenAPI spec index with proper error handling
+	index, err := loadOpenAPISpec(cacheManager, apiConfig.APISpec, cache.SpecOptions{
+		TTL:          ttl,
+		StaleIfError: apiConfig.StaleIfError,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to load spec for API %s: %w", apiName, err)
+		return fmt.Errorf("failed to load spec for API %s: %w", api.name, err)
 	}
+	api.index = index
 
-	// Check if the spec is nil
-	if spec == nil {
-		return fmt.Errorf("failed to load spec for API %s: spec is nil", apiName)
-	}
-
-	// Create a parser
-	parser := openapi.NewLibOpenAPISpecParser()
-
-	// Get the endpoints
-	endpoints, err := parser.GetEndpoints(spec)
-	if err != nil {
-		return fmt.Errorf("failed to get endpoints: %w", err)
+	// Show the spec's servers in the help
+	if len(index.Servers) > 0 {
+		cmd.Long += "\n\n" + describeServers(index.Servers)
 	}
 
 	// Group endpoints by tag
 	taggedEndpoints := make(map[string][]openapi.Endpoint)
-	for _, endpoint := range endpoints {
+	for _, endpoint := range index.Endpoints {
 		// Skip deprecated endpoints
 		if endpoint.Deprecated {
 			continue
@@ -141,7 +275,7 @@ func generateDynamicAPICommands(cmd *cobra.Command, apiName string, apiConfig co
 		// Add a command for each endpoint
 		for _, endpoint := range endpoints {
 			// Create a new command
-			endpointCmd := createEndpointCommand(endpoint, apiConfig)
+			endpointCmd := createEndpointCommand(endpoint, api)
 
 			// Add the command to the tag command
 			tagCmd.AddCommand(endpointCmd)
@@ -151,8 +285,8 @@ func generateDynamicAPICommands(cmd *cobra.Command, apiName string, apiConfig co
 	return nil
 }
 
-// loadOpenAPISpec loads an OpenAPI spec with proper error handling
-func loadOpenAPISpec(cacheManager *cache.LibOpenAPICacheManager, specPath string, ttl time.Duration) (*v3.Document, error) {
+// loadOpenAPISpec loads the index of an OpenAPI spec with proper error handling
+func loadOpenAPISpec(cacheManager *cache.LibOpenAPICacheManager, specPath string, opts cache.SpecOptions) (*openapi.SpecIndex, error) {
 	if cacheManager == nil {
 		return nil, fmt.Errorf("cache manager is nil")
 	}
@@ -162,90 +296,287 @@ func loadOpenAPISpec(cacheManager *cache.LibOpenAPICacheManager, specPath string
 		clearCache(cacheManager)
 	}
 
-	// Try to get the spec from the cache manager
-	spec, err := safeGetSpec(cacheManager, specPath, ttl)
-	if err != nil || spec == nil {
-		log.Error("Failed to get spec from cache", "path", specPath, "error", err)
+	// Get the spec index from the cache manager
+	index, err := cacheManager.GetSpec(specPath, opts)
+	if err != nil {
+		return nil, err
+	}
 
-		// Try to load the spec directly
-		parser := openapi.NewLibOpenAPISpecParser()
-		spec, err = parser.ParseSpec(specPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse spec: %w", err)
+	// Check if the index is nil
+	if index == nil {
+		return nil, fmt.Errorf("spec index is nil")
+	}
+
+	return index, nil
+}
+
+// clearCache clears the cache
+func clearCache(cacheManager *cache.LibOpenAPICacheManager) {
+	if err := cacheManager.Store.Clear(); err != nil {
+		log.Error("Failed to clear cache", "error", err)
+	} else {
+		log.Info("Cache cleared")
+	}
+}
+
+// describeServers describes the servers of a spec and their variables for the help
+func describeServers(servers []openapi.Server) string {
+	var b strings.Builder
+	b.WriteString("Servers (select with --server):")
+	for i, server := range servers {
+		fmt.Fprintf(&b, "\n  %d: %s", i, server.URL)
+		if server.Description != "" {
+			fmt.Fprintf(&b, " (%s)", server.Description)
 		}
+		for _, name := range server.VariableNames() {
+			variable := server.Variables[name]
+			fmt.Fprintf(&b, "\n     {%s} default %q", name, variable.Default)
+			if len(variable.Enum) > 0 {
+				fmt.Fprintf(&b, ", one of %s", strings.Join(variable.Enum, ", "))
+			}
+		}
+	}
+	return b.String()
+}
+
+// resolveBaseURL returns the base URL for an endpoint. --server selects one of the
+// spec's servers, or sets the URL directly. Without it, the configured url is used,
+// and otherwise the first server of the operation, its path, or the document.
+func resolveBaseURL(cmd *cobra.Command, endpoint openapi.Endpoint, api *apiContext) (string, error) {
+	selector, err := cmd.Flags().GetString("server")
+	if err != nil {
+		return "", fmt.Errorf("failed to get server flag: %w", err)
+	}
+	serverVarStrs, err := cmd.Flags().GetStringArray("server-var")
+	if err != nil {
+		return "", fmt.Errorf("failed to get server-var flags: %w", err)
+	}
+	serverVars, err := utils.ParseServerVarFlags(serverVarStrs)
+	if err != nil {
+		return "", err
+	}
+
+	// A URL given with --server is used as-is
+	if strings.Contains(selector, "://") {
+		return selector, nil
+	}
 
-		// Check if the spec is nil
-		if spec == nil {
-			return nil, fmt.Errorf("failed to parse spec: spec is nil")
+	// The configured url takes precedence over the spec's servers
+	if selector == "" && api.config.URL != "" {
+		if len(serverVars) > 0 {
+			log.Warn("Ignoring --server-var since the API has a configured url", "api", api.name, "url", api.config.URL)
 		}
+		return api.config.URL, nil
+	}
+
+	servers := endpoint.Servers
+	if len(servers) == 0 && api.index != nil {
+		servers = api.index.Servers
+	}
+	if len(servers) == 0 {
+		return "", fmt.Errorf("API %s has no base URL: set url in the config, or declare servers in the spec", api.name)
+	}
+
+	server, err := openapi.SelectServer(servers, selector)
+	if err != nil {
+		return "", err
+	}
+	serverURL, err := server.Expand(serverVars)
+	if err != nil {
+		return "", err
+	}
+	serverURL, err = openapi.ResolveServerURL(serverURL, api.config.APISpec)
+	if err != nil {
+		return "", fmt.Errorf("%w; set url in the config for API %s", err, api.name)
+	}
+
+	log.Debug("Using server from the spec", "api", api.name, "url", serverURL)
+	return serverURL, nil
+}
+
+// configureClient sets the default headers, timeout and retries of a client from
+// the API config, with the timeout and retries overridden by the flags
+func configureClient(cmd *cobra.Command, client *http.Client, apiConfig config.APIConfig) error {
+	for name, value := range apiConfig.Headers {
+		client.Headers[name] = value
+	}
+
+	timeout, err := cmd.Flags().GetDuration("timeout")
+	if err != nil {
+		return fmt.Errorf("failed to get timeout flag: %w", err)
+	}
+	if !cmd.Flags().Changed("timeout") {
+		timeout = apiConfig.Timeout.Duration
+	}
+	if timeout < 0 {
+		return &exitcode.Error{Code: exitcode.Validation, Err: fmt.Errorf("timeout must not be negative")}
+	}
+	if timeout > 0 {
+		client.Timeout = timeout
+		client.HTTPClient.Timeout = timeout
+	}
 
-		// Try to cache the spec
-		// Create a cache key
-		cacheKey := generateCacheKey(specPath)
-		if cacheErr := cacheManager.Store.Set(cacheKey, spec, ttl); cacheErr != nil {
-			log.Warn("Failed to cache spec", "error", cacheErr)
+	retries, err := cmd.Flags().GetInt("retries")
+	if err != nil {
+		return fmt.Errorf("failed to get retries flag: %w", err)
+	}
+	if !cmd.Flags().Changed("retries") {
+		retries = apiConfig.Retries
+	}
+	if retries < 0 {
+		return &exitcode.Error{Code: exitcode.Validation, Err: fmt.Errorf("retries must not be negative")}
+	}
+	client.Retry = http.NewRetryPolicy(retries)
+
+	// Set up TLS, the proxy and Unix domain sockets
+	transport := http.TransportConfig{Proxy: apiConfig.Proxy}
+	if apiConfig.TLS != nil {
+		transport.TLS = http.TLSConfig{
+			CAFile:             apiConfig.TLS.CAFile,
+			CertFile:           apiConfig.TLS.CertFile,
+			KeyFile:            apiConfig.TLS.KeyFile,
+			ServerName:         apiConfig.TLS.ServerName,
+			MinVersion:         apiConfig.TLS.MinVersion,
+			InsecureSkipVerify: apiConfig.TLS.InsecureSkipVerify,
 		}
 	}
+	if err := client.ConfigureTransport(transport); err != nil {
+		return &exitcode.Error{Code: exitcode.Config, Err: fmt.Errorf("failed to configure connection: %w", err)}
+	}
 
-	return spec, nil
+	return nil
 }
 
-// safeGetSpec safely gets a spec from the cache manager
-func safeGetSpec(cacheManager *cache.LibOpenAPICacheManager, specPath string, ttl time.Duration) (*v3.Document, error) {
-	var spec *v3.Document
-	var err error
+// idempotencyKey returns the Idempotency-Key to send with a request: the one given
+// with --idempotency-key, or a generated one when asked for with the flag or, for
+// POST and PATCH requests, with the API's idempotency_key setting
+func idempotencyKey(cmd *cobra.Command, method string, apiConfig config.APIConfig) (string, error) {
+	key, err := cmd.Flags().GetString("idempotency-key")
+	if err != nil {
+		return "", fmt.Errorf("failed to get idempotency-key flag: %w", err)
+	}
+	if key == "" && apiConfig.IdempotencyKey && (method == "POST" || method == "PATCH") {
+		key = "auto"
+	}
+	if key == "auto" {
+		return http.NewIdempotencyKey()
+	}
+	return key, nil
+}
 
-	// Use a defer to catch panics
-	defer func() {
-		if r := recover(); r != nil {
-			log.Error("Recovered from panic in GetSpec", "error", r)
-			// Clear the cache
-			clearCache(cacheManager)
-			spec = nil
-			err = fmt.Errorf("panic in GetSpec: %v", r)
+// hasHeader reports whether a header is set, ignoring the case of its name
+func hasHeader(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
 		}
-	}()
+	}
+	return false
+}
 
-	// Try to get the spec
-	spec, err = cacheManager.GetSpec(specPath, ttl)
-	return spec, err
+// headerValue returns the value of a header, whatever its case
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
 }
 
-// clearCache clears the cache
-func clearCache(cacheManager *cache.LibOpenAPICacheManager) {
-	if err := cacheManager.Store.Clear(); err != nil {
-		log.Error("Failed to clear cache", "error", err)
-	} else {
-		log.Info("Cache cleared")
+// pageLimit reports whether the pages of the results are followed, with --all or
+// --max-pages, and the maximum number of pages to fetch (0 for no limit)
+func pageLimit(cmd *cobra.Command) (bool, int, error) {
+	if cmd.Flags().Lookup("all") == nil {
+		return false, 1, nil
+	}
+
+	all, err := cmd.Flags().GetBool("all")
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to get all flag: %w", err)
+	}
+	maxPages, err := cmd.Flags().GetInt("max-pages")
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to get max-pages flag: %w", err)
+	}
+	if maxPages < 0 {
+		return false, 0, &exitcode.Error{Code: exitcode.Validation, Err: fmt.Errorf("--max-pages must not be negative")}
+	}
+
+	if maxPages > 0 {
+		return true, maxPages, nil
+	}
+	if all {
+		return true, 0, nil
 	}
+	return false, 1, nil
 }
 
-// generateCacheKey generates a cache key for a spec path
-func generateCacheKey(specPath string) string {
-	// Use the spec path as the key
-	return specPath
+// paginationFor returns how the results of an endpoint are paginated, from the
+// spec's x-pagination and query parameters, overridden by the API's config
+func paginationFor(endpoint openapi.Endpoint, api *apiContext) openapi.Pagination {
+	pagination := openapi.DetectPagination(endpoint)
+
+	if cfg := api.config.Pagination; cfg != nil {
+		pagination = pagination.Merge(openapi.Pagination{
+			Style:       cfg.Style,
+			ItemsPath:   cfg.ItemsPath,
+			CursorPath:  cfg.CursorPath,
+			CursorParam: cfg.CursorParam,
+			OffsetParam: cfg.OffsetParam,
+			PageParam:   cfg.PageParam,
+			LimitParam:  cfg.LimitParam,
+			Limit:       cfg.Limit,
+		})
+	}
+
+	return pagination
 }
 
 // createEndpointCommand creates a command for an endpoint
-func createEndpointCommand(endpoint openapi.Endpoint, apiConfig config.APIConfig) *cobra.Command {
+func createEndpointCommand(endpoint openapi.Endpoint, api *apiContext) *cobra.Command {
+	var bodyProps []utils.BodyProperty
+
+	// Show the active environment and the operation's own servers in the help
+	long := endpoint.Description
+	if api.env != "" {
+		long += "\n\nEnvironment: " + api.env
+		if api.config.URL != "" {
+			long += fmt.Sprintf(" (%s)", api.config.URL)
+		}
+	}
+	if len(endpoint.Servers) > 0 {
+		long += "\n\n" + describeServers(endpoint.Servers)
+	}
+	long = strings.TrimSpace(long)
+
 	// Create a new command
 	cmd := &cobra.Command{
 		Use:   getCommandUse(endpoint),
 		Short: endpoint.Summary,
-		Long:  endpoint.Description,
+		Long:  long,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return executeEndpoint(cmd, args, endpoint, apiConfig)
+			return executeEndpoint(cmd, args, endpoint, api, bodyProps)
 		},
 	}
 
-	// Add request flags
+	// Add request and pagination flags first, so that parameters of the same name get
+	// prefixed flags
 	utils.AddRequestFlags(cmd)
+	if endpoint.Method == "GET" {
+		utils.AddPaginationFlags(cmd)
+	}
 
 	// Add parameter flags
-	if err := utils.AddParameterFlags(cmd, convertParameters(endpoint.Parameters)); err != nil {
+	if err := utils.AddParameterFlags(cmd, convertParameters(endpoint.Parameters), rootCmd.PersistentFlags()); err != nil {
 		log.Error("Failed to add parameter flags", "endpoint", endpoint.OperationID, "error", err)
 	}
 
+	// Add request body flags
+	if mediaType := bodyMediaType(endpoint.RequestBody); mediaType != nil {
+		bodyProps = utils.AddBodyFlags(cmd, utils.BodyProperties(mediaType.Schema), rootCmd.PersistentFlags())
+	}
+
 	return cmd
 }
 
@@ -275,11 +606,13 @@ func getCommandUse(endpoint openapi.Endpoint) string {
 }
 
 // executeEndpoint executes an endpoint
-func executeEndpoint(cmd *cobra.Command, args []string, endpoint openapi.Endpoint, apiConfig config.APIConfig) error {
+func executeEndpoint(cmd *cobra.Command, args []string, endpoint openapi.Endpoint, api *apiContext, bodyProps []utils.BodyProperty) error {
+	apiConfig := api.config
+
 	// Get the output format
-	outputFormat, err := cmd.Flags().GetString("output")
+	outputFormat, err := resolveOutputFormat(cmd, endpoint, apiConfig)
 	if err != nil {
-		return fmt.Errorf("failed to get output format: %w", err)
+		return err
 	}
 
 	// Get the save path
@@ -288,21 +621,32 @@ func executeEndpoint(cmd *cobra.Command, args []string, endpoint openapi.Endpoin
 		return fmt.Errorf("failed to get save path: %w", err)
 	}
 
-	// Get the extract fields
+	// Get the fields to extract
 	extractStr, err := cmd.Flags().GetString("extract")
 	if err != nil {
 		return fmt.Errorf("failed to get extract fields: %w", err)
 	}
-	var extractFields []string
+	var extractor *output.Extractor
 	if extractStr != "" {
-		extractFields = strings.Split(extractStr, ",")
+		extractor, err = output.NewExtractor(strings.Split(extractStr, ","))
+		if err != nil {
+			return &exitcode.Error{Code: exitcode.Validation, Err: err}
+		}
 	}
 
-	// Get the filter
-	filter, err := cmd.Flags().GetString("filter")
+	// Get the filter, compiled before sending the request so that a mistake in it
+	// doesn't cost a request
+	filterStr, err := cmd.Flags().GetString("filter")
 	if err != nil {
 		return fmt.Errorf("failed to get filter: %w", err)
 	}
+	var filter *output.Filter
+	if filterStr != "" {
+		filter, err = output.NewFilter(filterStr)
+		if err != nil {
+			return &exitcode.Error{Code: exitcode.Validation, Err: err}
+		}
+	}
 
 	// Get the verbose flag
 	verbose, err := cmd.Flags().GetBool("verbose")
@@ -316,19 +660,6 @@ func executeEndpoint(cmd *cobra.Command, args []string, endpoint openapi.Endpoin
 		return fmt.Errorf("failed to get dry run flag: %w", err)
 	}
 
-	// Get the data flag
-	dataStr, err := cmd.Flags().GetString("data")
-	if err != nil {
-		return fmt.Errorf("failed to get data flag: %w", err)
-	}
-	var data interface{}
-	if dataStr != "" {
-		data, err = utils.ParseDataFlag(dataStr)
-		if err != nil {
-			return fmt.Errorf("failed to parse data: %w", err)
-		}
-	}
-
 	// Get the header flags
 	headerStrs, err := cmd.Flags().GetStringArray("header")
 	if err != nil {
@@ -354,144 +685,996 @@ func executeEndpoint(cmd *cobra.Command, args []string, endpoint openapi.Endpoin
 	if err != nil {
 		return fmt.Errorf("failed to get form flags: %w", err)
 	}
-	formData, formFiles, err := utils.ParseFormFlags(formStrs)
-	if err != nil {
-		return fmt.Errorf("failed to parse form data: %w", err)
+	formParts := make([]http.FormPart, 0, len(formStrs))
+	for _, formStr := range formStrs {
+		part, err := http.ParseFormPart(formStr)
+		if err != nil {
+			return fmt.Errorf("failed to parse form data: %w", err)
+		}
+		formParts = append(formParts, part)
 	}
 
 	// Get the auth flag
-	auth, err := cmd.Flags().GetString("auth")
+	authFlag, err := cmd.Flags().GetString("auth")
 	if err != nil {
 		return fmt.Errorf("failed to get auth flag: %w", err)
 	}
-	if auth == "" {
-		auth = apiConfig.Auth
-	}
 
-	// Get the content type flag
+	// Get the content type of the body: the flag, the Content-Type header, or the
+	// preferred content type declared by the operation
 	contentType, err := cmd.Flags().GetString("content-type")
 	if err != nil {
 		return fmt.Errorf("failed to get content type flag: %w", err)
 	}
-	if contentType != "" {
-		headers["Content-Type"] = contentType
+	if contentType == "" {
+		contentType = headerValue(headers, "Content-Type")
+	}
+	if contentType == "" && endpoint.RequestBody != nil {
+		contentType = http.RequestContentType(slices.Sorted(maps.Keys(endpoint.RequestBody.Content)))
 	}
 
-	// Create an HTTP client
-	client := http.NewClient(apiConfig.URL, auth)
-	client.Verbose = verbose
+	// Get the data flag
+	dataStr, err := cmd.Flags().GetString("data")
+	if err != nil {
+		return fmt.Errorf("failed to get data flag: %w", err)
+	}
+	var data interface{}
+	if dataStr != "" {
+		data, err = requestData(dataStr, contentType)
+		if err != nil {
+			return fmt.Errorf("failed to parse data: %w", err)
+		}
+	}
 
-	// Create a request
-	req := &http.Request{
-		Method:      endpoint.Method,
-		Path:        endpoint.Path,
-		QueryParams: url.Values{},
-		Headers:     headers,
-		Body:        data,
-		FormData:    formData,
-		FormFiles:   formFiles,
-		DryRun:      dryRun,
+	// Merge the body flags into the data
+	data, err = utils.BuildBody(cmd.Flags(), bodyProps, data)
+	if err != nil {
+		return err
 	}
 
-	// Add path parameters
-	pathParams := make(map[string]string)
-	for i, param := range endpoint.Parameters {
-		if param.In == "path" && i < len(args) {
-			pathParams[param.Name] = args[i]
-			req.Path = strings.ReplaceAll(req.Path, fmt.Sprintf("{%s}", param.Name), args[i])
+	// Ask for the content types the operation responds with
+	if !hasHeader(headers, "Accept") {
+		if accept := http.AcceptHeader(endpoint.SuccessContentTypes()); accept != "" {
+			headers["Accept"] = accept
 		}
 	}
 
-	// Add query parameters
-	for _, param := range endpoint.Parameters {
-		if param.In == "query" {
-			// Check if the parameter was provided as a flag
-			value, err := utils.GetStringFlagValue(cmd.Flags(), param.Name)
-			if err == nil && value != "" {
-				req.QueryParams.Add(param.Name, value)
-			}
+	// Validate the request against the schema unless disabled
+	noValidate, err := cmd.Flags().GetBool("no-validate")
+	if err != nil {
+		return fmt.Errorf("failed to get no-validate flag: %w", err)
+	}
+	if !noValidate {
+		hasForm := len(formParts) > 0
+		if err := validateRequest(cmd, args, endpoint, queryParams, data, hasForm); err != nil {
+			return err
 		}
 	}
 
-	// Add query parameters from the query flags
-	for k, v := range queryParams {
-		req.QueryParams.Add(k, v)
+	// Get the response validation mode
+	validateMode, err := responseValidationMode(cmd, apiConfig)
+	if err != nil {
+		return err
 	}
 
-	// Execute the request
-	resp, err := client.Execute(req)
+	// Get the statuses that count as failures
+	failOnStr, err := cmd.Flags().GetString("fail-on")
+	if err != nil {
+		return fmt.Errorf("failed to get fail-on flag: %w", err)
+	}
+	failOn, err := exitcode.ParseFailOn(failOnStr)
 	if err != nil {
-		return fmt.Errorf("failed to execute request: %w", err)
+		return err
 	}
 
-	// Check if this is a dry run
-	if dryRun {
-		fmt.Println("Dry run completed. No request was sent.")
-		return nil
+	// Get the number of pages to fetch
+	all, maxPages, err := pageLimit(cmd)
+	if err != nil {
+		return err
 	}
 
-	// Process the response
-	var responseData interface{}
-	if len(resp.Body) > 0 {
-		// Try to parse the response as JSON
-		if err := json.Unmarshal(resp.Body, &responseData); err != nil {
-			// If parsing fails, use the raw response
-			responseData = string(resp.Body)
-		}
+	// Get the base URL from the config or the spec's servers
+	baseURL, err := resolveBaseURL(cmd, endpoint, api)
+	if err != nil {
+		return &exitcode.Error{Code: exitcode.Config, Err: err}
+	}
+	if verbose && api.env != "" {
+		log.Info("Using environment", "api", api.name, "env", api.env, "url", baseURL)
 	}
 
-	// Extract fields if requested
-	if len(extractFields) > 0 {
-		responseData, err = output.ExtractFields(responseData, extractFields)
+	// Create an HTTP client
+	client := http.NewClient(baseURL, "")
+	client.Verbose = verbose
+	if err := configureClient(cmd, client, apiConfig); err != nil {
+		return err
+	}
+
+	// Add an Idempotency-Key header, which also allows retrying non-idempotent requests
+	key, err := idempotencyKey(cmd, endpoint.Method, apiConfig)
+	if err != nil {
+		return err
+	}
+	if key != "" && !hasHeader(headers, http.IdempotencyKeyHeader) {
+		headers[http.IdempotencyKeyHeader] = key
+	}
+
+	// Resolve the auth providers for the operation
+	authProviders, err := resolveAuthProviders(endpoint, api, authFlag, client.HTTPClient)
+	if err != nil {
+		return &exitcode.Error{Code: exitcode.Config, Err: err}
+	}
+
+	// Without auth providers, the type of the auth string is detected by the client
+	if authProviders == nil {
+		client.Auth, err = defaultAuth(api, authFlag)
 		if err != nil {
-			log.Warn("Failed to extract fields", "error", err)
+			return &exitcode.Error{Code: exitcode.Config, Err: err}
 		}
 	}
 
-	// Filter the response if requested
-	if filter != "" {
-		responseData, err = output.FilterData(responseData, filter)
-		if err != nil {
-			log.Warn("Failed to filter response", "error", err)
+	// Name the root element of XML bodies after the schema
+	if http.IsXML(contentType) && endpoint.RequestBody != nil {
+		data = xmlDocument(data, endpoint.RequestBody.Content[contentType])
+	}
+
+	// Send multipart bodies as parts encoded as described by the schema
+	if http.MediaTypeOf(contentType) == http.MediaTypeMultipart && endpoint.RequestBody != nil {
+		encodings := partEncodings(endpoint.RequestBody.Content[contentType])
+		if _, raw := data.([]byte); !raw {
+			parts, err := http.FormParts(data, encodings)
+			if err != nil {
+				return &exitcode.Error{Code: exitcode.Validation, Err: err}
+			}
+			formParts, data = append(parts, formParts...), nil
 		}
+		http.ApplyEncodings(formParts, encodings)
+	}
+
+	// Create a request
+	req := &http.Request{
+		Method:        endpoint.Method,
+		Path:          endpoint.Path,
+		QueryParams:   url.Values{},
+		Headers:       headers,
+		Body:          data,
+		ContentType:   contentType,
+		Parts:         formParts,
+		AuthProviders: authProviders,
+		DryRun:        dryRun,
+	}
+
+	// Add the path, query, header and cookie parameters, serialized in their style
+	if err := addParameters(cmd, req, endpoint, args); err != nil {
+		return &exitcode.Error{Code: exitcode.Validation, Err: err}
+	}
+
+	// Add query parameters from the query flags
+	for k, v := range queryParams {
+		req.QueryParams.Add(k, v)
 	}
 
 	// Create a formatter
-	formatter, err := output.NewFormatter(outputFormat)
+	tableOptions, err := tableOptionsFor(cmd, endpoint, outputFormat, savePath)
+	if err != nil {
+		return err
+	}
+	formatter, err := output.NewFormatter(outputFormat, tableOptions)
 	if err != nil {
 		return fmt.Errorf("failed to create formatter: %w", err)
 	}
 
-	// Write the output
-	if err := output.WriteOutput(responseData, formatter, savePath); err != nil {
-		return fmt.Errorf("failed to write output: %w", err)
+	// Templates are written for successful responses, so errors are rendered as JSON
+	errorFormatter := formatter
+	if template, ok := formatter.(*output.TemplateFormatter); ok {
+		if savePath != "" && savePath != "-" {
+			template.Color = false
+		}
+		errorFormatter = output.NewJSONFormatter(true)
 	}
 
-	return nil
-}
+	out := responseOutput{
+		formatter:      formatter,
+		errorFormatter: errorFormatter,
+		extractor:      extractor,
+		filter:         filter,
+		savePath:       savePath,
+	}
 
-// convertParameters converts openapi.Parameter to utils.Parameter
-func convertParameters(params []openapi.Parameter) []utils.Parameter {
-	var result []utils.Parameter
-	for _, param := range params {
-		// Skip path parameters
-		if param.In == "path" {
-			continue
+	// Stream events, records and downloads as they arrive
+	if !dryRun && !all && shouldStream(endpoint, savePath) {
+		return streamEndpoint(client, req, endpoint, validateMode, failOn, out)
+	}
+
+	// Execute the request, following the next pages with --all
+	paginator := http.NewPaginator(client, req, paginationFor(endpoint, api))
+	paginator.MaxPages = maxPages
+	if all && !dryRun {
+		return writeAllPages(paginator, endpoint, validateMode, failOn, out, verbose)
+	}
+
+	page, err := paginator.Next()
+	if err != nil {
+		return exitcode.RequestError(err)
+	}
+
+	// Check if this is a dry run
+	if dryRun {
+		fmt.Println("Dry run completed. No request was sent.")
+		return nil
+	}
+
+	// Validate the response against the spec
+	responseErr := checkResponse(endpoint, page.Response, validateMode, 0)
+
+	// Render failed responses to stderr and exit with the status class
+	if failOn.Matches(page.Response.StatusCode) {
+		if err := output.WriteError(page.Data, errorFormatter); err != nil {
+			log.Warn("Failed to write error response", "error", err)
+		}
+		return exitcode.StatusError(page.Response.StatusCode)
+	}
+
+	return renderResponse(page.Data, responseErr, out)
+}
+
+// writeAllPages follows the pages of a list and outputs their items as a single list.
+// The items of each page are written as it arrives when the output format allows it;
+// filters, extracted fields, tables, text, templates and CSV with columns taken from the
+// items work on the whole list, which is written once all pages are fetched. A failed
+// page stops the pagination, and the items of the pages before it are still written.
+func writeAllPages(paginator *http.Paginator, endpoint openapi.Endpoint, validateMode string, failOn *exitcode.StatusMatcher, out responseOutput, verbose bool) error {
+	stream := out.filter == nil && out.extractor == nil && output.CanWriteList(out.formatter)
+
+	var list *output.ListWriter
+	var items []interface{}
+	var responseErr, pageErr error
+	pages := 0
+	for {
+		page, err := paginator.Next()
+		if err != nil {
+			pageErr = exitcode.RequestError(err)
+			break
+		}
+		if page == nil {
+			break
+		}
+		resp := page.Response
+
+		// Validate the response against the spec
+		if err := checkResponse(endpoint, resp, validateMode, page.Number); err != nil {
+			responseErr = err
+		}
+
+		// Render failed responses to stderr and stop with the status class
+		if failOn.Matches(resp.StatusCode) {
+			if err := output.WriteError(page.Data, out.errorFormatter); err != nil {
+				log.Warn("Failed to write error response", "error", err)
+			}
+			pageErr = exitcode.StatusError(resp.StatusCode)
+			break
+		}
+
+		pages++
+		if verbose {
+			log.Info("Fetched page", "page", page.Number, "items", len(page.Items))
+		}
+
+		if !stream {
+			items = append(items, page.Items...)
+			continue
+		}
+
+		// Write the items of the page, starting the list with the first page
+		if list == nil {
+			list, err = output.NewListWriter(out.formatter, out.savePath)
+			if err != nil {
+				return err
+			}
+			defer list.Close()
+		}
+		if err := list.Write(page.Items); err != nil {
+			return err
+		}
+	}
+
+	if paginator.More() && pageErr == nil {
+		log.Warn("Stopped at the maximum number of pages; more pages are available", "pages", pages)
+	}
+
+	// Nothing is written when the first page failed
+	if pages == 0 && pageErr != nil {
+		return pageErr
+	}
+
+	if list != nil {
+		if err := list.Close(); err != nil {
+			return err
+		}
+	} else if err := renderResponse(items, responseErr, out); err != nil && pageErr == nil {
+		return err
+	}
+
+	if pageErr != nil {
+		return pageErr
+	}
+	return responseErr
+}
+
+// responseOutput is how the data of a response is extracted, filtered and written
+type responseOutput struct {
+	formatter      output.Formatter
+	errorFormatter output.Formatter
+	extractor      *output.Extractor
+	filter         *output.Filter
+	savePath       string
+}
+
+// checkResponse validates a response against the spec, returning the validation
+// errors in strict mode and logging them otherwise. The logged errors name the page
+// of the response when pages are followed, and page is 0 otherwise.
+func checkResponse(endpoint openapi.Endpoint, resp *http.Response, validateMode string, page int) error {
+	if validateMode == "off" {
+		return nil
+	}
+
+	errs := validation.ValidateResponse(endpoint.Responses, resp.StatusCode, resp.Headers.Get("Content-Type"), resp.Body)
+	if len(errs) == 0 {
+		return nil
+	}
+	if validateMode == "strict" {
+		return errs
+	}
+	for _, e := range errs {
+		keyvals := []interface{}{"pointer", e.Pointer, "error", e.Message}
+		if page > 0 {
+			keyvals = append([]interface{}{"page", page}, keyvals...)
+		}
+		log.Warn("Response does not match the spec", keyvals...)
+	}
+	return nil
+}
+
+// renderResponse extracts fields from the data of a response, filters it and writes it,
+// returning the error of the response
+func renderResponse(responseData interface{}, responseErr error, out responseOutput) error {
+	// Extract fields if requested
+	if out.extractor != nil {
+		extracted, err := out.extractor.Extract(responseData)
+		if err != nil {
+			log.Warn("Failed to extract fields", "error", err)
+		} else {
+			responseData = extracted
+		}
+	}
+
+	// Filter the response if requested. The body of a failed request is output
+	// unfiltered when the filter doesn't apply to it.
+	if out.filter != nil {
+		filtered, _, err := out.filter.Apply(responseData)
+		switch {
+		case err == nil:
+			responseData = filtered
+		case responseErr != nil:
+			log.Warn("Failed to filter response", "error", err)
+		default:
+			return err
+		}
+	}
+
+	// Write the output
+	if err := output.WriteOutput(responseData, out.formatter, out.savePath); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return responseErr
+}
+
+// shouldStream reports whether the response of an endpoint is read as it arrives: when
+// it declares Server-Sent Events or newline-delimited JSON, or a binary body that is
+// saved to a file
+func shouldStream(endpoint openapi.Endpoint, savePath string) bool {
+	for _, contentType := range endpoint.SuccessContentTypes() {
+		switch http.ContentStreamType(contentType) {
+		case http.StreamEvents, http.StreamRecords:
+			return true
+		case http.StreamBinary:
+			if savePath != "" && savePath != "-" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// streamEndpoint executes a request and writes the events or records of the response as
+// they arrive, each extracted, filtered and formatted on its own, or downloads a binary
+// response to the save path. Failed responses and responses that turn out not to be
+// streamed are read and written as a whole.
+func streamEndpoint(client *http.Client, req *http.Request, endpoint openapi.Endpoint, validateMode string, failOn *exitcode.StatusMatcher, out responseOutput) error {
+	resp, err := client.Stream(req)
+	if err != nil {
+		return exitcode.RequestError(err)
+	}
+	defer resp.Body.Close()
+
+	streamType := resp.Type()
+	saving := out.savePath != "" && out.savePath != "-"
+	if failOn.Matches(resp.StatusCode) || streamType == http.StreamNone || (streamType == http.StreamBinary && !saving) {
+		buffered, err := resp.Read()
+		if err != nil {
+			return &exitcode.Error{Code: exitcode.Network, Err: err}
+		}
+		data := http.DecodeBody(buffered.Body, buffered.Headers.Get("Content-Type"))
+
+		responseErr := checkResponse(endpoint, buffered, validateMode, 1)
+		if failOn.Matches(buffered.StatusCode) {
+			if err := output.WriteError(data, out.errorFormatter); err != nil {
+				log.Warn("Failed to write error response", "error", err)
+			}
+			return exitcode.StatusError(buffered.StatusCode)
+		}
+		return renderResponse(data, responseErr, out)
+	}
+
+	// Download binary responses straight to the file
+	if streamType == http.StreamBinary {
+		if _, err := output.Download(resp.Body, resp.ContentLength, out.savePath); err != nil {
+			return &exitcode.Error{Code: exitcode.Network, Err: err}
+		}
+		return nil
+	}
+
+	// Read the events or records
+	var next func() (interface{}, error)
+	if streamType == http.StreamEvents {
+		events := http.NewEventReader(resp.Body)
+		next = func() (interface{}, error) {
+			event, err := events.Next()
+			if err != nil {
+				return nil, err
+			}
+			log.Debug("Received event", "type", event.Type, "id", event.ID)
+			return http.DecodeData(event.Data), nil
+		}
+	} else {
+		next = http.NewRecordReader(resp.Body).Next
+	}
+
+	writer, err := output.NewStreamWriter(out.formatter, out.savePath)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	for {
+		data, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return &exitcode.Error{Code: exitcode.Network, Err: err}
+		}
+
+		// Extract fields and filter each record, skipping the records the filter drops
+		if out.extractor != nil {
+			extracted, err := out.extractor.Extract(data)
+			if err != nil {
+				log.Warn("Failed to extract fields", "error", err)
+			} else {
+				data = extracted
+			}
+		}
+		if out.filter != nil {
+			filtered, ok, err := out.filter.Apply(data)
+			if err != nil {
+				log.Warn("Failed to filter record", "error", err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			data = filtered
+		}
+
+		if err := writer.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+// resolveOutputFormat returns the output format from the flag, or the default output of
+// the operation or the API. A template=<name> format is replaced by the named template
+// of the operation or the API.
+func resolveOutputFormat(cmd *cobra.Command, endpoint openapi.Endpoint, apiConfig config.APIConfig) (string, error) {
+	format, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return "", fmt.Errorf("failed to get output format: %w", err)
+	}
+	if !cmd.Flags().Changed("output") {
+		if operation, ok := apiConfig.Operation(endpoint.OperationID); ok && operation.DefaultOutput != "" {
+			format = operation.DefaultOutput
+		} else if apiConfig.DefaultOutput != "" {
+			format = apiConfig.DefaultOutput
+		}
+	}
+
+	if name, ok := strings.CutPrefix(format, "template="); ok && !strings.HasPrefix(name, "@") {
+		if text, ok := apiConfig.Template(endpoint.OperationID, name); ok {
+			format = "template=" + text
+		}
+	}
+	return format, nil
+}
+
+// tableOptionsFor returns how csv and table output is laid out: the columns and sort
+// order from the flags, and the columns in the order of the response schema
+func tableOptionsFor(cmd *cobra.Command, endpoint openapi.Endpoint, outputFormat, savePath string) (output.TableOptions, error) {
+	var options output.TableOptions
+
+	columns, err := cmd.Flags().GetString("columns")
+	if err != nil {
+		return options, fmt.Errorf("failed to get columns flag: %w", err)
+	}
+	if columns != "" {
+		options.Columns = strings.Split(columns, ",")
+	}
+	options.SortBy, err = cmd.Flags().GetString("sort-by")
+	if err != nil {
+		return options, fmt.Errorf("failed to get sort-by flag: %w", err)
+	}
+	options.Truncate, err = cmd.Flags().GetBool("truncate")
+	if err != nil {
+		return options, fmt.Errorf("failed to get truncate flag: %w", err)
+	}
+
+	format := strings.ToLower(outputFormat)
+	if (columns != "" || options.SortBy != "") && format != "csv" && format != "table" {
+		log.Warn("--columns and --sort-by only apply to csv and table output", "output", outputFormat)
+	}
+
+	// Tables saved to a file aren't limited to the width of the terminal
+	if savePath != "" && savePath != "-" {
+		options.Width = -1
+	}
+
+	// Order the columns of list items before those of the response itself
+	if schema := endpoint.SuccessSchema(); schema != nil {
+		for _, name := range schema.PropertyOrder {
+			if property := schema.Properties[name]; property != nil && property.Items != nil {
+				options.Order = append(options.Order, property.Items.PropertyPaths()...)
+			}
+		}
+		options.Order = append(options.Order, schema.PropertyPaths()...)
+	}
+
+	return options, nil
+}
+
+// responseValidationMode returns the response validation mode from the flag or the API config
+func responseValidationMode(cmd *cobra.Command, apiConfig config.APIConfig) (string, error) {
+	mode, err := cmd.Flags().GetString("validate-response")
+	if err != nil {
+		return "", fmt.Errorf("failed to get validate-response flag: %w", err)
+	}
+	if !cmd.Flags().Changed("validate-response") {
+		mode = apiConfig.ValidateResponse
+	}
+
+	switch mode {
+	case "", "off":
+		return "off", nil
+	case "warn", "strict":
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid response validation mode %q (expected off, warn or strict)", mode)
+	}
+}
+
+// convertParameters converts openapi.Parameter to utils.Parameter
+func convertParameters(params []openapi.Parameter) []utils.Parameter {
+	var result []utils.Parameter
+	for _, param := range params {
+		// Skip path parameters
+		if param.In == "path" {
+			continue
 		}
 
 		// Convert the parameter
-		result = append(result, utils.Parameter{
+		parameter := utils.Parameter{
 			Name:        param.Name,
 			In:          param.In,
 			Description: param.Description,
 			Required:    param.Required,
-			Schema: &utils.ParameterSchema{
+		}
+		if param.Schema != nil {
+			parameter.Schema = &utils.ParameterSchema{
 				Type:    param.Schema.Type,
 				Format:  param.Schema.Format,
 				Default: param.Schema.Default,
 				Enum:    param.Schema.Enum,
-			},
-		})
+			}
+		}
+		result = append(result, parameter)
 	}
 	return result
 }
+
+// bodyMediaType returns the media type of the structured content of a request body:
+// JSON (preferring application/json), or else a form or an XML document, which are
+// encoded from the same data
+func bodyMediaType(requestBody *openapi.RequestBody) *openapi.MediaType {
+	if requestBody == nil {
+		return nil
+	}
+
+	// Prefer application/json over other JSON media types
+	if mediaType, ok := requestBody.Content["application/json"]; ok && mediaType != nil {
+		return mediaType
+	}
+
+	var structured []string
+	for key, mediaType := range requestBody.Content {
+		if mediaType != nil && (http.IsJSON(key) || http.IsXML(key) || isForm(key)) {
+			structured = append(structured, key)
+		}
+	}
+	if contentType := http.RequestContentType(structured); contentType != "" {
+		return requestBody.Content[contentType]
+	}
+
+	return nil
+}
+
+// requestData reads the data flag for a content type. JSON bodies, forms and XML
+// documents are encoded from a JSON document; other bodies, and data that isn't JSON,
+// are sent as they are.
+func requestData(value, contentType string) (interface{}, error) {
+	if contentType == "" || http.IsJSON(contentType) {
+		return utils.ParseDataFlag(value)
+	}
+
+	raw, err := utils.ReadDataFlag(value)
+	if err != nil {
+		return nil, err
+	}
+	if http.IsXML(contentType) || isForm(contentType) {
+		var data interface{}
+		if err := json.Unmarshal(raw, &data); err == nil {
+			return data, nil
+		}
+	}
+	return raw, nil
+}
+
+// isForm reports whether a content type is a URL-encoded or multipart form
+func isForm(contentType string) bool {
+	mediaType := http.MediaTypeOf(contentType)
+	return mediaType == http.MediaTypeForm || mediaType == http.MediaTypeMultipart
+}
+
+// partEncodings returns how the properties of a multipart body are sent as parts: by
+// default, objects as JSON and binary strings as files, unless the encoding of the
+// media type says otherwise
+func partEncodings(mediaType *openapi.MediaType) map[string]http.PartEncoding {
+	if mediaType == nil || mediaType.Schema == nil {
+		return nil
+	}
+
+	encodings := make(map[string]http.PartEncoding)
+	for name, prop := range mediaType.Schema.Properties {
+		if prop == nil {
+			continue
+		}
+		if prop.Type == "array" && prop.Items != nil {
+			prop = prop.Items
+		}
+
+		var encoding http.PartEncoding
+		switch {
+		case prop.Type == "object" || prop.Type == "array":
+			encoding.ContentType = http.MediaTypeJSON
+		case prop.Type == "string" && (prop.Format == "binary" || prop.Format == "base64"):
+			encoding.ContentType, encoding.Binary = http.MediaTypeBinary, true
+		}
+		encodings[name] = encoding
+	}
+
+	for name, enc := range mediaType.Encoding {
+		if enc == nil {
+			continue
+		}
+		encoding := encodings[name]
+		if enc.ContentType != "" {
+			encoding.ContentType = enc.ContentType
+		}
+		for header, schema := range enc.Headers {
+			if schema == nil {
+				continue
+			}
+			value := schema.Default
+			if value == nil {
+				value = schema.Example
+			}
+			if value == nil || strings.EqualFold(header, "Content-Type") {
+				continue
+			}
+			if encoding.Headers == nil {
+				encoding.Headers = make(map[string]string)
+			}
+			encoding.Headers[header] = fmt.Sprintf("%v", value)
+		}
+		encodings[name] = encoding
+	}
+
+	return encodings
+}
+
+// xmlDocument wraps the data of an XML body in an element named after the schema of
+// the media type, unless the data is already a single element of that name
+func xmlDocument(data interface{}, mediaType *openapi.MediaType) interface{} {
+	if data == nil || mediaType == nil || mediaType.Schema == nil || mediaType.Schema.XMLName == "" {
+		return data
+	}
+	if _, raw := data.([]byte); raw {
+		return data
+	}
+
+	name := mediaType.Schema.XMLName
+	if object, ok := data.(map[string]interface{}); ok && len(object) == 1 && object[name] != nil {
+		return data
+	}
+	return map[string]interface{}{name: data}
+}
+
+// resolveAuthProviders returns the auth providers for an endpoint, or nil to fall back to the auth string
+func resolveAuthProviders(endpoint openapi.Endpoint, api *apiContext, authFlag string, httpClient *nethttp.Client) ([]http.AuthProvider, error) {
+	oauth2, err := oauth2Config(api)
+	if err != nil {
+		return nil, err
+	}
+	store := http.NewFileTokenStore("")
+
+	// Resolve the auth from the operation's security requirements, if the spec declares any
+	if endpoint.Security != nil {
+		return http.ResolveAuth(endpoint.Security, api.index.SecuritySchemes, http.Credentials{
+			Schemes:        api.config.Credentials,
+			Default:        api.config.Auth,
+			DefaultCommand: api.config.AuthCommand,
+			Override:       authFlag,
+			OAuth2:         oauth2,
+			TokenStore:     store,
+			HTTPClient:     httpClient,
+		})
+	}
+
+	// Without security information in the spec, OAuth2 needs a configured token URL
+	if oauth2 != nil && authFlag == "" {
+		if oauth2.TokenURL == "" {
+			return nil, fmt.Errorf("the spec declares no oauth2 security scheme; set token_url in the oauth2 config")
+		}
+		return []http.AuthProvider{http.NewOAuth2Provider(*oauth2, store, httpClient)}, nil
+	}
+
+	return nil, nil
+}
+
+// defaultAuth returns the auth string of an API with secret references resolved,
+// running the auth command if no auth is set
+func defaultAuth(api *apiContext, authFlag string) (string, error) {
+	auth := authFlag
+	if auth == "" {
+		auth = api.config.Auth
+	}
+	if auth == "" && api.config.AuthCommand != "" {
+		return secrets.RunCommand(api.config.AuthCommand)
+	}
+	return secrets.Resolve(auth)
+}
+
+// oauth2Config returns the OAuth2 settings of an API with secret references resolved
+func oauth2Config(api *apiContext) (*http.OAuth2Config, error) {
+	cfg := api.config.OAuth2
+	if cfg == nil {
+		return nil, nil
+	}
+
+	// Infer the grant from the configured credentials
+	grant := cfg.Grant
+	if grant == "" {
+		switch {
+		case cfg.RefreshToken != "":
+			grant = http.GrantRefreshToken
+		case cfg.Username != "":
+			grant = http.GrantPassword
+		case cfg.ClientSecret != "":
+			grant = http.GrantClientCredentials
+		default:
+			// Public clients log in as the user
+			grant = http.GrantAuthorizationCode
+		}
+	}
+
+	config := &http.OAuth2Config{
+		API:                    api.name,
+		Grant:                  grant,
+		TokenURL:               cfg.TokenURL,
+		AuthorizationURL:       cfg.AuthorizationURL,
+		DeviceAuthorizationURL: cfg.DeviceAuthorizationURL,
+		RevocationURL:          cfg.RevocationURL,
+		RedirectPort:           cfg.RedirectPort,
+		ClientID:               cfg.ClientID,
+		ClientSecret:           cfg.ClientSecret,
+		ClientAuth:             cfg.ClientAuth,
+		Username:               cfg.Username,
+		Password:               cfg.Password,
+		RefreshToken:           cfg.RefreshToken,
+		Scopes:                 cfg.Scopes,
+		Params:                 cfg.Params,
+	}
+
+	// Resolve the secrets
+	for _, value := range []*string{&config.ClientID, &config.ClientSecret, &config.Username, &config.Password, &config.RefreshToken} {
+		secret, err := secrets.Resolve(*value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve oauth2 config: %w", err)
+		}
+		*value = secret
+	}
+
+	return config, nil
+}
+
+// pathArgs maps the path parameters of an endpoint to the positional arguments
+func pathArgs(endpoint openapi.Endpoint, args []string) map[string]string {
+	values := make(map[string]string)
+	i := 0
+	for _, param := range endpoint.Parameters {
+		if param.In != "path" {
+			continue
+		}
+		if i < len(args) {
+			values[param.Name] = args[i]
+		}
+		i++
+	}
+	return values
+}
+
+// addParameters adds the parameters given as arguments and flags to a request,
+// serialized as described by their style and explode. Header parameters don't replace
+// headers given with -H, and cookie parameters are added to the Cookie header.
+func addParameters(cmd *cobra.Command, req *http.Request, endpoint openapi.Endpoint, args []string) error {
+	pathValues := pathArgs(endpoint, args)
+	var cookies []string
+	for _, param := range endpoint.Parameters {
+		var value interface{}
+		if param.In == "path" {
+			raw, ok := pathValues[param.Name]
+			if !ok {
+				continue
+			}
+			value = raw
+			if param.Schema != nil && param.Schema.Type == "array" {
+				value = strings.Split(raw, ",")
+			}
+		} else {
+			values := parameterFlagValues(cmd, param)
+			if values == nil {
+				continue
+			}
+			value = values[0]
+			if param.Schema != nil && param.Schema.Type == "array" {
+				value = values
+			}
+		}
+
+		// Object parameters are given as JSON objects
+		if param.Schema != nil && param.Schema.Type == "object" {
+			var object map[string]interface{}
+			if err := json.Unmarshal([]byte(value.(string)), &object); err != nil {
+				return fmt.Errorf("invalid value for parameter %s: expected a JSON object", param.Name)
+			}
+			value = object
+		}
+
+		style, explode := http.ParameterStyle(param.In, param.Style, param.Explode)
+		switch param.In {
+		case "path":
+			req.Path = strings.ReplaceAll(req.Path, fmt.Sprintf("{%s}", param.Name), http.PathValue(param.Name, value, style, explode))
+		case "query":
+			http.AddQueryValue(req.QueryParams, param.Name, value, style, explode)
+		case "header":
+			if !hasHeader(req.Headers, param.Name) {
+				req.Headers[param.Name] = http.HeaderValue(value, explode)
+			}
+		case "cookie":
+			cookies = append(cookies, http.CookieValue(param.Name, value, explode))
+		}
+	}
+
+	// Send the cookie parameters after the cookies given with -H
+	if len(cookies) > 0 {
+		name := "Cookie"
+		for key := range req.Headers {
+			if strings.EqualFold(key, name) {
+				name = key
+				cookies = append([]string{req.Headers[key]}, cookies...)
+			}
+		}
+		req.Headers[name] = strings.Join(cookies, "; ")
+	}
+
+	return nil
+}
+
+// validateRequest validates the parameters and body of a request against the endpoint schema
+func validateRequest(cmd *cobra.Command, args []string, endpoint openapi.Endpoint, queryParams map[string]string, body interface{}, hasForm bool) error {
+	var errs validation.Errors
+
+	// Validate the parameters
+	pathValues := pathArgs(endpoint, args)
+	for _, param := range endpoint.Parameters {
+		var values []string
+		switch param.In {
+		case "path":
+			if value, ok := pathValues[param.Name]; ok {
+				values = []string{value}
+				if param.Schema != nil && param.Schema.Type == "array" {
+					values = strings.Split(value, ",")
+				}
+			}
+		default:
+			values = parameterFlagValues(cmd, param)
+			if value, ok := queryParams[param.Name]; ok && param.In == "query" {
+				values = append(values, value)
+			}
+		}
+
+		errs = append(errs, validation.ValidateParameter(param, values)...)
+	}
+
+	// Check that the path has no templates left, including those of undeclared parameters
+	for _, err := range validation.ValidatePath(endpoint.Path, pathValues) {
+		if !slices.ContainsFunc(errs, func(e validation.Error) bool { return e.Pointer == err.Pointer }) {
+			errs = append(errs, err)
+		}
+	}
+
+	// Validate the structured body; bodies sent as they are aren't checked
+	_, raw := body.([]byte)
+	if endpoint.RequestBody != nil && !hasForm {
+		if body == nil {
+			if endpoint.RequestBody.Required {
+				errs = append(errs, validation.Error{Pointer: "/body", Message: "missing required request body"})
+			}
+		} else if mediaType := bodyMediaType(endpoint.RequestBody); mediaType != nil && !raw {
+			errs = append(errs, validation.ValidateValue("/body", body, mediaType.Schema)...)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// parameterFlagValues returns the values given for a parameter flag, if it was set.
+// Flags of other parameters or options of the same name are ignored.
+func parameterFlagValues(cmd *cobra.Command, param openapi.Parameter) []string {
+	flag := utils.ParameterFlag(cmd.Flags(), param.Name, param.In)
+	if flag == nil || !flag.Changed {
+		return nil
+	}
+
+	if flag.Value.Type() == "stringArray" {
+		values, err := cmd.Flags().GetStringArray(flag.Name)
+		if err == nil {
+			return values
+		}
+	}
+
+	return []string{flag.Value.String()}
+}
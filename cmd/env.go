@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/log"
+	"github.com/fynxlabs/ontap/internal/pkg/config"
+	"github.com/fynxlabs/ontap/internal/pkg/exitcode"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// envCmd represents the env command
+	envCmd = &cobra.Command{
+		Use:   "env",
+		Short: "Manage the environments of APIs",
+		Long: `Manage the named environments of APIs, such as dev, staging and prod.
+
+The environment of an API is selected by --env, then ONTAP_ENV, then the one set
+with 'ontap env use', then the API's default_env.`,
+	}
+
+	// envListCmd represents the env list command
+	envListCmd = &cobra.Command{
+		Use:   "list [api]",
+		Short: "List the environments of APIs",
+		Long: `List the environments of APIs, marking the active one with *.
+
+Examples:
+  # List the environments of all APIs
+  ontap env list
+
+  # List the environments of a specific API
+  ontap env list my-api`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runEnvList,
+	}
+
+	// envUseCmd represents the env use command
+	envUseCmd = &cobra.Command{
+		Use:   "use <api> <env>",
+		Short: "Set the default environment of an API",
+		Long: `Set the default environment of an API. The choice is saved next to the config
+file and can be overridden for a single command with --env or ONTAP_ENV.
+
+Examples:
+  # Use the staging environment of my-api by default
+  ontap env use my-api staging`,
+		Args: cobra.ExactArgs(2),
+		RunE: runEnvUse,
+	}
+
+	// envUnsetCmd represents the env unset command
+	envUnsetCmd = &cobra.Command{
+		Use:   "unset <api>",
+		Short: "Clear the default environment of an API set with 'env use'",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runEnvUnset,
+	}
+)
+
+func init() {
+	envCmd.AddCommand(envListCmd)
+	envCmd.AddCommand(envUseCmd)
+	envCmd.AddCommand(envUnsetCmd)
+	rootCmd.AddCommand(envCmd)
+}
+
+// runEnvList lists the environments of the configured APIs
+func runEnvList(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return &exitcode.Error{Code: exitcode.Config, Err: fmt.Errorf("failed to load config: %w", err)}
+	}
+
+	// Get the APIs to show
+	var names []string
+	if len(args) > 0 {
+		if _, ok := cfg.APIs[args[0]]; !ok {
+			return &exitcode.Error{Code: exitcode.Config, Err: fmt.Errorf("API not found: %s", args[0])}
+		}
+		names = []string{args[0]}
+	} else {
+		for name := range cfg.APIs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	state := loadState()
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	found := false
+	for _, name := range names {
+		apiConfig := cfg.APIs[name]
+		if len(apiConfig.Environments) == 0 {
+			continue
+		}
+
+		// An unknown active environment is reported but doesn't hide the others
+		_, active, err := resolveEnvironment(name, apiConfig, state)
+		if err != nil {
+			log.Warn("Invalid active environment", "api", name, "error", err)
+		}
+
+		if !found {
+			fmt.Fprintln(w, "API\tENVIRONMENT\tURL")
+			found = true
+		}
+		for _, envName := range apiConfig.EnvironmentNames() {
+			marker := ""
+			if err == nil && envName == findEnvironmentName(apiConfig, active) {
+				marker = " *"
+			}
+
+			resolved, _ := apiConfig.WithEnvironment(envName)
+			fmt.Fprintf(w, "%s\t%s%s\t%s\n", name, envName, marker, resolved.URL)
+		}
+	}
+
+	if !found {
+		log.Info("No environments configured")
+		return nil
+	}
+	return w.Flush()
+}
+
+// runEnvUse saves the default environment of an API
+func runEnvUse(cmd *cobra.Command, args []string) error {
+	apiName, envName := args[0], args[1]
+
+	apiConfig, err := loadRawAPIConfig(apiName)
+	if err != nil {
+		return err
+	}
+
+	// Check that the environment exists, saving it under its configured name
+	if _, err := apiConfig.WithEnvironment(envName); err != nil {
+		return &exitcode.Error{Code: exitcode.Config, Err: fmt.Errorf("API %s: %w", apiName, err)}
+	}
+	envName = findEnvironmentName(apiConfig, envName)
+
+	state, err := config.LoadState("")
+	if err != nil {
+		return err
+	}
+	if state.Environments == nil {
+		state.Environments = make(map[string]string)
+	}
+	state.Environments[apiName] = envName
+
+	if err := config.SaveState(state, ""); err != nil {
+		return err
+	}
+
+	log.Info("Default environment set", "api", apiName, "env", envName)
+	return nil
+}
+
+// runEnvUnset clears the default environment of an API
+func runEnvUnset(cmd *cobra.Command, args []string) error {
+	state, err := config.LoadState("")
+	if err != nil {
+		return err
+	}
+
+	if _, ok := state.Environments[args[0]]; !ok {
+		log.Info("No default environment set", "api", args[0])
+		return nil
+	}
+	delete(state.Environments, args[0])
+
+	if err := config.SaveState(state, ""); err != nil {
+		return err
+	}
+
+	log.Info("Default environment cleared", "api", args[0])
+	return nil
+}
+
+// loadRawAPIConfig loads the config of an API without applying an environment
+func loadRawAPIConfig(apiName string) (config.APIConfig, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return config.APIConfig{}, &exitcode.Error{Code: exitcode.Config, Err: fmt.Errorf("failed to load config: %w", err)}
+	}
+
+	apiConfig, ok := cfg.APIs[apiName]
+	if !ok {
+		return config.APIConfig{}, &exitcode.Error{Code: exitcode.Config, Err: fmt.Errorf("API not found: %s", apiName)}
+	}
+
+	return apiConfig, nil
+}
+
+// findEnvironmentName returns the configured name of an environment matched case-insensitively
+func findEnvironmentName(apiConfig config.APIConfig, name string) string {
+	for _, envName := range apiConfig.EnvironmentNames() {
+		if envName == name {
+			return envName
+		}
+	}
+	for _, envName := range apiConfig.EnvironmentNames() {
+		if strings.EqualFold(envName, name) {
+			return envName
+		}
+	}
+	return name
+}
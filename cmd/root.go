@@ -8,6 +8,7 @@ import (
 
 	"github.com/charmbracelet/log"
 	"github.com/fynxlabs/ontap/internal/pkg/config"
+	"github.com/fynxlabs/ontap/internal/pkg/exitcode"
 	"github.com/fynxlabs/ontap/internal/pkg/utils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -22,6 +23,9 @@ var BuildTime = "unknown"
 // configFlag is the path to the config file
 var configFlag string
 
+// configErr is the error from loading the config, if any
+var configErr error
+
 var (
 	// rootCmd represents the base command when called without any subcommands
 	rootCmd = &cobra.Command{
@@ -37,14 +41,17 @@ Examples:
   # Use the CLI with your API
   ontap your-api list-users
   ontap your-api create-user --data=@user.json`,
+		SilenceErrors: true,
 	}
 )
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	// Parse the flags first
+	// Parse the flags first, skipping the flags of API commands that aren't generated yet
+	rootCmd.FParseErrWhitelist.UnknownFlags = true
 	rootCmd.ParseFlags(os.Args)
+	rootCmd.FParseErrWhitelist.UnknownFlags = false
 
 	// Get the config flag value
 	configFlag = rootCmd.Flag("config").Value.String()
@@ -58,34 +65,47 @@ func Execute() {
 		if strings.Contains(err.Error(), "config file not found") || strings.Contains(err.Error(), "no such file or directory") {
 			log.Info("No configuration found. Run 'ontap init' to create one.")
 		} else {
-			fmt.Println("Error initializing config:", err)
+			fmt.Fprintln(os.Stderr, "Error initializing config:", err)
+			configErr = err
 		}
 	} else {
 		// Generate dynamic commands after config is loaded
-		if err := generateDynamicCommands(); err != nil {
+		if err := generateDynamicCommands(os.Args[1:]); err != nil {
 			log.Error("Failed to generate dynamic commands", "error", err)
+			configErr = err
 		}
 	}
 
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, "Error:", err)
+
+		// Commands may be missing because the config could not be loaded
+		code := exitcode.FromError(err)
+		if code == exitcode.General && configErr != nil {
+			code = exitcode.Config
+		}
+		os.Exit(code)
 	}
 }
 
 func init() {
 	// Add global flags
 	rootCmd.PersistentFlags().StringVarP(&configFlag, "config", "c", "", "Config file (default is platform-specific user config directory)")
-	rootCmd.PersistentFlags().StringP("output", "o", "json", "Output format (json, yaml, csv, text, table)")
+	rootCmd.PersistentFlags().StringP("env", "e", "", "Environment of the API to use (default is $ONTAP_ENV or the one set with 'ontap env use')")
+	rootCmd.PersistentFlags().StringP("output", "o", "json", "Output format (json, yaml, csv, text, table, template=<template|@file|name>)")
 	rootCmd.PersistentFlags().StringP("log-level", "l", "info", "Log level (debug, info, warn, error)")
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Verbose output")
 	rootCmd.PersistentFlags().Bool("dry-run", false, "Dry run (don't execute requests)")
 	rootCmd.PersistentFlags().String("save", "", "Save response to file")
-	rootCmd.PersistentFlags().String("extract", "", "Extract fields from response (comma-separated)")
-	rootCmd.PersistentFlags().String("filter", "", "Filter response using JQ-like syntax")
+	rootCmd.PersistentFlags().String("extract", "", "Extract fields from the response (comma-separated paths, optionally name=path)")
+	rootCmd.PersistentFlags().String("filter", "", "Filter the response with a jq expression")
+	rootCmd.PersistentFlags().String("columns", "", "Columns of csv and table output, in order (comma-separated)")
+	rootCmd.PersistentFlags().String("sort-by", "", "Column to sort csv and table output by (prefix with - for descending order)")
+	rootCmd.PersistentFlags().Bool("truncate", false, "Truncate the cells of tables wider than the terminal instead of wrapping them")
 
 	// Bind flags to viper
 	viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config"))
+	viper.BindPFlag("env", rootCmd.PersistentFlags().Lookup("env"))
 	viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
 	viper.BindPFlag("log_level", rootCmd.PersistentFlags().Lookup("log-level"))
 	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
@@ -93,9 +113,15 @@ func init() {
 	viper.BindPFlag("save", rootCmd.PersistentFlags().Lookup("save"))
 	viper.BindPFlag("extract", rootCmd.PersistentFlags().Lookup("extract"))
 	viper.BindPFlag("filter", rootCmd.PersistentFlags().Lookup("filter"))
+	viper.BindPFlag("columns", rootCmd.PersistentFlags().Lookup("columns"))
+	viper.BindPFlag("sort_by", rootCmd.PersistentFlags().Lookup("sort-by"))
+	viper.BindPFlag("truncate", rootCmd.PersistentFlags().Lookup("truncate"))
 
 	// Set up logging in PersistentPreRun
 	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		// Flags and arguments are valid at this point, so errors no longer need the usage
+		cmd.SilenceUsage = true
+
 		// Initialize logging with the log level from the flag
 		logLevel, err := cmd.Flags().GetString("log-level")
 		if err != nil {
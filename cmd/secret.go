@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/fynxlabs/ontap/internal/pkg/secrets"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	// secretCmd represents the secret command
+	secretCmd = &cobra.Command{
+		Use:   "secret",
+		Short: "Manage stored secrets",
+		Long: `Manage the secrets referenced from the config, so credentials don't have to be
+stored in the config file. Secrets are kept in an encrypted file, or in the system
+keyring (Secret Service) with --keyring.
+
+Reference them in the config as secret:NAME or keyring:NAME.`,
+	}
+
+	// secretSetCmd represents the secret set command
+	secretSetCmd = &cobra.Command{
+		Use:   "set <name>",
+		Short: "Store a secret",
+		Long: `Store a secret, read from the terminal without echo or from stdin.
+
+Examples:
+  # Store a token in the encrypted secret file
+  ontap secret set my-api/token
+
+  # Store a token from another tool
+  pass show api/token | ontap secret set my-api/token
+
+  # Store a token in the system keyring
+  ontap secret set my-api/token --keyring`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			useKeyring, _ := cmd.Flags().GetBool("keyring")
+
+			value, err := readSecret(fmt.Sprintf("Value for %s: ", args[0]))
+			if err != nil {
+				return err
+			}
+
+			if useKeyring {
+				if err := secrets.NewKeyring().Set(args[0], value); err != nil {
+					return fmt.Errorf("failed to store secret: %w", err)
+				}
+				log.Info("Stored secret in the keyring", "reference", secrets.SchemeKeyring+":"+args[0])
+				return nil
+			}
+
+			if err := secrets.NewFileStore("").Set(args[0], value); err != nil {
+				return fmt.Errorf("failed to store secret: %w", err)
+			}
+			log.Info("Stored secret", "reference", secrets.SchemeSecret+":"+args[0])
+			return nil
+		},
+	}
+
+	// secretListCmd represents the secret list command
+	secretListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the secrets in the encrypted secret file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names, err := secrets.NewFileStore("").List()
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				fmt.Fprintln(cmd.OutOrStdout(), name)
+			}
+			return nil
+		},
+	}
+
+	// secretDeleteCmd represents the secret delete command
+	secretDeleteCmd = &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a secret",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			useKeyring, _ := cmd.Flags().GetBool("keyring")
+
+			var deleted bool
+			var err error
+			if useKeyring {
+				deleted, err = secrets.NewKeyring().Delete(args[0])
+			} else {
+				deleted, err = secrets.NewFileStore("").Delete(args[0])
+			}
+			if err != nil {
+				return fmt.Errorf("failed to delete secret: %w", err)
+			}
+			if !deleted {
+				return fmt.Errorf("secret not found: %s", args[0])
+			}
+
+			log.Info("Deleted secret", "name", args[0])
+			return nil
+		},
+	}
+)
+
+func init() {
+	secretSetCmd.Flags().Bool("keyring", false, "Store the secret in the system keyring")
+	secretDeleteCmd.Flags().Bool("keyring", false, "Delete the secret from the system keyring")
+
+	secretCmd.AddCommand(secretSetCmd)
+	secretCmd.AddCommand(secretListCmd)
+	secretCmd.AddCommand(secretDeleteCmd)
+	rootCmd.AddCommand(secretCmd)
+}
+
+// readSecret reads a secret from the terminal without echo, or from stdin when it isn't a terminal
+func readSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		value, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		if len(value) == 0 {
+			return "", fmt.Errorf("no secret given")
+		}
+		return string(value), nil
+	}
+
+	value, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+
+	secret := strings.TrimRight(string(value), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("no secret given on stdin")
+	}
+	return secret, nil
+}
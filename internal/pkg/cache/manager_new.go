@@ -4,16 +4,20 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/fynxlabs/ontap/internal/pkg/openapi"
-	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
 )
 
+// specHTTPClient is the HTTP client used to fetch remote specs
+var specHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
 // LibOpenAPICacheManager manages the caching of OpenAPI specs using libopenapi
 type LibOpenAPICacheManager struct {
 	// Store is the cache store
@@ -36,56 +40,168 @@ func NewLibOpenAPICacheManager(cacheDir string) (*LibOpenAPICacheManager, error)
 	}, nil
 }
 
-// GetSpec retrieves a cached spec or loads it from the source
-func (m *LibOpenAPICacheManager) GetSpec(specPath string, ttl time.Duration) (*v3.Document, error) {
+// SpecOptions controls how a spec is loaded and cached
+type SpecOptions struct {
+	// TTL is the time-to-live for the cached spec
+	TTL time.Duration
+
+	// StaleIfError keeps using the last cached spec when the source cannot be loaded
+	StaleIfError bool
+}
+
+// SpecData is a raw spec along with the HTTP validators it was served with
+type SpecData struct {
+	// Raw is the raw spec
+	Raw []byte
+
+	// ETag is the ETag header of the response the spec was fetched with
+	ETag string
+
+	// LastModified is the Last-Modified header of the response the spec was fetched with
+	LastModified string
+}
+
+// GetSpec retrieves a cached spec index or loads it from the source
+func (m *LibOpenAPICacheManager) GetSpec(specPath string, opts SpecOptions) (*openapi.SpecIndex, error) {
 	// Generate a cache key for the spec
 	key := m.generateCacheKey(specPath)
 
 	// Try to get from cache
 	entry, err := m.Store.Get(key)
-	if err == nil && entry != nil && !entry.IsExpired() {
+	if err != nil {
+		log.Debug("OpenAPI spec not cached", "path", specPath, "reason", err)
+		entry = nil
+	} else if !entry.IsExpired() {
 		log.Info("Using cached OpenAPI spec", "path", specPath)
-		return entry.Spec, nil
+		return entry.Index, nil
 	}
 
-	// Load the spec from the source
+	// Load the spec from the source, revalidating the expired entry if there is one
 	log.Info("Loading OpenAPI spec", "path", specPath)
-	spec, err := LoadLibOpenAPISpec(specPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load spec: %w", err)
+	data, notModified, err := fetchSpec(specPath, entry)
+	if err == nil && notModified {
+		log.Info("OpenAPI spec not modified, extending cache", "path", specPath)
+		if err := m.Store.Touch(key, opts.TTL); err != nil {
+			log.Warn("Failed to extend cached spec", "error", err)
+		}
+		return entry.Index, nil
 	}
 
-	// Cache the spec
-	if err := m.Store.Set(key, spec, ttl); err != nil {
-		log.Warn("Failed to cache spec", "error", err)
+	var index *openapi.SpecIndex
+	if err == nil {
+		index, err = m.storeSpec(key, specPath, data, entry, opts.TTL)
+	}
+	if err != nil {
+		// Fall back to the last good spec if allowed
+		if opts.StaleIfError && entry != nil {
+			log.Warn("Failed to load OpenAPI spec, using stale cached copy", "path", specPath, "error", err)
+			return entry.Index, nil
+		}
+		return nil, fmt.Errorf("failed to load spec: %w", err)
 	}
 
-	return spec, nil
+	return index, nil
 }
 
 // RefreshSpec refreshes a cached spec
-func (m *LibOpenAPICacheManager) RefreshSpec(specPath string, ttl time.Duration) (*v3.Document, error) {
+func (m *LibOpenAPICacheManager) RefreshSpec(specPath string, ttl time.Duration) (*openapi.SpecIndex, error) {
 	// Generate a cache key for the spec
 	key := m.generateCacheKey(specPath)
 
-	// Delete the cached spec
-	if err := m.Store.Delete(key); err != nil {
-		log.Warn("Failed to delete cached spec", "error", err)
+	// Load the spec from the source unconditionally
+	log.Info("Refreshing OpenAPI spec", "path", specPath)
+	data, _, err := fetchSpec(specPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load spec: %w", err)
 	}
 
-	// Load the spec from the source
-	log.Info("Refreshing OpenAPI spec", "path", specPath)
-	spec, err := LoadLibOpenAPISpec(specPath)
+	// Only replace the cached spec once the new one has been loaded
+	index, err := m.storeSpec(key, specPath, data, nil, ttl)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load spec: %w", err)
 	}
 
+	return index, nil
+}
+
+// storeSpec indexes a raw spec and caches it, reusing the index of the
+// previous cache entry when the spec content has not changed
+func (m *LibOpenAPICacheManager) storeSpec(key, specPath string, data *SpecData, previous *LibOpenAPICacheEntry, ttl time.Duration) (*openapi.SpecIndex, error) {
+	var index *openapi.SpecIndex
+	if previous != nil && previous.Hash == HashSpec(data.Raw) {
+		log.Debug("OpenAPI spec unchanged, reusing cached index", "path", specPath)
+		index = previous.Index
+	} else {
+		var err error
+		index, err = BuildLibOpenAPISpecIndex(data.Raw, specPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Cache the spec
-	if err := m.Store.Set(key, spec, ttl); err != nil {
+	if err := m.Store.Set(key, index, data, ttl); err != nil {
 		log.Warn("Failed to cache spec", "error", err)
 	}
 
-	return spec, nil
+	return index, nil
+}
+
+// fetchSpec loads a raw spec from a file or URL. For URLs with a previous cache
+// entry, the request is made conditional on the entry's validators and
+// notModified reports whether the server answered 304 Not Modified.
+func fetchSpec(specPath string, previous *LibOpenAPICacheEntry) (data *SpecData, notModified bool, err error) {
+	// Local files are simply read again
+	if !IsLibOpenAPIURL(specPath) {
+		raw, err := openapi.LoadSpecData(specPath)
+		if err != nil {
+			return nil, false, err
+		}
+		return &SpecData{Raw: raw}, false, nil
+	}
+
+	// Create the request
+	req, err := http.NewRequest(http.MethodGet, specPath, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Add the validators of the previous entry
+	if previous != nil {
+		if previous.ETag != "" {
+			req.Header.Set("If-None-Match", previous.ETag)
+		}
+		if previous.LastModified != "" {
+			req.Header.Set("If-Modified-Since", previous.LastModified)
+		}
+	}
+
+	// Fetch the spec from the URL
+	resp, err := specHTTPClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch spec from URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check the status
+	if resp.StatusCode == http.StatusNotModified && previous != nil {
+		return nil, true, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("failed to fetch spec from URL: %s", resp.Status)
+	}
+
+	// Read the response body
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return &SpecData{
+		Raw:          raw,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, false, nil
 }
 
 // ClearCache clears the entire cache
@@ -100,15 +216,15 @@ func (m *LibOpenAPICacheManager) generateCacheKey(specPath string) string {
 	return hex.EncodeToString(hash[:])
 }
 
-// LoadLibOpenAPISpec loads an OpenAPI specification from a file or URL using libopenapi
-func LoadLibOpenAPISpec(specPath string) (*v3.Document, error) {
+// BuildLibOpenAPISpecIndex parses a raw OpenAPI specification using libopenapi and builds its index
+func BuildLibOpenAPISpecIndex(raw []byte, specPath string) (*openapi.SpecIndex, error) {
 	parser := openapi.NewLibOpenAPISpecParser()
-	return parser.ParseSpec(specPath)
+	return parser.BuildIndexData(raw, specPath)
 }
 
 // IsLibOpenAPIURL checks if a string is a URL
 func IsLibOpenAPIURL(s string) bool {
-	return s != "" && (s[:7] == "http://" || s[:8] == "https://")
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
 }
 
 // DownloadLibOpenAPISpec downloads an OpenAPI specification from a URL to a file
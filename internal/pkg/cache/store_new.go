@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -9,16 +11,22 @@ import (
 	"time"
 
 	"github.com/charmbracelet/log"
-	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/fynxlabs/ontap/internal/pkg/openapi"
 )
 
 // LibOpenAPICacheStore is the interface for caching OpenAPI specs using libopenapi
 type LibOpenAPICacheStore interface {
-	// Get retrieves a cached spec
+	// Get retrieves a cached spec, including expired entries
 	Get(key string) (*LibOpenAPICacheEntry, error)
 
-	// Set stores a spec in the cache
-	Set(key string, spec *v3.Document, ttl time.Duration) error
+	// Set stores a spec index and the raw spec it was built from in the cache
+	Set(key string, index *openapi.SpecIndex, data *SpecData, ttl time.Duration) error
+
+	// Touch extends the expiry of a cached spec
+	Touch(key string, ttl time.Duration) error
+
+	// GetRaw retrieves the raw spec bytes for a cached spec
+	GetRaw(key string) ([]byte, error)
 
 	// Delete removes a spec from the cache
 	Delete(key string) error
@@ -32,14 +40,23 @@ type LibOpenAPICacheStore interface {
 
 // LibOpenAPICacheEntry represents a cached OpenAPI spec using libopenapi
 type LibOpenAPICacheEntry struct {
-	// Spec is the OpenAPI spec
-	Spec *v3.Document
+	// Index is the precompiled index of the spec
+	Index *openapi.SpecIndex `json:"index"`
+
+	// Hash is the SHA-256 hash of the raw spec
+	Hash string `json:"hash"`
+
+	// ETag is the ETag of the remote spec, used for revalidation
+	ETag string `json:"etag,omitempty"`
+
+	// LastModified is the Last-Modified time of the remote spec, used for revalidation
+	LastModified string `json:"lastModified,omitempty"`
 
 	// CreatedAt is the time the entry was created
-	CreatedAt time.Time
+	CreatedAt time.Time `json:"createdAt"`
 
 	// ExpiresAt is the time the entry expires
-	ExpiresAt time.Time
+	ExpiresAt time.Time `json:"expiresAt"`
 }
 
 // IsExpired checks if the cache entry is expired
@@ -72,19 +89,12 @@ func NewLibOpenAPIFileSystemCacheStore(cacheDir string) (*LibOpenAPIFileSystemCa
 	}, nil
 }
 
-// Get retrieves a cached spec
+// Get retrieves a cached spec, including expired entries
 func (s *LibOpenAPIFileSystemCacheStore) Get(key string) (*LibOpenAPICacheEntry, error) {
 	s.mutex.RLock()
 	// Check memory cache first
 	if entry, ok := s.memoryCache[key]; ok {
 		s.mutex.RUnlock()
-		if entry.IsExpired() {
-			// Remove expired entry
-			if err := s.Delete(key); err != nil {
-				log.Warn("Failed to delete expired cache entry", "key", key, "error", err)
-			}
-			return nil, fmt.Errorf("cache entry expired")
-		}
 		return entry, nil
 	}
 	s.mutex.RUnlock()
@@ -102,13 +112,9 @@ func (s *LibOpenAPIFileSystemCacheStore) Get(key string) (*LibOpenAPICacheEntry,
 		return nil, fmt.Errorf("failed to unmarshal cache entry: %w", err)
 	}
 
-	// Check if the entry is expired
-	if entry.IsExpired() {
-		// Remove expired entry
-		if err := s.Delete(key); err != nil {
-			log.Warn("Failed to delete expired cache entry", "key", key, "error", err)
-		}
-		return nil, fmt.Errorf("cache entry expired")
+	// Check if the entry was written with the current index layout
+	if entry.Index == nil || entry.Index.Version != openapi.SpecIndexVersion {
+		return nil, fmt.Errorf("cache entry has an outdated format")
 	}
 
 	// Add to memory cache
@@ -119,16 +125,51 @@ func (s *LibOpenAPIFileSystemCacheStore) Get(key string) (*LibOpenAPICacheEntry,
 	return &entry, nil
 }
 
-// Set stores a spec in the cache
-func (s *LibOpenAPIFileSystemCacheStore) Set(key string, spec *v3.Document, ttl time.Duration) error {
+// Set stores a spec index and the raw spec it was built from in the cache
+func (s *LibOpenAPIFileSystemCacheStore) Set(key string, index *openapi.SpecIndex, data *SpecData, ttl time.Duration) error {
 	// Create the cache entry
 	now := time.Now()
 	entry := &LibOpenAPICacheEntry{
-		Spec:      spec,
-		CreatedAt: now,
-		ExpiresAt: now.Add(ttl),
+		Index:        index,
+		Hash:         HashSpec(data.Raw),
+		ETag:         data.ETag,
+		LastModified: data.LastModified,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(ttl),
 	}
 
+	// Write the raw spec next to the entry
+	if err := os.MkdirAll(s.CacheDir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.WriteFile(s.getRawPath(key), data.Raw, 0644); err != nil {
+		return fmt.Errorf("failed to write raw spec file: %w", err)
+	}
+
+	if err := s.writeEntry(key, entry); err != nil {
+		return err
+	}
+
+	log.Info("Cached OpenAPI spec", "key", key, "path", s.GetCachePath(key), "ttl", ttl)
+	return nil
+}
+
+// Touch extends the expiry of a cached spec
+func (s *LibOpenAPIFileSystemCacheStore) Touch(key string, ttl time.Duration) error {
+	entry, err := s.Get(key)
+	if err != nil {
+		return err
+	}
+
+	// Copy the entry so readers of the old one are unaffected
+	touched := *entry
+	touched.ExpiresAt = time.Now().Add(ttl)
+
+	return s.writeEntry(key, &touched)
+}
+
+// writeEntry stores a cache entry in memory and on disk
+func (s *LibOpenAPIFileSystemCacheStore) writeEntry(key string, entry *LibOpenAPICacheEntry) error {
 	// Add to memory cache
 	s.mutex.Lock()
 	s.memoryCache[key] = entry
@@ -150,10 +191,19 @@ func (s *LibOpenAPIFileSystemCacheStore) Set(key string, spec *v3.Document, ttl
 		return fmt.Errorf("failed to write cache file: %w", err)
 	}
 
-	log.Info("Cached OpenAPI spec", "key", key, "path", cachePath, "ttl", ttl)
 	return nil
 }
 
+// GetRaw retrieves the raw spec bytes for a cached spec
+func (s *LibOpenAPIFileSystemCacheStore) GetRaw(key string) ([]byte, error) {
+	data, err := os.ReadFile(s.getRawPath(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read raw spec file: %w", err)
+	}
+
+	return data, nil
+}
+
 // Delete removes a spec from the cache
 func (s *LibOpenAPIFileSystemCacheStore) Delete(key string) error {
 	// Remove from memory cache
@@ -166,6 +216,9 @@ func (s *LibOpenAPIFileSystemCacheStore) Delete(key string) error {
 	if err := os.Remove(cachePath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to remove cache file: %w", err)
 	}
+	if err := os.Remove(s.getRawPath(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove raw spec file: %w", err)
+	}
 
 	log.Info("Removed cached OpenAPI spec", "key", key)
 	return nil
@@ -198,3 +251,16 @@ func (s *LibOpenAPIFileSystemCacheStore) GetCachePath(key string) string {
 	safeKey := filepath.Base(key)
 	return filepath.Join(s.CacheDir, safeKey+".json")
 }
+
+// getRawPath returns the path to the raw spec file for a key
+func (s *LibOpenAPIFileSystemCacheStore) getRawPath(key string) string {
+	// Create a safe filename from the key
+	safeKey := filepath.Base(key)
+	return filepath.Join(s.CacheDir, safeKey+".spec")
+}
+
+// HashSpec returns the SHA-256 hash of a raw spec
+func HashSpec(raw []byte) string {
+	hash := sha256.Sum256(raw)
+	return hex.EncodeToString(hash[:])
+}
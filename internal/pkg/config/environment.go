@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultStateFileName is the name of the file holding the state persisted by commands
+const DefaultStateFileName = "state.yaml"
+
+// State holds the settings persisted by commands, kept apart from the config file
+type State struct {
+	// Environments maps API names to the environment selected with 'ontap env use'
+	Environments map[string]string `yaml:"environments,omitempty"`
+}
+
+// EnvironmentNames returns the sorted names of the environments of an API
+func (c APIConfig) EnvironmentNames() []string {
+	names := make([]string, 0, len(c.Environments))
+	for name := range c.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Environment returns the environment with the given name. Names are matched
+// case-insensitively since config keys are lowercased when the config is loaded.
+func (c APIConfig) Environment(name string) (EnvironmentConfig, bool) {
+	if env, ok := c.Environments[name]; ok {
+		return env, true
+	}
+	for key, env := range c.Environments {
+		if strings.EqualFold(key, name) {
+			return env, true
+		}
+	}
+	return EnvironmentConfig{}, false
+}
+
+// EnvironmentVar is the environment variable selecting the environment of the APIs
+const EnvironmentVar = "ONTAP_ENV"
+
+// ActiveEnvironment returns the name of the active environment of an API: the one given
+// with --env, then ONTAP_ENV, then the one set with 'ontap env use' in the state, then the
+// API's default_env. APIs without environments have no active environment.
+func (c APIConfig) ActiveEnvironment(apiName, flag string, state *State) string {
+	if len(c.Environments) == 0 {
+		return ""
+	}
+	if flag != "" {
+		return flag
+	}
+	if env := os.Getenv(EnvironmentVar); env != "" {
+		return env
+	}
+	if state != nil && state.Environments[apiName] != "" {
+		return state.Environments[apiName]
+	}
+	return c.DefaultEnvironment
+}
+
+// WithEnvironment returns the API config with the overrides of an environment applied.
+// An empty name returns the config unchanged.
+func (c APIConfig) WithEnvironment(name string) (APIConfig, error) {
+	if name == "" {
+		return c, nil
+	}
+
+	env, ok := c.Environment(name)
+	if !ok {
+		if len(c.Environments) == 0 {
+			return c, fmt.Errorf("unknown environment %q (no environments are configured)", name)
+		}
+		return c, fmt.Errorf("unknown environment %q (available: %s)", name, strings.Join(c.EnvironmentNames(), ", "))
+	}
+
+	if env.URL != "" {
+		c.URL = env.URL
+	}
+
+	// An environment's auth replaces both ways of setting the auth
+	if env.Auth != "" || env.AuthCommand != "" {
+		c.Auth = env.Auth
+		c.AuthCommand = env.AuthCommand
+	}
+
+	c.Credentials = mergeStringMaps(c.Credentials, env.Credentials)
+	c.Headers = mergeStringMaps(c.Headers, env.Headers)
+
+	if env.OAuth2 != nil {
+		c.OAuth2 = env.OAuth2
+	}
+	if env.DefaultOutput != "" {
+		c.DefaultOutput = env.DefaultOutput
+	}
+	if env.ValidateResponse != "" {
+		c.ValidateResponse = env.ValidateResponse
+	}
+	if env.TLS != nil {
+		c.TLS = env.TLS
+	}
+	if env.Proxy != "" {
+		c.Proxy = env.Proxy
+	}
+
+	return c, nil
+}
+
+// mergeStringMaps returns a new map with the entries of override added to base
+func mergeStringMaps(base, override map[string]string) map[string]string {
+	if len(override) == 0 {
+		return base
+	}
+
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
+// GetDefaultStatePath returns the default path for the state file
+func GetDefaultStatePath() string {
+	return filepath.Join(filepath.Dir(NewConfigLoader().GetDefaultConfigPath()), DefaultStateFileName)
+}
+
+// LoadState loads the state from the specified path, returning an empty state if the file doesn't exist
+func LoadState(path string) (*State, error) {
+	if path == "" {
+		path = GetDefaultStatePath()
+	}
+
+	state := &State{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file: %w", err)
+	}
+
+	return state, nil
+}
+
+// SaveState saves the state to the specified path
+func SaveState(state *State, path string) error {
+	if path == "" {
+		path = GetDefaultStatePath()
+	}
+
+	// Create the directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	return nil
+}
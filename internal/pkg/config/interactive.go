@@ -10,6 +10,7 @@ import (
 
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/log"
+	"github.com/fynxlabs/ontap/internal/pkg/secrets"
 )
 
 // CreateInteractiveConfig creates a configuration file interactively
@@ -302,13 +303,7 @@ func addNewAPI(config *Config, index, total int) error {
 
 			huh.NewInput().
 				Title("Base URL").
-				Description("Base URL for API requests").
-				Validate(func(s string) error {
-					if s == "" {
-						return fmt.Errorf("base URL cannot be empty")
-					}
-					return nil
-				}).
+				Description("Base URL for API requests (leave empty to use the spec's servers)").
 				Value(&baseURL),
 
 			huh.NewInput().
@@ -329,7 +324,8 @@ func addNewAPI(config *Config, index, total int) error {
 
 			huh.NewInput().
 				Title("Authentication").
-				Description("Authentication credentials (username:password or token)").
+				Description("Credentials (username:password or token), stored in the encrypted secret file, or a reference like env:API_TOKEN").
+				EchoMode(huh.EchoModePassword).
 				Value(&auth),
 
 			huh.NewSelect[string]().
@@ -367,6 +363,12 @@ func addNewAPI(config *Config, index, total int) error {
 		return fmt.Errorf("failed to parse cache TTL: %w", err)
 	}
 
+	// Keep the credentials out of the config file
+	auth, err = storeAuthSecret(apiName, auth)
+	if err != nil {
+		return err
+	}
+
 	// Create the API config
 	apiConfig := APIConfig{
 		APISpec:       apiSpec,
@@ -447,13 +449,7 @@ func editAPI(config *Config, apiName string) error {
 
 			huh.NewInput().
 				Title("Base URL").
-				Description("Base URL for API requests").
-				Validate(func(s string) error {
-					if s == "" {
-						return fmt.Errorf("base URL cannot be empty")
-					}
-					return nil
-				}).
+				Description("Base URL for API requests (leave empty to use the spec's servers)").
 				Value(&baseURL),
 
 			huh.NewInput().
@@ -473,7 +469,7 @@ func editAPI(config *Config, apiName string) error {
 
 			huh.NewInput().
 				Title("Authentication").
-				Description("Authentication credentials (username:password or token)").
+				Description("Credentials (username:password or token), stored in the encrypted secret file, or a reference like env:API_TOKEN").
 				Value(&auth),
 
 			huh.NewSelect[string]().
@@ -503,6 +499,12 @@ func editAPI(config *Config, apiName string) error {
 		return fmt.Errorf("failed to parse cache TTL: %w", err)
 	}
 
+	// Keep the credentials out of the config file
+	auth, err = storeAuthSecret(newAPIName, auth)
+	if err != nil {
+		return err
+	}
+
 	// Update the API config
 	apiConfig.APISpec = apiSpec
 	apiConfig.URL = baseURL
@@ -729,3 +731,23 @@ func editCustomHeaders(apiConfig *APIConfig) error {
 
 	return nil
 }
+
+// storeAuthSecret stores credentials entered for an API in the encrypted secret file
+// and returns the reference to use in the config. Empty values, ${VAR} values and
+// references are returned unchanged.
+func storeAuthSecret(apiName, auth string) (string, error) {
+	if auth == "" || strings.HasPrefix(auth, "${") {
+		return auth, nil
+	}
+	if _, _, ok := secrets.ParseReference(auth); ok {
+		return auth, nil
+	}
+
+	name := apiName + "/auth"
+	if err := secrets.NewFileStore("").Set(name, auth); err != nil {
+		return "", fmt.Errorf("failed to store credentials: %w", err)
+	}
+
+	log.Info("Stored credentials in the secret file", "secret", name)
+	return secrets.SchemeSecret + ":" + name, nil
+}
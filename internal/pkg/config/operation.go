@@ -0,0 +1,31 @@
+package config
+
+import "strings"
+
+// Operation returns the settings of an operation. Operation IDs are matched
+// case-insensitively, since the config loader lowercases keys.
+func (c APIConfig) Operation(operationID string) (OperationConfig, bool) {
+	if operation, ok := c.Operations[operationID]; ok {
+		return operation, true
+	}
+	for id, operation := range c.Operations {
+		if strings.EqualFold(id, operationID) {
+			return operation, true
+		}
+	}
+	return OperationConfig{}, false
+}
+
+// Template returns a named output template of an operation, or of the API when the
+// operation doesn't declare it
+func (c APIConfig) Template(operationID, name string) (string, bool) {
+	operation, _ := c.Operation(operationID)
+	for _, templates := range []map[string]string{operation.Templates, c.Templates} {
+		for templateName, text := range templates {
+			if strings.EqualFold(templateName, name) {
+				return text, true
+			}
+		}
+	}
+	return "", false
+}
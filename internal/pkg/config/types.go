@@ -22,18 +22,204 @@ type APIConfig struct {
 	// - API key: "key123"
 	Auth string `yaml:"auth" json:"auth"`
 
+	// AuthCommand is a command whose output is used as the auth when Auth is empty
+	// (e.g. "pass show api/token")
+	AuthCommand string `yaml:"auth_command,omitempty" json:"auth_command,omitempty" mapstructure:"auth_command"`
+
+	// Credentials maps security scheme names from the spec to credentials.
+	// The credential format depends on the scheme (API key, username:password, token).
+	Credentials map[string]string `yaml:"credentials,omitempty" json:"credentials,omitempty"`
+
+	// OAuth2 configures how OAuth2 access tokens are obtained for this API
+	OAuth2 *OAuth2Config `yaml:"oauth2,omitempty" json:"oauth2,omitempty"`
+
 	// URL is the base URL for the API
 	URL string `yaml:"url" json:"url"`
 
 	// CacheTTL is the time-to-live for the cached OpenAPI spec
 	// Format: time.Duration string (e.g., "24h", "30m")
-	CacheTTL Duration `yaml:"cache_ttl" json:"cache_ttl" default:"24h"`
+	CacheTTL Duration `yaml:"cache_ttl" json:"cache_ttl" mapstructure:"cache_ttl" default:"24h"`
+
+	// StaleIfError keeps using the last cached spec when it cannot be re-fetched
+	StaleIfError bool `yaml:"stale_if_error,omitempty" json:"stale_if_error,omitempty" mapstructure:"stale_if_error"`
 
 	// DefaultOutput is the default output format for this API
-	DefaultOutput string `yaml:"output" json:"output" default:"json"`
+	DefaultOutput string `yaml:"output" json:"output" mapstructure:"output" default:"json"`
 
 	// Headers are additional headers to include with every request
 	Headers map[string]string `yaml:"headers" json:"headers"`
+
+	// ValidateResponse is the response validation mode for this API (off, warn, strict)
+	ValidateResponse string `yaml:"validate_response,omitempty" json:"validate_response,omitempty" mapstructure:"validate_response"`
+
+	// Timeout is the timeout of each request attempt (default: 30s)
+	Timeout Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// Retries is the number of times failed requests are retried (default: 0)
+	Retries int `yaml:"retries,omitempty" json:"retries,omitempty"`
+
+	// IdempotencyKey sends a generated Idempotency-Key header with POST and PATCH requests,
+	// which also allows retrying them
+	IdempotencyKey bool `yaml:"idempotency_key,omitempty" json:"idempotency_key,omitempty" mapstructure:"idempotency_key"`
+
+	// Pagination is how the list operations of the API are paginated; overrides the spec's x-pagination
+	Pagination *PaginationConfig `yaml:"pagination,omitempty" json:"pagination,omitempty"`
+
+	// TLS configures the TLS connections to the API
+	TLS *TLSConfig `yaml:"tls,omitempty" json:"tls,omitempty"`
+
+	// Proxy is the URL of the proxy to reach the API through, or "none" to not use one
+	// (default: from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables)
+	Proxy string `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+
+	// Templates are named output templates, used with -o template=<name>
+	Templates map[string]string `yaml:"templates,omitempty" json:"templates,omitempty"`
+
+	// Operations are the settings of individual operations, keyed by operation ID
+	Operations map[string]OperationConfig `yaml:"operations,omitempty" json:"operations,omitempty"`
+
+	// Environments are named sets of overrides for the API (e.g. dev, staging, prod)
+	Environments map[string]EnvironmentConfig `yaml:"environments,omitempty" json:"environments,omitempty"`
+
+	// DefaultEnvironment is the environment used when none is selected
+	DefaultEnvironment string `yaml:"default_env,omitempty" json:"default_env,omitempty" mapstructure:"default_env"`
+}
+
+// EnvironmentConfig represents the settings an environment overrides for an API.
+// Empty fields keep the API's values, and maps are merged into the API's maps.
+type EnvironmentConfig struct {
+	// URL is the base URL for the API
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+
+	// Auth is the authentication string
+	Auth string `yaml:"auth,omitempty" json:"auth,omitempty"`
+
+	// AuthCommand is a command whose output is used as the auth when Auth is empty
+	AuthCommand string `yaml:"auth_command,omitempty" json:"auth_command,omitempty" mapstructure:"auth_command"`
+
+	// Credentials maps security scheme names from the spec to credentials
+	Credentials map[string]string `yaml:"credentials,omitempty" json:"credentials,omitempty"`
+
+	// OAuth2 configures how OAuth2 access tokens are obtained, replacing the API's settings
+	OAuth2 *OAuth2Config `yaml:"oauth2,omitempty" json:"oauth2,omitempty"`
+
+	// Headers are additional headers to include with every request
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+
+	// DefaultOutput is the default output format
+	DefaultOutput string `yaml:"output,omitempty" json:"output,omitempty" mapstructure:"output"`
+
+	// ValidateResponse is the response validation mode (off, warn, strict)
+	ValidateResponse string `yaml:"validate_response,omitempty" json:"validate_response,omitempty" mapstructure:"validate_response"`
+
+	// TLS configures the TLS connections, replacing the API's settings
+	TLS *TLSConfig `yaml:"tls,omitempty" json:"tls,omitempty"`
+
+	// Proxy is the URL of the proxy to use, or "none" to not use one
+	Proxy string `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+}
+
+// OAuth2Config represents the OAuth2 settings for an API
+type OAuth2Config struct {
+	// Grant is the grant type (client_credentials, password, refresh_token, authorization_code, device_code)
+	Grant string `yaml:"grant" json:"grant"`
+
+	// TokenURL is the token endpoint; defaults to the one in the spec's oauth2 security scheme
+	TokenURL string `yaml:"token_url,omitempty" json:"token_url,omitempty" mapstructure:"token_url"`
+
+	// AuthorizationURL is the authorization endpoint; defaults to the one in the spec's oauth2 security scheme
+	AuthorizationURL string `yaml:"authorization_url,omitempty" json:"authorization_url,omitempty" mapstructure:"authorization_url"`
+
+	// DeviceAuthorizationURL is the device authorization endpoint for the device_code grant
+	DeviceAuthorizationURL string `yaml:"device_authorization_url,omitempty" json:"device_authorization_url,omitempty" mapstructure:"device_authorization_url"`
+
+	// RevocationURL is the token revocation endpoint used on logout
+	RevocationURL string `yaml:"revocation_url,omitempty" json:"revocation_url,omitempty" mapstructure:"revocation_url"`
+
+	// RedirectPort is the loopback port for the authorization_code redirect (default: any free port)
+	RedirectPort int `yaml:"redirect_port,omitempty" json:"redirect_port,omitempty" mapstructure:"redirect_port"`
+
+	// ClientID is the client ID
+	ClientID string `yaml:"client_id,omitempty" json:"client_id,omitempty" mapstructure:"client_id"`
+
+	// ClientSecret is the client secret
+	ClientSecret string `yaml:"client_secret,omitempty" json:"client_secret,omitempty" mapstructure:"client_secret"`
+
+	// ClientAuth is how the client authenticates to the token endpoint (header or body)
+	ClientAuth string `yaml:"client_auth,omitempty" json:"client_auth,omitempty" mapstructure:"client_auth"`
+
+	// Username is the resource owner username for the password grant
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+
+	// Password is the resource owner password for the password grant
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+
+	// RefreshToken is the refresh token for the refresh_token grant
+	RefreshToken string `yaml:"refresh_token,omitempty" json:"refresh_token,omitempty" mapstructure:"refresh_token"`
+
+	// Scopes are the scopes to request; defaults to the scopes required by the operation
+	Scopes []string `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+
+	// Params are additional parameters to send to the token endpoint (e.g. audience)
+	Params map[string]string `yaml:"params,omitempty" json:"params,omitempty"`
+}
+
+// OperationConfig represents the settings of a single operation of an API
+type OperationConfig struct {
+	// DefaultOutput is the default output format of the operation
+	DefaultOutput string `yaml:"output,omitempty" json:"output,omitempty" mapstructure:"output"`
+
+	// Templates are named output templates of the operation, taking precedence over the API's
+	Templates map[string]string `yaml:"templates,omitempty" json:"templates,omitempty"`
+}
+
+// TLSConfig represents the TLS settings for an API
+type TLSConfig struct {
+	// CAFile is a PEM bundle of CA certificates trusted in addition to the system's
+	CAFile string `yaml:"ca_file,omitempty" json:"ca_file,omitempty" mapstructure:"ca_file"`
+
+	// CertFile is the PEM client certificate for mutual TLS
+	CertFile string `yaml:"cert_file,omitempty" json:"cert_file,omitempty" mapstructure:"cert_file"`
+
+	// KeyFile is the PEM private key of the client certificate
+	KeyFile string `yaml:"key_file,omitempty" json:"key_file,omitempty" mapstructure:"key_file"`
+
+	// ServerName is the name to verify the server certificate against, instead of the URL's host
+	ServerName string `yaml:"server_name,omitempty" json:"server_name,omitempty" mapstructure:"server_name"`
+
+	// MinVersion is the minimum TLS version (1.0, 1.1, 1.2, 1.3)
+	MinVersion string `yaml:"min_version,omitempty" json:"min_version,omitempty" mapstructure:"min_version"`
+
+	// InsecureSkipVerify disables the verification of the server certificate. Only for testing.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty" mapstructure:"insecure_skip_verify"`
+}
+
+// PaginationConfig represents the pagination settings for an API. Empty fields are
+// detected from the spec and the responses.
+type PaginationConfig struct {
+	// Style is the pagination style (link, cursor, offset, page)
+	Style string `yaml:"style,omitempty" json:"style,omitempty"`
+
+	// ItemsPath is the dotted path of the items in the response body (e.g. data.items)
+	ItemsPath string `yaml:"items_path,omitempty" json:"items_path,omitempty" mapstructure:"items_path"`
+
+	// CursorPath is the dotted path of the next cursor or next page URL in the response body
+	CursorPath string `yaml:"cursor_path,omitempty" json:"cursor_path,omitempty" mapstructure:"cursor_path"`
+
+	// CursorParam is the query parameter the cursor is sent in
+	CursorParam string `yaml:"cursor_param,omitempty" json:"cursor_param,omitempty" mapstructure:"cursor_param"`
+
+	// OffsetParam is the query parameter holding the offset of the first item
+	OffsetParam string `yaml:"offset_param,omitempty" json:"offset_param,omitempty" mapstructure:"offset_param"`
+
+	// PageParam is the query parameter holding the page number
+	PageParam string `yaml:"page_param,omitempty" json:"page_param,omitempty" mapstructure:"page_param"`
+
+	// LimitParam is the query parameter holding the page size
+	LimitParam string `yaml:"limit_param,omitempty" json:"limit_param,omitempty" mapstructure:"limit_param"`
+
+	// Limit is the page size to request
+	Limit int `yaml:"limit,omitempty" json:"limit,omitempty"`
 }
 
 // Duration is a wrapper around time.Duration for YAML/JSON marshaling
@@ -83,6 +269,17 @@ func (d *Duration) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// UnmarshalText implements the encoding.TextUnmarshaler interface
+func (d *Duration) UnmarshalText(b []byte) error {
+	duration, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+
+	d.Duration = duration
+	return nil
+}
+
 // MarshalJSON implements the json.Marshaler interface
 func (d Duration) MarshalJSON() ([]byte, error) {
 	return json.Marshal(d.String())
@@ -0,0 +1,172 @@
+// Package exitcode maps the errors of commands and the statuses of responses to the
+// exit codes of the CLI.
+package exitcode
+
+import (
+	"errors"
+	"fmt"
+	nethttp "net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/fynxlabs/ontap/internal/pkg/validation"
+)
+
+// Exit codes returned by the CLI
+const (
+	// General is returned for general errors
+	General = 1
+
+	// Config is returned when the config or an API spec cannot be loaded
+	Config = 2
+
+	// Redirect is returned for 3xx responses
+	Redirect = 3
+
+	// Client is returned for 4xx responses
+	Client = 4
+
+	// Server is returned for 5xx responses
+	Server = 5
+
+	// Network is returned when the request could not be sent or answered
+	Network = 6
+
+	// Validation is returned when request or response validation fails
+	Validation = 7
+)
+
+// Error is an error that carries the exit code the CLI should exit with
+type Error struct {
+	// Code is the exit code
+	Code int
+
+	// Err is the underlying error
+	Err error
+}
+
+// Error returns the error message
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// FromError returns the exit code for an error
+func FromError(err error) int {
+	var exitErr *Error
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	var requestErrs validation.Errors
+	var responseErrs validation.ResponseErrors
+	if errors.As(err, &requestErrs) || errors.As(err, &responseErrs) {
+		return Validation
+	}
+
+	return General
+}
+
+// RequestError marks transport failures from executing a request as network errors
+func RequestError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return &Error{Code: Network, Err: err}
+	}
+
+	return err
+}
+
+// StatusError returns the error for a response status that counts as a failure
+func StatusError(statusCode int) *Error {
+	code := General
+	switch statusCode / 100 {
+	case 3:
+		code = Redirect
+	case 4:
+		code = Client
+	case 5:
+		code = Server
+	}
+
+	return &Error{
+		Code: code,
+		Err:  fmt.Errorf("request failed with status %d %s", statusCode, nethttp.StatusText(statusCode)),
+	}
+}
+
+// StatusMatcher matches response status codes against the --fail-on list
+type StatusMatcher struct {
+	// classes are the status classes (e.g. 4 for 4xx)
+	classes map[int]bool
+
+	// codes are the individual status codes
+	codes map[int]bool
+
+	// ranges are the inclusive ranges of status codes (e.g. 400-403)
+	ranges [][2]int
+}
+
+// ParseFailOn parses a comma-separated list of status classes (4xx), codes (404) and
+// ranges of codes (400-403), or "none"
+func ParseFailOn(value string) (*StatusMatcher, error) {
+	matcher := &StatusMatcher{classes: map[int]bool{}, codes: map[int]bool{}}
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		switch {
+		case item == "" || item == "none":
+			continue
+		case len(item) == 3 && strings.HasSuffix(item, "xx") && item[0] >= '1' && item[0] <= '5':
+			matcher.classes[int(item[0]-'0')] = true
+		case strings.Contains(item, "-"):
+			from, to, _ := strings.Cut(item, "-")
+			start, startOK := parseStatusCode(from)
+			end, endOK := parseStatusCode(to)
+			if !startOK || !endOK || start > end {
+				return nil, invalidFailOn(item)
+			}
+			matcher.ranges = append(matcher.ranges, [2]int{start, end})
+		default:
+			code, ok := parseStatusCode(item)
+			if !ok {
+				return nil, invalidFailOn(item)
+			}
+			matcher.codes[code] = true
+		}
+	}
+
+	return matcher, nil
+}
+
+// Matches checks if a status code counts as a failure
+func (m *StatusMatcher) Matches(statusCode int) bool {
+	if m.codes[statusCode] || m.classes[statusCode/100] {
+		return true
+	}
+	for _, r := range m.ranges {
+		if statusCode >= r[0] && statusCode <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStatusCode parses an HTTP status code
+func parseStatusCode(value string) (int, bool) {
+	code, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || code < 100 || code > 599 {
+		return 0, false
+	}
+	return code, true
+}
+
+// invalidFailOn returns the error for an invalid --fail-on item
+func invalidFailOn(item string) error {
+	return fmt.Errorf("invalid --fail-on value %q (expected a status class like 4xx, a status code like 404, a range like 400-403, or none)", item)
+}
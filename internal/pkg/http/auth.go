@@ -7,7 +7,7 @@ import (
 	"os"
 	"strings"
 
-	"github.com/charmbracelet/log"
+	"github.com/fynxlabs/ontap/internal/pkg/secrets"
 )
 
 // AuthType represents an authentication type
@@ -37,6 +37,9 @@ type AuthProvider interface {
 
 	// GetAuthString returns the authentication string
 	GetAuthString() string
+
+	// Apply adds the authentication to a request
+	Apply(req *http.Request) error
 }
 
 // BasicAuth represents basic authentication
@@ -91,6 +94,12 @@ func (a *BasicAuth) GetAuthString() string {
 	return a.Username + ":" + a.Password
 }
 
+// Apply adds the authentication to a request
+func (a *BasicAuth) Apply(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
 // BearerAuth represents bearer token authentication
 type BearerAuth struct {
 	// Token is the bearer token
@@ -134,6 +143,12 @@ func (a *BearerAuth) GetAuthString() string {
 	return "Bearer " + a.Token
 }
 
+// Apply adds the authentication to a request
+func (a *BearerAuth) Apply(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
 // APIKeyAuth represents API key authentication
 type APIKeyAuth struct {
 	// Key is the API key
@@ -142,7 +157,7 @@ type APIKeyAuth struct {
 	// Name is the name of the API key header
 	Name string
 
-	// In is the location of the API key (header, query)
+	// In is the location of the API key (header, query, cookie)
 	In string
 }
 
@@ -181,6 +196,23 @@ func (a *APIKeyAuth) GetAuthString() string {
 	return a.Key
 }
 
+// Apply adds the authentication to a request
+func (a *APIKeyAuth) Apply(req *http.Request) error {
+	switch a.In {
+	case "header":
+		req.Header.Set(a.Name, a.Key)
+	case "query":
+		query := req.URL.Query()
+		query.Set(a.Name, a.Key)
+		req.URL.RawQuery = query.Encode()
+	case "cookie":
+		req.AddCookie(&http.Cookie{Name: a.Name, Value: a.Key})
+	default:
+		return fmt.Errorf("unsupported API key location: %s", a.In)
+	}
+	return nil
+}
+
 // NoAuth represents no authentication
 type NoAuth struct{}
 
@@ -204,6 +236,11 @@ func (a *NoAuth) GetAuthString() string {
 	return ""
 }
 
+// Apply adds the authentication to a request
+func (a *NoAuth) Apply(req *http.Request) error {
+	return nil
+}
+
 // DetectAuthType detects the authentication type from a string
 func DetectAuthType(auth string) AuthType {
 	if auth == "" {
@@ -237,15 +274,13 @@ func DetectAuthType(auth string) AuthType {
 
 // NewAuthProvider creates a new AuthProvider based on the authentication type
 func NewAuthProvider(auth string) (AuthProvider, error) {
-	// Check if it's an environment variable
-	if strings.HasPrefix(auth, "${") && strings.HasSuffix(auth, "}") {
-		envVar := auth[2 : len(auth)-1]
-		envValue := os.Getenv(envVar)
-		if envValue == "" {
-			log.Warn("Environment variable not set", "var", envVar)
-			return NewNoAuth(), nil
-		}
-		auth = envValue
+	// Resolve environment variables and secret references
+	auth, err := secrets.Resolve(auth)
+	if err != nil {
+		return nil, err
+	}
+	if auth == "" {
+		return NewNoAuth(), nil
 	}
 
 	// Detect the authentication type
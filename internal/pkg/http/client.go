@@ -6,17 +6,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/log"
 )
 
+// redactedValue replaces the credentials of logged requests
+const redactedValue = "<redacted>"
+
 // Client is the HTTP client for making API requests
 type Client struct {
 	// BaseURL is the base URL for the API
@@ -34,6 +34,9 @@ type Client struct {
 	// HTTPClient is the underlying HTTP client
 	HTTPClient *http.Client
 
+	// Retry configures the retries of failed requests; the zero value makes a single attempt
+	Retry RetryPolicy
+
 	// Verbose indicates whether to log verbose output
 	Verbose bool
 }
@@ -71,15 +74,25 @@ type Request struct {
 	// Body is the request body
 	Body interface{}
 
+	// ContentType is the content type the body is encoded with (JSON when empty)
+	ContentType string
+
 	// FormData is the form data
 	FormData map[string]string
 
 	// FormFiles are the files to upload
 	FormFiles map[string]string
 
+	// Parts are the parts of a multipart form, sent before the form data and files
+	Parts []FormPart
+
 	// Auth is the authentication string
 	Auth string
 
+	// AuthProviders are the providers resolved from the operation's security requirements.
+	// When set, they replace the authentication string.
+	AuthProviders []AuthProvider
+
 	// DryRun indicates whether to perform a dry run
 	DryRun bool
 }
@@ -107,56 +120,15 @@ func (c *Client) Execute(req *Request) (*Response, error) {
 	// Start the timer
 	start := time.Now()
 
-	// Create the URL
-	reqURL, err := c.buildURL(req.Path, req.QueryParams)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build URL: %w", err)
-	}
-
-	// Create the request body
-	var reqBody io.Reader
-	var contentType string
-	if req.FormData != nil || req.FormFiles != nil {
-		reqBody, contentType, err = c.createFormBody(req.FormData, req.FormFiles)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create form body: %w", err)
-		}
-	} else if req.Body != nil {
-		reqBody, contentType, err = c.createJSONBody(req.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create JSON body: %w", err)
-		}
-	}
-
 	// Create the HTTP request
-	httpReq, err := http.NewRequest(req.Method, reqURL, reqBody)
+	httpReq, err := c.newHTTPRequest(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Add headers
-	for k, v := range c.Headers {
-		httpReq.Header.Set(k, v)
-	}
-	for k, v := range req.Headers {
-		httpReq.Header.Set(k, v)
-	}
-	if contentType != "" {
-		httpReq.Header.Set("Content-Type", contentType)
-	}
-
-	// Add authentication
-	auth := req.Auth
-	if auth == "" {
-		auth = c.Auth
-	}
-	if auth != "" {
-		c.addAuth(httpReq, auth)
+		return nil, err
 	}
 
 	// Log the request
 	if c.Verbose || req.DryRun {
-		c.logRequest(httpReq, req.Body)
+		c.logRequest(httpReq, req)
 	}
 
 	// If this is a dry run, return a dummy response
@@ -170,10 +142,10 @@ func (c *Client) Execute(req *Request) (*Response, error) {
 		}, nil
 	}
 
-	// Execute the request
-	httpResp, err := c.HTTPClient.Do(httpReq)
+	// Execute the request, retrying failed attempts
+	httpResp, err := c.do(c.HTTPClient, req, httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, err
 	}
 	defer httpResp.Body.Close()
 
@@ -200,6 +172,158 @@ func (c *Client) Execute(req *Request) (*Response, error) {
 	return resp, nil
 }
 
+// do sends an HTTP request with a client, retrying the failed attempts allowed by the
+// retry policy
+func (c *Client) do(client *http.Client, req *Request, httpReq *http.Request) (*http.Response, error) {
+	httpResp, err := c.send(client, req, httpReq)
+	for retry := 1; ; retry++ {
+		wait, reason, ok := c.retryWait(req, httpResp, err, retry)
+		if !ok {
+			if reason != "" {
+				log.Warn("Not retrying request", "reason", reason)
+			}
+			break
+		}
+		if httpResp != nil {
+			// Drain the body so the connection can be reused
+			if _, err := io.Copy(io.Discard, httpResp.Body); err != nil {
+				log.Debug("Failed to drain response body", "error", err)
+			}
+			httpResp.Body.Close()
+		}
+
+		log.Warn("Retrying request", "reason", reason, "retry", retry, "wait", wait.Round(time.Millisecond))
+		time.Sleep(wait)
+
+		httpReq, err = c.newHTTPRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		httpResp, err = c.send(client, req, httpReq)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	return httpResp, nil
+}
+
+// send sends an HTTP request, renewing expired credentials and sending it again
+// once when the request is unauthorized
+func (c *Client) send(client *http.Client, req *Request, httpReq *http.Request) (*http.Response, error) {
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if httpResp.StatusCode == http.StatusUnauthorized && c.refreshAuth(req) {
+		httpResp.Body.Close()
+		log.Debug("Retrying request with refreshed credentials")
+
+		httpReq, err = c.newHTTPRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		return client.Do(httpReq)
+	}
+
+	return httpResp, nil
+}
+
+// newHTTPRequest creates the HTTP request for a request, including its body and authentication
+func (c *Client) newHTTPRequest(req *Request) (*http.Request, error) {
+	// Create the URL
+	reqURL, err := c.buildURL(req.Path, req.QueryParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build URL: %w", err)
+	}
+
+	// Create the request body
+	var reqBody io.Reader
+	var contentType string
+	parts := req.formParts()
+	if len(parts) > 0 && MediaTypeOf(req.ContentType) == MediaTypeForm {
+		if body, ok := urlEncodedBody(parts); ok {
+			reqBody, contentType = body, req.ContentType
+		}
+	}
+	switch {
+	case reqBody != nil:
+	case len(parts) > 0:
+		reqBody, contentType, err = createMultipartBody(parts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create form body: %w", err)
+		}
+	case req.Body != nil:
+		reqBody, err = EncodeBody(req.Body, req.ContentType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request body: %w", err)
+		}
+		contentType = req.ContentType
+		if contentType == "" {
+			contentType = MediaTypeJSON
+		}
+	}
+
+	// Create the HTTP request
+	httpReq, err := http.NewRequest(req.Method, reqURL, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Add headers
+	for k, v := range c.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	for k, v := range req.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+
+	// Add authentication, without obtaining deferred credentials for requests that
+	// aren't sent
+	if req.AuthProviders != nil {
+		for _, provider := range req.AuthProviders {
+			if deferred, ok := provider.(DeferredAuthProvider); ok && req.DryRun {
+				deferred.ApplyPlaceholder(httpReq)
+				continue
+			}
+			if err := provider.Apply(httpReq); err != nil {
+				return nil, fmt.Errorf("failed to apply authentication: %w", err)
+			}
+		}
+	} else {
+		auth := req.Auth
+		if auth == "" {
+			auth = c.Auth
+		}
+		if auth != "" {
+			c.addAuth(httpReq, auth)
+		}
+	}
+
+	return httpReq, nil
+}
+
+// refreshAuth renews the credentials of the refreshable auth providers of a request,
+// reporting whether any of them was refreshed
+func (c *Client) refreshAuth(req *Request) bool {
+	refreshed := false
+	for _, provider := range req.AuthProviders {
+		refreshable, ok := provider.(RefreshableAuthProvider)
+		if !ok {
+			continue
+		}
+		if err := refreshable.Refresh(); err != nil {
+			log.Warn("Failed to refresh credentials", "error", err)
+			continue
+		}
+		refreshed = true
+	}
+	return refreshed
+}
+
 // Get executes a GET request
 func (c *Client) Get(path string, queryParams url.Values, headers map[string]string) (*Response, error) {
 	req := &Request{
@@ -269,8 +393,7 @@ func (c *Client) PostForm(path string, formData map[string]string, formFiles map
 // buildURL builds the full URL for a request
 func (c *Client) buildURL(path string, queryParams url.Values) (string, error) {
 	// Parse the base URL
-	baseURL, err := url.Parse(c.BaseURL)
-	if err != nil {
+	if _, err := url.Parse(c.BaseURL); err != nil {
 		return "", fmt.Errorf("invalid base URL: %w", err)
 	}
 
@@ -280,8 +403,14 @@ func (c *Client) buildURL(path string, queryParams url.Values) (string, error) {
 		return "", fmt.Errorf("invalid path: %w", err)
 	}
 
-	// Resolve the path against the base URL
-	fullURL := baseURL.ResolveReference(pathURL)
+	// Append the path to the base URL, keeping the base URL's own path (such as /v1)
+	fullURL := pathURL
+	if !pathURL.IsAbs() {
+		fullURL, err = url.Parse(strings.TrimRight(c.BaseURL, "/") + "/" + strings.TrimLeft(path, "/"))
+		if err != nil {
+			return "", fmt.Errorf("invalid path: %w", err)
+		}
+	}
 
 	// Add query parameters
 	if queryParams != nil {
@@ -291,67 +420,6 @@ func (c *Client) buildURL(path string, queryParams url.Values) (string, error) {
 	return fullURL.String(), nil
 }
 
-// createJSONBody creates a JSON request body
-func (c *Client) createJSONBody(body interface{}) (io.Reader, string, error) {
-	// Marshal the body to JSON
-	jsonBody, err := json.Marshal(body)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to marshal JSON: %w", err)
-	}
-
-	return bytes.NewReader(jsonBody), "application/json", nil
-}
-
-// createFormBody creates a multipart form request body
-func (c *Client) createFormBody(formData map[string]string, formFiles map[string]string) (io.Reader, string, error) {
-	// Create a buffer to write the form data
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
-
-	// Add form fields
-	for k, v := range formData {
-		if err := writer.WriteField(k, v); err != nil {
-			return nil, "", fmt.Errorf("failed to write form field: %w", err)
-		}
-	}
-
-	// Add form files
-	for k, v := range formFiles {
-		// Check if the value is a file path
-		if strings.HasPrefix(v, "@") {
-			filePath := v[1:]
-			file, err := os.Open(filePath)
-			if err != nil {
-				return nil, "", fmt.Errorf("failed to open file: %w", err)
-			}
-			defer file.Close()
-
-			// Create a form file
-			part, err := writer.CreateFormFile(k, filepath.Base(filePath))
-			if err != nil {
-				return nil, "", fmt.Errorf("failed to create form file: %w", err)
-			}
-
-			// Copy the file to the form
-			if _, err := io.Copy(part, file); err != nil {
-				return nil, "", fmt.Errorf("failed to copy file: %w", err)
-			}
-		} else {
-			// Add as a regular form field
-			if err := writer.WriteField(k, v); err != nil {
-				return nil, "", fmt.Errorf("failed to write form field: %w", err)
-			}
-		}
-	}
-
-	// Close the writer
-	if err := writer.Close(); err != nil {
-		return nil, "", fmt.Errorf("failed to close form writer: %w", err)
-	}
-
-	return &buf, writer.FormDataContentType(), nil
-}
-
 // addAuth adds authentication to a request
 func (c *Client) addAuth(req *http.Request, auth string) {
 	// Check if the auth is a basic auth string (username:password)
@@ -370,16 +438,79 @@ func (c *Client) addAuth(req *http.Request, auth string) {
 	}
 }
 
-// logRequest logs a request
-func (c *Client) logRequest(req *http.Request, body interface{}) {
-	log.Info("Request", "method", req.Method, "url", req.URL.String())
-	log.Info("Request Headers", "headers", req.Header)
-	if body != nil {
+// logRequest logs a request, with its credentials redacted
+func (c *Client) logRequest(httpReq *http.Request, req *Request) {
+	header, reqURL := redactRequest(httpReq, req.AuthProviders)
+	log.Info("Request", "method", httpReq.Method, "url", reqURL)
+	log.Info("Request Headers", "headers", header)
+	switch body := req.Body.(type) {
+	case nil:
+	case []byte:
+		log.Info("Request Body", "body", string(body))
+	case string:
+		log.Info("Request Body", "body", body)
+	default:
 		jsonBody, _ := json.MarshalIndent(body, "", "  ")
 		log.Info("Request Body", "body", string(jsonBody))
 	}
 }
 
+// redactRequest returns the headers and URL of a request with the credentials they carry
+// replaced: the Authorization and Cookie headers, and the API keys of auth providers
+func redactRequest(httpReq *http.Request, providers []AuthProvider) (http.Header, string) {
+	headers := map[string]bool{"Authorization": true, "Proxy-Authorization": true, "Cookie": true, "X-Api-Key": true}
+	queryParams := map[string]bool{}
+	for _, provider := range providers {
+		if apiKey, ok := provider.(*APIKeyAuth); ok {
+			switch apiKey.In {
+			case "header":
+				headers[http.CanonicalHeaderKey(apiKey.Name)] = true
+			case "query":
+				queryParams[apiKey.Name] = true
+			}
+		}
+	}
+
+	// Replace the header values, keeping the auth scheme and the names of cookies
+	header := httpReq.Header.Clone()
+	for name, values := range header {
+		if !headers[name] {
+			continue
+		}
+		for i, value := range values {
+			switch {
+			case value == "Bearer "+OAuth2TokenPlaceholder:
+			case name == "Cookie":
+				cookies := strings.Split(value, ";")
+				for j, cookie := range cookies {
+					cookieName, _, _ := strings.Cut(cookie, "=")
+					cookies[j] = cookieName + "=" + redactedValue
+				}
+				values[i] = strings.Join(cookies, ";")
+			case strings.HasSuffix(name, "Authorization") && strings.Contains(value, " "):
+				scheme, _, _ := strings.Cut(value, " ")
+				values[i] = scheme + " " + redactedValue
+			default:
+				values[i] = redactedValue
+			}
+		}
+	}
+
+	// Replace the API keys in the query
+	reqURL := *httpReq.URL
+	if len(queryParams) > 0 {
+		query := reqURL.Query()
+		for name := range queryParams {
+			if query.Has(name) {
+				query.Set(name, redactedValue)
+			}
+		}
+		reqURL.RawQuery = query.Encode()
+	}
+
+	return header, strings.ReplaceAll(reqURL.String(), url.QueryEscape(redactedValue), redactedValue)
+}
+
 // logResponse logs a response
 func (c *Client) logResponse(resp *Response) {
 	log.Info("Response", "status", resp.StatusCode, "duration", resp.Duration)
@@ -0,0 +1,472 @@
+package http
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Media types of the request and response bodies the client encodes and decodes
+const (
+	// MediaTypeJSON is the media type of JSON bodies
+	MediaTypeJSON = "application/json"
+
+	// MediaTypeForm is the media type of URL-encoded forms
+	MediaTypeForm = "application/x-www-form-urlencoded"
+
+	// MediaTypeMultipart is the media type of multipart forms
+	MediaTypeMultipart = "multipart/form-data"
+
+	// MediaTypeXML is the media type of XML bodies
+	MediaTypeXML = "application/xml"
+
+	// MediaTypeText is the media type of plain text bodies
+	MediaTypeText = "text/plain"
+
+	// MediaTypeBinary is the media type of binary bodies
+	MediaTypeBinary = "application/octet-stream"
+)
+
+// xmlRoot is the name of the root element of XML bodies encoded from data without a
+// single top-level element
+const xmlRoot = "root"
+
+// MediaTypeOf returns the media type of a content type in lowercase, without parameters
+func MediaTypeOf(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+	return mediaType
+}
+
+// IsJSON reports whether a content type is JSON, including +json types
+func IsJSON(contentType string) bool {
+	mediaType := MediaTypeOf(contentType)
+	return mediaType == MediaTypeJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// IsXML reports whether a content type is XML, including +xml types
+func IsXML(contentType string) bool {
+	mediaType := MediaTypeOf(contentType)
+	return mediaType == MediaTypeXML || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// IsYAML reports whether a content type is YAML
+func IsYAML(contentType string) bool {
+	switch mediaType := MediaTypeOf(contentType); mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return true
+	default:
+		return strings.HasSuffix(mediaType, "+yaml")
+	}
+}
+
+// IsCSV reports whether a content type is CSV
+func IsCSV(contentType string) bool {
+	return MediaTypeOf(contentType) == "text/csv"
+}
+
+// requestRank ranks the content types of request bodies by preference
+func requestRank(contentType string) int {
+	mediaType := MediaTypeOf(contentType)
+	switch {
+	case IsJSON(mediaType):
+		return 0
+	case mediaType == MediaTypeForm:
+		return 1
+	case mediaType == MediaTypeMultipart:
+		return 2
+	case IsXML(mediaType):
+		return 3
+	case IsYAML(mediaType):
+		return 4
+	case mediaType == MediaTypeText:
+		return 5
+	case mediaType == MediaTypeBinary:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// responseRank ranks the content types of responses by preference: the types decoded
+// into structured data first
+func responseRank(contentType string) int {
+	mediaType := MediaTypeOf(contentType)
+	switch {
+	case IsJSON(mediaType):
+		return 0
+	case IsYAML(mediaType):
+		return 1
+	case IsXML(mediaType):
+		return 2
+	case IsCSV(mediaType):
+		return 3
+	case ContentStreamType(mediaType) == StreamRecords, ContentStreamType(mediaType) == StreamEvents:
+		return 4
+	case strings.HasPrefix(mediaType, "text/"):
+		return 5
+	default:
+		return 6
+	}
+}
+
+// sortContentTypes returns the concrete content types (not wildcards such as */*)
+// sorted by a rank, then alphabetically
+func sortContentTypes(contentTypes []string, rank func(string) int) []string {
+	var sorted []string
+	for _, contentType := range contentTypes {
+		if !strings.Contains(contentType, "*") {
+			sorted = append(sorted, contentType)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := rank(sorted[i]), rank(sorted[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
+
+// RequestContentType chooses the content type of a request body among the content types
+// an operation accepts, preferring JSON, then URL-encoded and multipart forms, XML, YAML,
+// plain text and binary bodies. It returns an empty string when none is declared.
+func RequestContentType(declared []string) string {
+	sorted := sortContentTypes(declared, requestRank)
+	if len(sorted) == 0 {
+		return ""
+	}
+	return sorted[0]
+}
+
+// AcceptHeader returns an Accept header for the content types of an operation's
+// responses, preferring JSON, YAML, XML and CSV, which are decoded into structured data.
+// It returns an empty string when none is declared.
+func AcceptHeader(declared []string) string {
+	sorted := sortContentTypes(declared, responseRank)
+	for i := range sorted {
+		if i > 0 {
+			sorted[i] += ";q=" + strconv.FormatFloat(max(1-float64(i)/10, 0.1), 'f', 1, 64)
+		}
+	}
+	return strings.Join(sorted, ", ")
+}
+
+// EncodeBody encodes a request body for a content type, JSON by default. Raw bodies
+// ([]byte, and strings for types other than JSON) are sent as they are.
+func EncodeBody(body interface{}, contentType string) (io.Reader, error) {
+	if raw, ok := body.([]byte); ok {
+		return bytes.NewReader(raw), nil
+	}
+
+	mediaType := MediaTypeOf(contentType)
+	if s, ok := body.(string); ok && mediaType != "" && !IsJSON(mediaType) {
+		return strings.NewReader(s), nil
+	}
+
+	switch {
+	case mediaType == "" || IsJSON(mediaType):
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		return bytes.NewReader(data), nil
+	case mediaType == MediaTypeForm:
+		values, err := formValues(body)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(values.Encode()), nil
+	case IsXML(mediaType):
+		data, err := encodeXML(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal XML: %w", err)
+		}
+		return bytes.NewReader(data), nil
+	case IsYAML(mediaType):
+		data, err := yaml.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+		return bytes.NewReader(data), nil
+	case strings.HasPrefix(mediaType, "text/"):
+		return strings.NewReader(formatValue(body)), nil
+	default:
+		return nil, fmt.Errorf("cannot encode a %T body as %s; pass the body as is from a file with --data @file", body, mediaType)
+	}
+}
+
+// formValues converts an object to URL-encoded form values. The items of lists are
+// repeated values, and nested objects are sent as JSON.
+func formValues(body interface{}) (url.Values, error) {
+	object, ok := body.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("a form body must be an object, got %T", body)
+	}
+
+	values := url.Values{}
+	for key, value := range object {
+		if list, ok := value.([]interface{}); ok {
+			for _, item := range list {
+				values.Add(key, formatValue(item))
+			}
+			continue
+		}
+		values.Set(key, formatValue(value))
+	}
+	return values, nil
+}
+
+// formatValue formats a value as text: strings as they are, numbers without exponents,
+// null as nothing, and objects and lists as JSON
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// encodeXML encodes data as an XML document. An object with a single property is the
+// root element; other data is wrapped in a <root> element. Properties prefixed with @
+// are attributes, #text is the text of an element, and the items of lists are repeated
+// elements.
+func encodeXML(body interface{}) ([]byte, error) {
+	name := xmlRoot
+	if object, ok := body.(map[string]interface{}); ok && len(object) == 1 {
+		for key, value := range object {
+			name, body = key, value
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	if err := encodeXMLElement(encoder, name, body); err != nil {
+		return nil, err
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeXMLElement encodes a value as an element, or a list as repeated elements
+func encodeXMLElement(encoder *xml.Encoder, name string, value interface{}) error {
+	if list, ok := value.([]interface{}); ok {
+		for _, item := range list {
+			if err := encodeXMLElement(encoder, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	object, isObject := value.(map[string]interface{})
+
+	// Sort the properties for a stable document, collecting the attributes
+	var keys []string
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if attr, ok := strings.CutPrefix(key, "@"); ok {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: attr}, Value: formatValue(object[key])})
+		}
+	}
+
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	if isObject {
+		if text, ok := object["#text"]; ok {
+			if err := encoder.EncodeToken(xml.CharData(formatValue(text))); err != nil {
+				return err
+			}
+		}
+		for _, key := range keys {
+			if strings.HasPrefix(key, "@") || key == "#text" {
+				continue
+			}
+			if err := encodeXMLElement(encoder, key, object[key]); err != nil {
+				return err
+			}
+		}
+	} else if value != nil {
+		if err := encoder.EncodeToken(xml.CharData(formatValue(value))); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+// DecodeBody decodes a response body into structured data according to its content type:
+// YAML, XML (see decodeXML) and CSV (a list of objects keyed by the header row) bodies,
+// and JSON otherwise. Bodies that can't be decoded are returned as a string, and empty
+// bodies as nil.
+func DecodeBody(body []byte, contentType string) interface{} {
+	var value interface{}
+	var err error
+	switch {
+	case len(body) == 0:
+		return nil
+	case IsYAML(contentType):
+		value, err = decodeYAML(body)
+	case IsXML(contentType):
+		value, err = decodeXML(body)
+	case IsCSV(contentType):
+		value, err = decodeCSV(body)
+	default:
+		err = json.Unmarshal(body, &value)
+		if err == nil {
+			return value
+		}
+	}
+
+	if err != nil {
+		if !IsJSON(contentType) && contentType != "" {
+			log.Debug("Failed to decode response body", "content-type", contentType, "error", err)
+		}
+		return string(body)
+	}
+	return value
+}
+
+// decodeYAML decodes a YAML document into the values JSON decodes to
+func decodeYAML(body []byte) (interface{}, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(body, &value); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// decodeXML decodes an XML document into an object with the root element as its single
+// property. Elements with only text are strings; other elements are objects with their
+// attributes prefixed with @, their text as #text, and their child elements, repeated
+// ones as lists.
+func decodeXML(body []byte) (interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			value, err := decodeXMLElement(decoder, start)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{start.Name.Local: value}, nil
+		}
+	}
+}
+
+// decodeXMLElement decodes the content of an element up to its end
+func decodeXMLElement(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	object := make(map[string]interface{})
+	for _, attr := range start.Attr {
+		object["@"+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+
+			// Collect repeated elements into a list
+			name := t.Name.Local
+			switch existing := object[name].(type) {
+			case nil:
+				object[name] = child
+			case []interface{}:
+				object[name] = append(existing, child)
+			default:
+				object[name] = []interface{}{existing, child}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			content := strings.TrimSpace(text.String())
+			if len(object) == 0 {
+				return content, nil
+			}
+			if content != "" {
+				object["#text"] = content
+			}
+			return object, nil
+		}
+	}
+}
+
+// decodeCSV decodes a CSV document with a header row into a list of objects
+func decodeCSV(body []byte) (interface{}, error) {
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]interface{}, 0, max(len(rows)-1, 0))
+	if len(rows) == 0 {
+		return records, nil
+	}
+	header := rows[0]
+	for _, row := range rows[1:] {
+		record := make(map[string]interface{}, len(header))
+		for i, name := range header {
+			if i < len(row) {
+				record[name] = row[i]
+			} else {
+				record[name] = nil
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,342 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// deviceCodeGrantType is the grant type sent to the token endpoint for the device_code grant
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// DeviceAuthorization is the response of a device authorization endpoint
+type DeviceAuthorization struct {
+	// DeviceCode is the code used to poll the token endpoint
+	DeviceCode string
+
+	// UserCode is the code the user enters on the verification page
+	UserCode string
+
+	// VerificationURI is the page where the user enters the code
+	VerificationURI string
+
+	// VerificationURIComplete is the verification page with the code filled in, if supported
+	VerificationURIComplete string
+
+	// ExpiresIn is how many seconds the codes are valid for
+	ExpiresIn int
+
+	// Interval is how many seconds to wait between polls of the token endpoint
+	Interval int
+}
+
+// LoginWithBrowser runs the authorization code flow with PKCE. The authorization URL
+// is passed to open, and the code is received on a loopback redirect listener.
+func (p *OAuth2Provider) LoginWithBrowser(ctx context.Context, open func(authURL string) error) (*Token, error) {
+	if p.Config.AuthorizationURL == "" {
+		return nil, fmt.Errorf("no OAuth2 authorization URL configured")
+	}
+	if p.Config.ClientID == "" {
+		return nil, fmt.Errorf("no OAuth2 client ID configured")
+	}
+
+	// Listen on the loopback interface for the redirect
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", p.Config.RedirectPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start redirect listener: %w", err)
+	}
+	defer listener.Close()
+	redirectURI := fmt.Sprintf("http://127.0.0.1:%d/callback", listener.Addr().(*net.TCPAddr).Port)
+
+	// Generate the PKCE code verifier and the state
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the authorization URL
+	authURL, err := url.Parse(p.Config.AuthorizationURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization URL: %w", err)
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.Config.ClientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("state", state)
+	query.Set("code_challenge", pkceChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	if len(p.Config.Scopes) > 0 {
+		query.Set("scope", strings.Join(p.Config.Scopes, " "))
+	}
+	for k, v := range p.Config.Params {
+		query.Set(k, v)
+	}
+	authURL.RawQuery = query.Encode()
+
+	// Serve the redirect until the first callback arrives
+	type callbackResult struct {
+		code string
+		err  error
+	}
+	results := make(chan callbackResult, 1)
+	server := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/callback" {
+				http.NotFound(w, r)
+				return
+			}
+
+			params := r.URL.Query()
+			var result callbackResult
+			switch {
+			case params.Get("state") != state:
+				result.err = fmt.Errorf("invalid state in authorization response")
+			case params.Get("error") != "":
+				result.err = authorizationError(params.Get("error"), params.Get("error_description"))
+			case params.Get("code") == "":
+				result.err = fmt.Errorf("no authorization code in authorization response")
+			default:
+				result.code = params.Get("code")
+			}
+
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			if result.err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "Login failed: %v\n", result.err)
+			} else {
+				fmt.Fprintln(w, "Login complete. You can close this window.")
+			}
+
+			select {
+			case results <- result:
+			default:
+			}
+		}),
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	if err := open(authURL.String()); err != nil {
+		return nil, err
+	}
+
+	// Wait for the redirect
+	var result callbackResult
+	select {
+	case result = <-results:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("login aborted: %w", ctx.Err())
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	// Exchange the code for a token
+	log.Debug("Received authorization code, requesting token")
+	token, err := p.requestToken(url.Values{
+		"grant_type":    {GrantAuthorizationCode},
+		"code":          {result.code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p.saveToken(token), nil
+}
+
+// LoginWithDevice runs the device authorization flow. The user code and verification
+// URI are passed to prompt, and the token endpoint is polled until the user approves.
+func (p *OAuth2Provider) LoginWithDevice(ctx context.Context, prompt func(*DeviceAuthorization)) (*Token, error) {
+	if p.Config.DeviceAuthorizationURL == "" {
+		return nil, fmt.Errorf("no OAuth2 device authorization URL configured")
+	}
+	if p.Config.ClientID == "" {
+		return nil, fmt.Errorf("no OAuth2 client ID configured")
+	}
+
+	// Request the device and user codes
+	params := url.Values{}
+	if len(p.Config.Scopes) > 0 {
+		params.Set("scope", strings.Join(p.Config.Scopes, " "))
+	}
+	req, err := p.newClientRequest(p.Config.DeviceAuthorizationURL, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create device authorization request: %w", err)
+	}
+	auth, err := doDeviceAuthorizationRequest(p.HTTPClient, req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	prompt(auth)
+
+	// Stop polling when the codes expire
+	if auth.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(auth.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+
+	// Poll the token endpoint until the user approves or denies the request
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("login aborted: %w", ctx.Err())
+		case <-time.After(interval):
+		}
+
+		token, err := p.requestToken(url.Values{
+			"grant_type":  {deviceCodeGrantType},
+			"device_code": {auth.DeviceCode},
+		})
+		if err == nil {
+			return p.saveToken(token), nil
+		}
+
+		var tokenErr *TokenError
+		if !errors.As(err, &tokenErr) {
+			return nil, err
+		}
+		switch tokenErr.Code {
+		case "authorization_pending":
+			log.Debug("Waiting for the device authorization to be approved")
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return nil, err
+		}
+	}
+}
+
+// Revoke revokes a token at the revocation endpoint, preferring its refresh token
+// so the whole session is ended. Nothing is done without a revocation endpoint.
+func (p *OAuth2Provider) Revoke(token *Token) error {
+	if p.Config.RevocationURL == "" || token == nil {
+		return nil
+	}
+
+	value, hint := token.RefreshToken, "refresh_token"
+	if value == "" {
+		value, hint = token.AccessToken, "access_token"
+	}
+	if value == "" {
+		return nil
+	}
+
+	req, err := p.newClientRequest(p.Config.RevocationURL, url.Values{
+		"token":           {value},
+		"token_type_hint": {hint},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create revocation request: %w", err)
+	}
+
+	resp, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("token revocation failed with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// doDeviceAuthorizationRequest sends a request to a device authorization endpoint and parses the response
+func doDeviceAuthorizationRequest(httpClient *http.Client, req *http.Request) (*DeviceAuthorization, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request device authorization: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device authorization response: %w", err)
+	}
+
+	var authResp struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURL         string `json:"verification_url"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+		Error                   string `json:"error"`
+		ErrorDescription        string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &authResp); err != nil || resp.StatusCode != http.StatusOK {
+		if authResp.Error != "" {
+			return nil, authorizationError(authResp.Error, authResp.ErrorDescription)
+		}
+		return nil, fmt.Errorf("device authorization failed with status %d", resp.StatusCode)
+	}
+	if authResp.DeviceCode == "" || authResp.UserCode == "" {
+		return nil, fmt.Errorf("device authorization response has no device or user code")
+	}
+
+	// Some servers use verification_url instead of verification_uri
+	verificationURI := authResp.VerificationURI
+	if verificationURI == "" {
+		verificationURI = authResp.VerificationURL
+	}
+
+	return &DeviceAuthorization{
+		DeviceCode:              authResp.DeviceCode,
+		UserCode:                authResp.UserCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: authResp.VerificationURIComplete,
+		ExpiresIn:               authResp.ExpiresIn,
+		Interval:                authResp.Interval,
+	}, nil
+}
+
+// authorizationError creates the error for an OAuth2 error response of the authorization server
+func authorizationError(code, description string) error {
+	if description != "" {
+		return fmt.Errorf("authorization failed: %s: %s", code, description)
+	}
+	return fmt.Errorf("authorization failed: %s", code)
+}
+
+// randomString returns a URL-safe random string made from n random bytes
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random string: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// pkceChallenge returns the S256 PKCE code challenge for a code verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
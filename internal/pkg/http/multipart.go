@@ -0,0 +1,350 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// stdinFilename is the file name of a part read from stdin
+const stdinFilename = "stdin"
+
+// ErrStdinRead is returned when stdin is read a second time, as it can only be read once
+var ErrStdinRead = errors.New("stdin has already been read")
+
+// stdinRead is the stdin that has been read
+var stdinRead *os.File
+
+// quoteEscaper escapes the quoted names of the Content-Disposition header of a part
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// FormPart is a part of a multipart form
+type FormPart struct {
+	// Name is the name of the form field
+	Name string
+
+	// Value is the content of the part, unless it is read from a file
+	Value string
+
+	// File is the path of the file the content of the part is read from
+	File string
+
+	// Filename is the file name of the part; the base name of the file by default
+	Filename string
+
+	// ContentType is the content type of the part. File parts without one are typed from
+	// their file name.
+	ContentType string
+
+	// Headers are other headers of the part
+	Headers map[string]string
+}
+
+// PartEncoding is how the values of a form field are sent as parts
+type PartEncoding struct {
+	// ContentType is the content type of the parts, or a comma-separated list of the
+	// allowed ones
+	ContentType string
+
+	// Headers are other headers of the parts
+	Headers map[string]string
+
+	// Binary indicates that the values are file contents: values prefixed with @ are
+	// read from a file, or from stdin with @-
+	Binary bool
+}
+
+// ParseFormPart parses a form field given as name=value or name=@file (@- for stdin).
+// Attributes can follow the value: ;type=<content type> sets the content type of the
+// part and ;filename=<name> its file name.
+func ParseFormPart(value string) (FormPart, error) {
+	name, content, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return FormPart{}, fmt.Errorf("invalid form data format: %s (expected name=value or name=@file)", value)
+	}
+	part := FormPart{Name: name}
+
+	// Split the attributes off the value, keeping other semicolons in it
+	segments := strings.Split(content, ";")
+	content = segments[0]
+	for _, segment := range segments[1:] {
+		if contentType, ok := strings.CutPrefix(segment, "type="); ok {
+			part.ContentType = contentType
+		} else if filename, ok := strings.CutPrefix(segment, "filename="); ok {
+			part.Filename = filename
+		} else {
+			content += ";" + segment
+		}
+	}
+
+	if err := part.setContent(content, true); err != nil {
+		return FormPart{}, err
+	}
+	return part, nil
+}
+
+// ReadStdin reads all of stdin. A second read is rejected with ErrStdinRead rather than
+// getting an empty value.
+func ReadStdin() ([]byte, error) {
+	if stdinRead == os.Stdin {
+		return nil, ErrStdinRead
+	}
+	stdinRead = os.Stdin
+	return io.ReadAll(os.Stdin)
+}
+
+// setContent sets the content of a part: a file when prefixed with @ and files are
+// allowed, or the value itself
+func (p *FormPart) setContent(content string, files bool) error {
+	path, isFile := strings.CutPrefix(content, "@")
+	if !files || !isFile {
+		p.Value = content
+		return nil
+	}
+
+	// Read stdin right away, as it can only be read once
+	if path == "-" {
+		data, err := ReadStdin()
+		if err != nil {
+			return fmt.Errorf("failed to read form field %s from stdin: %w", p.Name, err)
+		}
+		p.Value = string(data)
+		if p.Filename == "" {
+			p.Filename = stdinFilename
+		}
+		return nil
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to open file for form field %s: %w", p.Name, err)
+	}
+	p.File = path
+	return nil
+}
+
+// FormParts converts the properties of an object into the parts of a multipart form,
+// encoded as described for each property. Lists are repeated parts, objects are JSON
+// parts, and other values are text parts.
+func FormParts(data interface{}, encodings map[string]PartEncoding) ([]FormPart, error) {
+	if data == nil {
+		return nil, nil
+	}
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("a multipart body must be an object, got %T", data)
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parts []FormPart
+	for _, name := range names {
+		values, isList := object[name].([]interface{})
+		if !isList {
+			values = []interface{}{object[name]}
+		}
+
+		encoding := encodings[name]
+		for _, value := range values {
+			part := FormPart{Name: name, Headers: encoding.Headers}
+			switch v := value.(type) {
+			case map[string]interface{}, []interface{}:
+				content, err := json.Marshal(v)
+				if err != nil {
+					return nil, fmt.Errorf("failed to marshal form field %s: %w", name, err)
+				}
+				part.Value = string(content)
+				part.applyEncoding(encoding)
+				if part.ContentType == "" {
+					part.ContentType = MediaTypeJSON
+				}
+			default:
+				if err := part.setContent(formatValue(v), encoding.Binary); err != nil {
+					return nil, err
+				}
+				part.applyEncoding(encoding)
+			}
+			parts = append(parts, part)
+		}
+	}
+
+	return parts, nil
+}
+
+// ApplyEncodings sets the content types and headers of parts from the encodings of
+// their fields, keeping the ones given explicitly
+func ApplyEncodings(parts []FormPart, encodings map[string]PartEncoding) {
+	for i := range parts {
+		encoding, ok := encodings[parts[i].Name]
+		if !ok {
+			continue
+		}
+		if parts[i].Headers == nil {
+			parts[i].Headers = encoding.Headers
+		}
+		parts[i].applyEncoding(encoding)
+	}
+}
+
+// applyEncoding sets the content type of a part from an encoding, unless it has one.
+// Of a list of allowed content types, the one matching the file name is used, or the first.
+func (p *FormPart) applyEncoding(encoding PartEncoding) {
+	if encoding.ContentType == "" || p.ContentType != "" {
+		return
+	}
+
+	allowed := strings.Split(encoding.ContentType, ",")
+	p.ContentType = strings.TrimSpace(allowed[0])
+	if detected := p.detectContentType(); detected != "" {
+		for _, contentType := range allowed {
+			if MediaTypeOf(contentType) == MediaTypeOf(detected) {
+				p.ContentType = strings.TrimSpace(contentType)
+			}
+		}
+	}
+}
+
+// filename returns the file name of a part, if it is a file part
+func (p *FormPart) filename() string {
+	if p.Filename != "" {
+		return p.Filename
+	}
+	if p.File != "" {
+		return filepath.Base(p.File)
+	}
+	return ""
+}
+
+// detectContentType returns the content type of a file part from its file name
+func (p *FormPart) detectContentType() string {
+	ext := filepath.Ext(p.filename())
+	if ext == "" {
+		return ""
+	}
+	return mime.TypeByExtension(ext)
+}
+
+// formParts returns the parts of the form of a request: its parts, then its form data
+// and files
+func (r *Request) formParts() []FormPart {
+	parts := append([]FormPart(nil), r.Parts...)
+
+	names := make([]string, 0, len(r.FormData))
+	for name := range r.FormData {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, FormPart{Name: name, Value: r.FormData[name]})
+	}
+
+	names = names[:0]
+	for name := range r.FormFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// Files are given as @path; other values are regular fields
+		part := FormPart{Name: name}
+		if path, ok := strings.CutPrefix(r.FormFiles[name], "@"); ok {
+			part.File = path
+		} else {
+			part.Value = r.FormFiles[name]
+		}
+		parts = append(parts, part)
+	}
+
+	return parts
+}
+
+// urlEncodedBody creates a URL-encoded form request body from parts without files
+func urlEncodedBody(parts []FormPart) (io.Reader, bool) {
+	values := url.Values{}
+	for _, part := range parts {
+		if part.filename() != "" {
+			return nil, false
+		}
+		values.Add(part.Name, part.Value)
+	}
+	return strings.NewReader(values.Encode()), true
+}
+
+// createMultipartBody creates a multipart form request body from parts
+func createMultipartBody(parts []FormPart) (io.Reader, string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	for _, part := range parts {
+		// Describe the part
+		header := make(textproto.MIMEHeader)
+		disposition := fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(part.Name))
+		filename := part.filename()
+		if filename != "" {
+			disposition += fmt.Sprintf(`; filename="%s"`, quoteEscaper.Replace(filename))
+		}
+		header.Set("Content-Disposition", disposition)
+
+		contentType := part.ContentType
+		if contentType == "" && filename != "" {
+			contentType = part.detectContentType()
+			if contentType == "" {
+				contentType = MediaTypeBinary
+			}
+		}
+		if contentType != "" {
+			header.Set("Content-Type", contentType)
+		}
+		for name, value := range part.Headers {
+			header.Set(name, value)
+		}
+
+		w, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create form part: %w", err)
+		}
+
+		// Write the content of the part
+		if part.File == "" {
+			if _, err := io.WriteString(w, part.Value); err != nil {
+				return nil, "", fmt.Errorf("failed to write form field: %w", err)
+			}
+			continue
+		}
+		if err := copyFile(w, part.File); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to close form writer: %w", err)
+	}
+
+	return &buf, writer.FormDataContentType(), nil
+}
+
+// copyFile copies the content of a file to a writer
+func copyFile(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(w, file); err != nil {
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+	return nil
+}
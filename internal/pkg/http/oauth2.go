@@ -0,0 +1,481 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fynxlabs/ontap/internal/pkg/openapi"
+)
+
+// AuthTypeOAuth2 represents OAuth2 authentication
+const AuthTypeOAuth2 AuthType = "oauth2"
+
+// OAuth2 grant types
+const (
+	// GrantClientCredentials is the client credentials grant
+	GrantClientCredentials = "client_credentials"
+
+	// GrantPassword is the resource owner password credentials grant
+	GrantPassword = "password"
+
+	// GrantRefreshToken is the refresh token grant
+	GrantRefreshToken = "refresh_token"
+
+	// GrantAuthorizationCode is the authorization code grant with PKCE, used by 'ontap auth login'
+	GrantAuthorizationCode = "authorization_code"
+
+	// GrantDeviceCode is the device authorization grant, used by 'ontap auth login --device'
+	GrantDeviceCode = "device_code"
+)
+
+// IsLoginGrant checks if a grant needs the user to log in interactively
+func IsLoginGrant(grant string) bool {
+	return grant == GrantAuthorizationCode || grant == GrantDeviceCode
+}
+
+// TokenError is an error response from a token endpoint
+type TokenError struct {
+	// Code is the OAuth2 error code (e.g. invalid_grant)
+	Code string
+
+	// Description is the human-readable description of the error
+	Description string
+}
+
+// Error returns the error message
+func (e *TokenError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("token request failed: %s: %s", e.Code, e.Description)
+	}
+	return fmt.Sprintf("token request failed: %s", e.Code)
+}
+
+// RefreshableAuthProvider is an AuthProvider whose credentials can be renewed after a 401 response
+type RefreshableAuthProvider interface {
+	AuthProvider
+
+	// Refresh discards the current credentials and obtains new ones
+	Refresh() error
+}
+
+// DeferredAuthProvider is an AuthProvider whose credentials are obtained when a request
+// is sent, such as from a token endpoint
+type DeferredAuthProvider interface {
+	AuthProvider
+
+	// ApplyPlaceholder marks where the credentials go on a request that isn't sent,
+	// without obtaining them
+	ApplyPlaceholder(req *http.Request)
+}
+
+// OAuth2TokenPlaceholder stands in for the access token on requests that aren't sent
+const OAuth2TokenPlaceholder = "<oauth2 token>"
+
+// OAuth2Config holds the settings used to obtain OAuth2 tokens
+type OAuth2Config struct {
+	// API is the name of the API the tokens are stored under
+	API string
+
+	// Grant is the grant type (client_credentials, password, refresh_token, authorization_code, device_code)
+	Grant string
+
+	// TokenURL is the token endpoint
+	TokenURL string
+
+	// AuthorizationURL is the authorization endpoint for the authorization_code grant
+	AuthorizationURL string
+
+	// DeviceAuthorizationURL is the device authorization endpoint for the device_code grant
+	DeviceAuthorizationURL string
+
+	// RevocationURL is the token revocation endpoint
+	RevocationURL string
+
+	// RedirectPort is the loopback port for the authorization_code redirect (0 for any free port)
+	RedirectPort int
+
+	// ClientID is the client ID
+	ClientID string
+
+	// ClientSecret is the client secret
+	ClientSecret string
+
+	// ClientAuth is how the client authenticates to the token endpoint (header or body)
+	ClientAuth string
+
+	// Username is the resource owner username for the password grant
+	Username string
+
+	// Password is the resource owner password for the password grant
+	Password string
+
+	// RefreshToken is the refresh token for the refresh_token grant
+	RefreshToken string
+
+	// Scopes are the scopes to request
+	Scopes []string
+
+	// Params are additional parameters to send to the token endpoint (e.g. audience)
+	Params map[string]string
+}
+
+// WithScheme returns a copy of the configuration with the endpoints it doesn't set taken
+// from an oauth2 security scheme. Operations request the scopes they require unless
+// scopes are configured; login sessions only use the configured scopes so that one
+// session serves every operation.
+func (c OAuth2Config) WithScheme(scheme *openapi.SecurityScheme, scopes []string) OAuth2Config {
+	if c.TokenURL == "" {
+		c.TokenURL = SchemeTokenURL(scheme, c.Grant)
+	}
+	if c.AuthorizationURL == "" {
+		c.AuthorizationURL = SchemeAuthorizationURL(scheme)
+	}
+	if len(c.Scopes) == 0 && !IsLoginGrant(c.Grant) {
+		c.Scopes = scopes
+	}
+	return c
+}
+
+// OAuth2Provider is an AuthProvider that obtains access tokens from an OAuth2 token endpoint
+type OAuth2Provider struct {
+	// Config is the OAuth2 configuration
+	Config OAuth2Config
+
+	// Store caches tokens between invocations
+	Store TokenStore
+
+	// HTTPClient is the client used for token requests
+	HTTPClient *http.Client
+
+	// token is the current token
+	token *Token
+}
+
+// NewOAuth2Provider creates a new OAuth2Provider
+func NewOAuth2Provider(config OAuth2Config, store TokenStore, httpClient *http.Client) *OAuth2Provider {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &OAuth2Provider{
+		Config:     config,
+		Store:      store,
+		HTTPClient: httpClient,
+	}
+}
+
+// GetAuthHeader returns the authentication header
+func (p *OAuth2Provider) GetAuthHeader() (string, string) {
+	token, err := p.Token()
+	if err != nil {
+		log.Warn("Failed to get OAuth2 token", "error", err)
+		return "", ""
+	}
+	return "Authorization", "Bearer " + token.AccessToken
+}
+
+// GetAuthType returns the authentication type
+func (p *OAuth2Provider) GetAuthType() AuthType {
+	return AuthTypeOAuth2
+}
+
+// GetAuthString returns the authentication string
+func (p *OAuth2Provider) GetAuthString() string {
+	_, value := p.GetAuthHeader()
+	return value
+}
+
+// Apply adds the authentication to a request
+func (p *OAuth2Provider) Apply(req *http.Request) error {
+	token, err := p.Token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	return nil
+}
+
+// ApplyPlaceholder marks the access token on a request that isn't sent, without
+// requesting one from the token endpoint
+func (p *OAuth2Provider) ApplyPlaceholder(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+OAuth2TokenPlaceholder)
+}
+
+// TokenKey returns the key tokens for this configuration are stored under
+func (p *OAuth2Provider) TokenKey() string {
+	scopes := append([]string(nil), p.Config.Scopes...)
+	sort.Strings(scopes)
+
+	// Sessions from both login flows are shared
+	kind := p.Config.Grant
+	if IsLoginGrant(kind) {
+		kind = "login"
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		kind,
+		p.Config.TokenURL,
+		p.Config.ClientID,
+		p.Config.Username,
+		strings.Join(scopes, " "),
+	}, "\n")))
+
+	return kind + "-" + hex.EncodeToString(sum[:8])
+}
+
+// Token returns a valid access token, using the cached token, refreshing it, or
+// requesting a new one as needed
+func (p *OAuth2Provider) Token() (*Token, error) {
+	if p.token.Valid() {
+		return p.token, nil
+	}
+
+	// Check the token cache
+	cached := p.token
+	if cached == nil && p.Store != nil {
+		var err error
+		cached, err = p.Store.Load(p.Config.API, p.TokenKey())
+		if err != nil {
+			log.Warn("Failed to load cached token", "error", err)
+		}
+		if cached.Valid() {
+			log.Debug("Using cached OAuth2 token", "api", p.Config.API)
+			p.token = cached
+			return cached, nil
+		}
+	}
+
+	// Use the refresh token of an expired token, or the configured one
+	refreshToken := p.Config.RefreshToken
+	if cached != nil && cached.RefreshToken != "" {
+		refreshToken = cached.RefreshToken
+	}
+	if refreshToken != "" {
+		token, err := p.requestToken(url.Values{
+			"grant_type":    {GrantRefreshToken},
+			"refresh_token": {refreshToken},
+		})
+		if err == nil {
+			if token.RefreshToken == "" {
+				token.RefreshToken = refreshToken
+			}
+			return p.saveToken(token), nil
+		}
+		if p.Config.Grant == GrantRefreshToken {
+			return nil, err
+		}
+		if IsLoginGrant(p.Config.Grant) {
+			return nil, fmt.Errorf("session expired (%w); run 'ontap auth login %s'", err, p.Config.API)
+		}
+		log.Debug("Failed to refresh OAuth2 token", "error", err)
+	}
+
+	// Request a new token with the configured grant
+	var params url.Values
+	switch p.Config.Grant {
+	case GrantClientCredentials:
+		params = url.Values{"grant_type": {GrantClientCredentials}}
+	case GrantPassword:
+		params = url.Values{
+			"grant_type": {GrantPassword},
+			"username":   {p.Config.Username},
+			"password":   {p.Config.Password},
+		}
+	case GrantRefreshToken:
+		return nil, fmt.Errorf("no refresh token configured for the refresh_token grant")
+	case GrantAuthorizationCode, GrantDeviceCode:
+		return nil, fmt.Errorf("not logged in; run 'ontap auth login %s'", p.Config.API)
+	default:
+		return nil, fmt.Errorf("unsupported OAuth2 grant: %s", p.Config.Grant)
+	}
+
+	token, err := p.requestToken(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.saveToken(token), nil
+}
+
+// Refresh discards the current access token and obtains a new one
+func (p *OAuth2Provider) Refresh() error {
+	// Keep the refresh token so it can be used for the new access token
+	expired := &Token{}
+	if p.token != nil {
+		expired.RefreshToken = p.token.RefreshToken
+	}
+	p.token = expired
+
+	_, err := p.Token()
+	return err
+}
+
+// saveToken stores a token in memory and in the token cache
+func (p *OAuth2Provider) saveToken(token *Token) *Token {
+	p.token = token
+	if p.Store != nil {
+		if err := p.Store.Save(p.Config.API, p.TokenKey(), token); err != nil {
+			log.Warn("Failed to cache OAuth2 token", "error", err)
+		}
+	}
+	return token
+}
+
+// requestToken requests a token from the token endpoint
+func (p *OAuth2Provider) requestToken(params url.Values) (*Token, error) {
+	if p.Config.TokenURL == "" {
+		return nil, fmt.Errorf("no OAuth2 token URL configured")
+	}
+
+	// Add the scopes and additional parameters
+	grantType := params.Get("grant_type")
+	if len(p.Config.Scopes) > 0 && grantType != GrantRefreshToken && grantType != GrantAuthorizationCode && grantType != deviceCodeGrantType {
+		params.Set("scope", strings.Join(p.Config.Scopes, " "))
+	}
+	for k, v := range p.Config.Params {
+		params.Set(k, v)
+	}
+
+	req, err := p.newClientRequest(p.Config.TokenURL, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+
+	log.Debug("Requesting OAuth2 token", "url", p.Config.TokenURL, "grant", grantType)
+	return doTokenRequest(p.HTTPClient, req)
+}
+
+// newClientRequest creates a form POST to an endpoint of the authorization server,
+// authenticating the client in the body or with basic auth
+func (p *OAuth2Provider) newClientRequest(endpoint string, params url.Values) (*http.Request, error) {
+	useHeader := p.Config.ClientSecret != "" && p.Config.ClientAuth != "body"
+	if !useHeader && p.Config.ClientID != "" {
+		params.Set("client_id", p.Config.ClientID)
+		if p.Config.ClientSecret != "" {
+			params.Set("client_secret", p.Config.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useHeader {
+		req.SetBasicAuth(url.QueryEscape(p.Config.ClientID), url.QueryEscape(p.Config.ClientSecret))
+	}
+
+	return req, nil
+}
+
+// doTokenRequest sends a request to a token endpoint and parses the token response
+func doTokenRequest(httpClient *http.Client, req *http.Request) (*Token, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var tokenResp struct {
+		AccessToken      string          `json:"access_token"`
+		TokenType        string          `json:"token_type"`
+		RefreshToken     string          `json:"refresh_token"`
+		IDToken          string          `json:"id_token"`
+		Scope            string          `json:"scope"`
+		ExpiresIn        json.RawMessage `json:"expires_in"`
+		Error            string          `json:"error"`
+		ErrorDescription string          `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("token request failed with status %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+
+	if tokenResp.Error != "" {
+		return nil, &TokenError{Code: tokenResp.Error, Description: tokenResp.ErrorDescription}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed with status %d", resp.StatusCode)
+	}
+	if tokenResp.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access token")
+	}
+
+	token := &Token{
+		AccessToken:  tokenResp.AccessToken,
+		TokenType:    tokenResp.TokenType,
+		RefreshToken: tokenResp.RefreshToken,
+		IDToken:      tokenResp.IDToken,
+		Scope:        tokenResp.Scope,
+	}
+
+	// Some servers send expires_in as a string
+	if expiresIn, err := strconv.ParseInt(strings.Trim(string(tokenResp.ExpiresIn), `"`), 10, 64); err == nil && expiresIn > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+// SchemeTokenURL returns the token URL declared by an oauth2 security scheme for a grant
+func SchemeTokenURL(scheme *openapi.SecurityScheme, grant string) string {
+	if scheme == nil || scheme.Flows == nil {
+		return ""
+	}
+
+	flows := scheme.Flows
+	switch grant {
+	case GrantClientCredentials:
+		if flows.ClientCredentials != nil {
+			return flows.ClientCredentials.TokenURL
+		}
+	case GrantPassword:
+		if flows.Password != nil {
+			return flows.Password.TokenURL
+		}
+	case GrantAuthorizationCode, GrantDeviceCode:
+		if flows.AuthorizationCode != nil {
+			return flows.AuthorizationCode.TokenURL
+		}
+	default:
+		// Refresh tokens are issued by the flows that have a token endpoint
+		for _, flow := range []*openapi.OAuthFlow{flows.AuthorizationCode, flows.Password, flows.ClientCredentials} {
+			if flow == nil {
+				continue
+			}
+			if flow.RefreshURL != "" {
+				return flow.RefreshURL
+			}
+			if flow.TokenURL != "" {
+				return flow.TokenURL
+			}
+		}
+	}
+
+	return ""
+}
+
+// SchemeAuthorizationURL returns the authorization URL declared by an oauth2 security scheme
+func SchemeAuthorizationURL(scheme *openapi.SecurityScheme) string {
+	if scheme == nil || scheme.Flows == nil || scheme.Flows.AuthorizationCode == nil {
+		return ""
+	}
+	return scheme.Flows.AuthorizationCode.AuthorizationURL
+}
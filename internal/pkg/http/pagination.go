@@ -0,0 +1,436 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/fynxlabs/ontap/internal/pkg/openapi"
+)
+
+// Common body fields holding the items of a page
+var itemsPaths = []string{"items", "data", "results", "records", "entries", "values", "elements", "content", "hits.hits"}
+
+// Common body fields holding the next cursor or the URL of the next page
+var cursorPaths = []string{
+	"next_cursor", "nextCursor", "next_page_token", "nextPageToken", "next_token", "nextToken",
+	"@odata.nextLink", "nextLink", "next",
+	"meta.next_cursor", "meta.nextCursor", "meta.next",
+	"pagination.next_cursor", "pagination.nextCursor", "pagination.next",
+	"response_metadata.next_cursor", "paging.next", "links.next", "_links.next.href",
+}
+
+// Common body fields reporting the total number of items or pages
+var (
+	totalItemsPaths = []string{"total", "total_count", "totalCount", "meta.total", "pagination.total"}
+	totalPagesPaths = []string{"total_pages", "totalPages", "meta.total_pages", "pagination.total_pages"}
+	hasMorePaths    = []string{"has_more", "hasMore", "meta.has_more", "pagination.has_more"}
+)
+
+// Page is a page of results
+type Page struct {
+	// Number is the number of the page, starting at 1
+	Number int
+
+	// Response is the response of the page
+	Response *Response
+
+	// Data is the decoded response body, or the raw body when it isn't JSON
+	Data interface{}
+
+	// Items are the items of the page, or the whole body when it has no list of items
+	Items []interface{}
+
+	// listed reports whether the items were found in a list
+	listed bool
+}
+
+// Paginator follows the pages of a list operation
+type Paginator struct {
+	// Client executes the requests
+	Client *Client
+
+	// Pagination describes how the operation is paginated
+	Pagination openapi.Pagination
+
+	// MaxPages is the maximum number of pages to fetch, or 0 for no limit
+	MaxPages int
+
+	next      *Request
+	pages     int
+	style     string
+	firstSize int
+	seen      map[string]bool
+}
+
+// NewPaginator creates a new Paginator starting with a request
+func NewPaginator(client *Client, req *Request, pagination openapi.Pagination) *Paginator {
+	// Request the configured page size unless it is given
+	if pagination.Limit > 0 && pagination.LimitParam != "" && req.QueryParams.Get(pagination.LimitParam) == "" {
+		req.QueryParams.Set(pagination.LimitParam, strconv.Itoa(pagination.Limit))
+	}
+
+	return &Paginator{
+		Client:     client,
+		Pagination: pagination,
+		next:       req,
+		style:      pagination.Style,
+		seen:       map[string]bool{},
+	}
+}
+
+// Next fetches the next page, returning nil when there are no more pages
+func (p *Paginator) Next() (*Page, error) {
+	if !p.More() || (p.MaxPages > 0 && p.pages >= p.MaxPages) {
+		return nil, nil
+	}
+
+	req := p.next
+	p.next = nil
+	if key, err := p.Client.buildURL(req.Path, req.QueryParams); err == nil {
+		p.seen[key] = true
+	}
+
+	resp, err := p.Client.Execute(req)
+	if err != nil {
+		return nil, err
+	}
+	p.pages++
+
+	page := &Page{Number: p.pages, Response: resp}
+	if req.DryRun {
+		return page, nil
+	}
+
+	// Decode the body, keeping the raw body when it can't be decoded
+	page.Data = DecodeBody(resp.Body, resp.Headers.Get("Content-Type"))
+	page.Items, page.listed = p.items(page.Data)
+
+	// Only successful responses lead to a next page
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		next, err := p.nextRequest(req, resp, page)
+		if err != nil {
+			log.Warn("Stopped following pages", "page", page.Number, "error", err)
+		} else if next != nil {
+			p.next = next
+		}
+	}
+
+	return page, nil
+}
+
+// More reports whether there is a next page that hasn't been fetched
+func (p *Paginator) More() bool {
+	return p.next != nil
+}
+
+// items returns the items of a page, reporting whether they were found in a list
+func (p *Paginator) items(data interface{}) ([]interface{}, bool) {
+	if p.Pagination.ItemsPath != "" {
+		if items, ok := lookupPath(data, p.Pagination.ItemsPath); ok {
+			if list, ok := items.([]interface{}); ok {
+				return list, true
+			}
+		}
+		log.Warn("No list of items found in the response", "path", p.Pagination.ItemsPath)
+		return []interface{}{data}, false
+	}
+
+	if list, ok := data.([]interface{}); ok {
+		return list, true
+	}
+	for _, path := range itemsPaths {
+		if items, ok := lookupPath(data, path); ok {
+			if list, ok := items.([]interface{}); ok {
+				return list, true
+			}
+		}
+	}
+
+	// Use the only list in the body
+	if object, ok := data.(map[string]interface{}); ok {
+		var found []interface{}
+		lists := 0
+		for _, value := range object {
+			if list, ok := value.([]interface{}); ok {
+				found = list
+				lists++
+			}
+		}
+		if lists == 1 {
+			return found, true
+		}
+	}
+
+	return []interface{}{data}, false
+}
+
+// nextRequest returns the request for the page after the given one, or nil on the last page
+func (p *Paginator) nextRequest(req *Request, resp *Response, page *Page) (*Request, error) {
+	if p.firstSize == 0 {
+		p.firstSize = len(page.Items)
+	}
+
+	// Follow the Link header
+	if p.style == "" || p.style == openapi.PaginationLink {
+		if link := nextLink(resp.Headers); link != "" {
+			p.style = openapi.PaginationLink
+			return p.followURL(req, link)
+		}
+		if p.style == openapi.PaginationLink {
+			return nil, nil
+		}
+	}
+
+	// Send the cursor, or follow the next page URL, found in the body
+	if p.style == "" || p.style == openapi.PaginationCursor {
+		if cursor, param, found := p.cursor(page.Data); found {
+			p.style = openapi.PaginationCursor
+			if cursor == "" || isFalse(page.Data, hasMorePaths) {
+				return nil, nil
+			}
+			if isURL(cursor) {
+				return p.followURL(req, cursor)
+			}
+			if param == "" {
+				return nil, fmt.Errorf("no query parameter to send the cursor in; set cursor_param in the pagination config")
+			}
+			return p.withQuery(req, param, cursor)
+		}
+		if p.style == openapi.PaginationCursor {
+			return nil, nil
+		}
+	}
+
+	// Advance the offset or page number while full pages are returned
+	switch {
+	case p.style == openapi.PaginationOffset || (p.style == "" && p.Pagination.OffsetParam != ""):
+		p.style = openapi.PaginationOffset
+		if p.lastPage(req, page) {
+			return nil, nil
+		}
+
+		offset := queryInt(req.QueryParams, p.Pagination.OffsetParam, 0) + len(page.Items)
+		if total, ok := findInt(page.Data, totalItemsPaths); ok && offset >= total {
+			return nil, nil
+		}
+		return p.withQuery(req, p.Pagination.OffsetParam, strconv.Itoa(offset))
+
+	case p.style == openapi.PaginationPage || (p.style == "" && p.Pagination.PageParam != ""):
+		p.style = openapi.PaginationPage
+		if p.lastPage(req, page) {
+			return nil, nil
+		}
+
+		number := queryInt(req.QueryParams, p.Pagination.PageParam, 1)
+		if total, ok := findInt(page.Data, totalPagesPaths); ok && number >= total {
+			return nil, nil
+		}
+		return p.withQuery(req, p.Pagination.PageParam, strconv.Itoa(number+1))
+	}
+
+	return nil, nil
+}
+
+// lastPage reports whether a page of an offset or page paginated list is the last one,
+// which is the case when it holds fewer items than the page size
+func (p *Paginator) lastPage(req *Request, page *Page) bool {
+	if !page.listed || len(page.Items) == 0 {
+		return true
+	}
+
+	limit := queryInt(req.QueryParams, p.Pagination.LimitParam, p.Pagination.Limit)
+	if limit <= 0 {
+		limit = p.firstSize
+	}
+	return len(page.Items) < limit
+}
+
+// cursor returns the next cursor found in a body, with the query parameter to send it in
+func (p *Paginator) cursor(data interface{}) (string, string, bool) {
+	paths := cursorPaths
+	if p.Pagination.CursorPath != "" {
+		paths = []string{p.Pagination.CursorPath}
+	}
+
+	for _, path := range paths {
+		value, ok := lookupPath(data, path)
+		if !ok {
+			continue
+		}
+
+		// A cursor field without a value marks the last page
+		cursor := ""
+		switch v := value.(type) {
+		case string:
+			cursor = v
+		case float64:
+			cursor = strconv.FormatFloat(v, 'f', -1, 64)
+		case nil:
+		default:
+			continue
+		}
+
+		param := p.Pagination.CursorParam
+		if param == "" {
+			param = cursorParam(path)
+		}
+		return cursor, param, true
+	}
+
+	return "", "", false
+}
+
+// followURL returns the request for a next page URL, which must be on the same host
+func (p *Paginator) followURL(req *Request, next string) (*Request, error) {
+	current, err := p.Client.buildURL(req.Path, req.QueryParams)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := url.Parse(next)
+	if err != nil {
+		return nil, fmt.Errorf("invalid next page URL %q: %w", next, err)
+	}
+
+	// Don't send the credentials to another host
+	resolved := base.ResolveReference(ref)
+	if resolved.Host != base.Host {
+		return nil, fmt.Errorf("next page URL %s is on another host", resolved)
+	}
+
+	nextReq := *req
+	nextReq.Path = resolved.String()
+	nextReq.QueryParams = nil
+	return p.visit(&nextReq)
+}
+
+// withQuery returns a copy of a request with a query parameter set
+func (p *Paginator) withQuery(req *Request, name, value string) (*Request, error) {
+	nextReq := *req
+	nextReq.QueryParams = url.Values{}
+	for k, v := range req.QueryParams {
+		nextReq.QueryParams[k] = append([]string(nil), v...)
+	}
+	nextReq.QueryParams.Set(name, value)
+	return p.visit(&nextReq)
+}
+
+// visit checks that the page of a request wasn't fetched before, so a server that
+// keeps returning the same next page doesn't loop forever
+func (p *Paginator) visit(req *Request) (*Request, error) {
+	key, err := p.Client.buildURL(req.Path, req.QueryParams)
+	if err != nil {
+		return nil, err
+	}
+	if p.seen[key] {
+		return nil, fmt.Errorf("page %s was already fetched", key)
+	}
+	return req, nil
+}
+
+// nextLink returns the target of the rel="next" link of the Link headers (RFC 8288)
+func nextLink(headers http.Header) string {
+	for _, header := range headers.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, param := range parts[1:] {
+				name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(name, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// cursorParam derives the query parameter of a cursor from its body field,
+// e.g. next_page_token is sent as page_token and nextCursor as cursor
+func cursorParam(path string) string {
+	name := path[strings.LastIndex(path, ".")+1:]
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "next_"), "next")
+	if name == "" {
+		return "cursor"
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// isURL reports whether a cursor is the URL of the next page
+func isURL(cursor string) bool {
+	return strings.Contains(cursor, "://") || strings.HasPrefix(cursor, "/") || strings.HasPrefix(cursor, "?")
+}
+
+// lookupPath returns the value at a dotted path of a body. Keys that contain dots
+// themselves (such as @odata.nextLink) are matched as a whole first.
+func lookupPath(data interface{}, path string) (interface{}, bool) {
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	if value, ok := object[path]; ok {
+		return value, true
+	}
+
+	key, rest, nested := strings.Cut(path, ".")
+	value, ok := object[key]
+	if !ok {
+		return nil, false
+	}
+	if !nested {
+		return value, true
+	}
+	return lookupPath(value, rest)
+}
+
+// findInt returns the first integer found at one of the paths of a body
+func findInt(data interface{}, paths []string) (int, bool) {
+	for _, path := range paths {
+		if value, ok := lookupPath(data, path); ok {
+			if number, ok := value.(float64); ok {
+				return int(number), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// isFalse reports whether one of the paths of a body is false
+func isFalse(data interface{}, paths []string) bool {
+	for _, path := range paths {
+		if value, ok := lookupPath(data, path); ok {
+			if b, ok := value.(bool); ok {
+				return !b
+			}
+		}
+	}
+	return false
+}
+
+// queryInt returns the integer value of a query parameter, or a fallback
+func queryInt(query url.Values, name string, fallback int) int {
+	if name == "" {
+		return fallback
+	}
+	value, err := strconv.Atoi(query.Get(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,199 @@
+package http
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Parameter styles, which describe how parameters are serialized
+const (
+	StyleForm           = "form"
+	StyleSimple         = "simple"
+	StyleLabel          = "label"
+	StyleMatrix         = "matrix"
+	StyleSpaceDelimited = "spaceDelimited"
+	StylePipeDelimited  = "pipeDelimited"
+	StyleDeepObject     = "deepObject"
+)
+
+// ParameterStyle returns the style and explode of a parameter in a location, filling in
+// the defaults: form for query and cookie parameters and simple for path and header
+// parameters, exploded for the form style only
+func ParameterStyle(in, style string, explode *bool) (string, bool) {
+	if style == "" {
+		style = StyleSimple
+		if in == "query" || in == "cookie" {
+			style = StyleForm
+		}
+	}
+	if explode == nil {
+		return style, style == StyleForm
+	}
+	return style, *explode
+}
+
+// PathValue serializes a path parameter in a style, percent-encoding its values
+func PathValue(name string, value interface{}, style string, explode bool) string {
+	items, pairs := parameterValues(value, url.PathEscape)
+
+	switch style {
+	case StyleLabel:
+		// .a.b when exploded, .a,b otherwise; objects as .k=v.k2=v2 or .k,v,k2,v2
+		if pairs != nil && explode {
+			return "." + joinPairs(pairs, "=", ".")
+		}
+		if pairs != nil {
+			return "." + joinPairs(pairs, ",", ",")
+		}
+		if explode {
+			return "." + strings.Join(items, ".")
+		}
+		return "." + strings.Join(items, ",")
+	case StyleMatrix:
+		// ;name=a;name=b when exploded, ;name=a,b otherwise; objects as ;k=v;k2=v2 or
+		// ;name=k,v,k2,v2
+		name = url.PathEscape(name)
+		if pairs != nil && explode {
+			return ";" + joinPairs(pairs, "=", ";")
+		}
+		if pairs != nil {
+			return ";" + name + "=" + joinPairs(pairs, ",", ",")
+		}
+		if len(items) == 1 && items[0] == "" {
+			return ";" + name
+		}
+		if explode {
+			return ";" + name + "=" + strings.Join(items, ";"+name+"=")
+		}
+		return ";" + name + "=" + strings.Join(items, ",")
+	default:
+		return simpleValue(items, pairs, explode)
+	}
+}
+
+// AddQueryValue adds a query parameter serialized in a style to query values, which
+// percent-encode it when the URL is built
+func AddQueryValue(values url.Values, name string, value interface{}, style string, explode bool) {
+	items, pairs := parameterValues(value, nil)
+
+	switch {
+	case style == StyleDeepObject && pairs != nil:
+		// name[k]=v&name[k2]=v2
+		for _, pair := range pairs {
+			values.Add(name+"["+pair.key+"]", pair.value)
+		}
+	case pairs != nil && explode:
+		// k=v&k2=v2
+		for _, pair := range pairs {
+			values.Add(pair.key, pair.value)
+		}
+	case pairs != nil:
+		// name=k,v,k2,v2
+		values.Add(name, joinPairs(pairs, ",", ","))
+	case explode:
+		// name=a&name=b
+		for _, item := range items {
+			values.Add(name, item)
+		}
+	case style == StyleSpaceDelimited:
+		values.Add(name, strings.Join(items, " "))
+	case style == StylePipeDelimited:
+		values.Add(name, strings.Join(items, "|"))
+	default:
+		values.Add(name, strings.Join(items, ","))
+	}
+}
+
+// HeaderValue serializes a header parameter in the simple style
+func HeaderValue(value interface{}, explode bool) string {
+	items, pairs := parameterValues(value, nil)
+	return simpleValue(items, pairs, explode)
+}
+
+// CookieValue serializes a cookie parameter in the form style as the pairs of a Cookie
+// header, percent-encoding its values
+func CookieValue(name string, value interface{}, explode bool) string {
+	items, pairs := parameterValues(value, url.PathEscape)
+
+	switch {
+	case pairs != nil && explode:
+		return joinPairs(pairs, "=", "; ")
+	case pairs != nil:
+		return name + "=" + joinPairs(pairs, ",", ",")
+	case explode:
+		cookies := make([]string, len(items))
+		for i, item := range items {
+			cookies[i] = name + "=" + item
+		}
+		return strings.Join(cookies, "; ")
+	default:
+		return name + "=" + strings.Join(items, ",")
+	}
+}
+
+// keyValue is a property of an object parameter
+type keyValue struct {
+	key   string
+	value string
+}
+
+// parameterValues returns the values of a parameter, escaped with a function when given:
+// the items of an array (a single item for other values), or the properties of an object
+// sorted by name
+func parameterValues(value interface{}, escape func(string) string) ([]string, []keyValue) {
+	if escape == nil {
+		escape = func(s string) string { return s }
+	}
+
+	switch v := value.(type) {
+	case []string:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = escape(item)
+		}
+		return items, nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = escape(formatValue(item))
+		}
+		return items, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		pairs := make([]keyValue, len(keys))
+		for i, key := range keys {
+			pairs[i] = keyValue{key: escape(key), value: escape(formatValue(v[key]))}
+		}
+		return nil, pairs
+	default:
+		return []string{escape(formatValue(value))}, nil
+	}
+}
+
+// simpleValue serializes values in the simple style: a,b for arrays, and k=v,k2=v2 or
+// k,v,k2,v2 for objects
+func simpleValue(items []string, pairs []keyValue, explode bool) string {
+	if pairs != nil && explode {
+		return joinPairs(pairs, "=", ",")
+	}
+	if pairs != nil {
+		return joinPairs(pairs, ",", ",")
+	}
+	return strings.Join(items, ",")
+}
+
+// joinPairs joins the properties of an object, with a separator between each key and
+// value and another between properties
+func joinPairs(pairs []keyValue, kvSep, sep string) string {
+	joined := make([]string, len(pairs))
+	for i, pair := range pairs {
+		joined[i] = pair.key + kvSep + pair.value
+	}
+	return strings.Join(joined, sep)
+}
@@ -0,0 +1,178 @@
+package http
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// IdempotencyKeyHeader is the header that makes retrying a non-idempotent request safe
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	// DefaultRetryMinWait is the wait before the first retry
+	DefaultRetryMinWait = 500 * time.Millisecond
+
+	// DefaultRetryMaxWait caps the wait between retries
+	DefaultRetryMaxWait = 30 * time.Second
+)
+
+// RetryPolicy configures the retries of requests that fail with a connection error,
+// 429 Too Many Requests or a 5xx status
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of retries after the first attempt
+	MaxRetries int
+
+	// MinWait is the wait before the first retry, doubled for each further retry
+	MinWait time.Duration
+
+	// MaxWait caps the backoff between retries. A request whose Retry-After asks for a
+	// longer wait isn't retried, since retrying it sooner would fail again.
+	MaxWait time.Duration
+}
+
+// NewRetryPolicy creates a new RetryPolicy with the default waits
+func NewRetryPolicy(maxRetries int) RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: maxRetries,
+		MinWait:    DefaultRetryMinWait,
+		MaxWait:    DefaultRetryMaxWait,
+	}
+}
+
+// retryWait returns how long to wait before retrying a failed attempt, reporting
+// whether the request should be retried and why
+func (c *Client) retryWait(req *Request, resp *http.Response, err error, retry int) (time.Duration, string, bool) {
+	policy := c.Retry
+	if retry > policy.MaxRetries {
+		return 0, "", false
+	}
+
+	// Non-idempotent requests are only retried when the server can detect duplicates
+	if !isIdempotent(req.Method) && !c.hasIdempotencyKey(req) {
+		return 0, "", false
+	}
+
+	var reason string
+	switch {
+	case err != nil:
+		if !isConnectionError(err) {
+			return 0, "", false
+		}
+		reason = err.Error()
+	case resp.StatusCode == http.StatusTooManyRequests || isRetryableServerError(resp.StatusCode):
+		reason = resp.Status
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if policy.MaxWait > 0 && wait > policy.MaxWait {
+				return 0, fmt.Sprintf("%s with Retry-After %s exceeding the maximum wait", reason, wait), false
+			}
+			return wait, reason, true
+		}
+	default:
+		return 0, "", false
+	}
+
+	return policy.backoff(retry), reason, true
+}
+
+// backoff returns the exponential wait before a retry, with jitter so that clients
+// failing together don't retry together
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	wait := p.MinWait
+	if wait <= 0 {
+		wait = DefaultRetryMinWait
+	}
+	for i := 1; i < retry && (p.MaxWait <= 0 || wait < p.MaxWait); i++ {
+		wait *= 2
+	}
+	if p.MaxWait > 0 && wait > p.MaxWait {
+		wait = p.MaxWait
+	}
+
+	// Wait between half and all of the backoff
+	jitter, err := rand.Int(rand.Reader, big.NewInt(int64(wait/2)+1))
+	if err != nil {
+		return wait
+	}
+	return wait/2 + time.Duration(jitter.Int64())
+}
+
+// hasIdempotencyKey reports whether an Idempotency-Key header is sent with a request
+func (c *Client) hasIdempotencyKey(req *Request) bool {
+	for _, headers := range []map[string]string{c.Headers, req.Headers} {
+		for name, value := range headers {
+			if http.CanonicalHeaderKey(name) == IdempotencyKeyHeader && value != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isIdempotent reports whether repeating a request with a method has no further effect
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isRetryableServerError reports whether a 5xx status may succeed when retried
+func isRetryableServerError(statusCode int) bool {
+	return statusCode >= 500 && statusCode <= 599 &&
+		statusCode != http.StatusNotImplemented && statusCode != http.StatusHTTPVersionNotSupported
+}
+
+// isConnectionError reports whether a request failed to reach the server, or the
+// connection broke, in a way that may not happen again. Certificate errors are final.
+func isConnectionError(err error) bool {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return false
+	}
+
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalidCert x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var recordHeader tls.RecordHeaderError
+	var verification *tls.CertificateVerificationError
+	switch {
+	case errors.As(err, &unknownAuthority), errors.As(err, &invalidCert), errors.As(err, &hostname),
+		errors.As(err, &recordHeader), errors.As(err, &verification):
+		return false
+	}
+	return true
+}
+
+// retryAfter parses a Retry-After header, given in seconds or as an HTTP date
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// NewIdempotencyKey returns a random key for the Idempotency-Key header (a UUID v4)
+func NewIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
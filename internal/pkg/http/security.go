@@ -0,0 +1,196 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/fynxlabs/ontap/internal/pkg/openapi"
+	"github.com/fynxlabs/ontap/internal/pkg/secrets"
+)
+
+// Credentials holds the configured credentials used to satisfy security requirements.
+// Credentials may be secret references, which are only resolved when they are used.
+type Credentials struct {
+	// Schemes maps security scheme names to credentials
+	Schemes map[string]string
+
+	// Default is used for a requirement with a single scheme that has no credential of its own
+	Default string
+
+	// DefaultCommand is a command whose output is used as the default credential when Default is empty
+	DefaultCommand string
+
+	// Override replaces the credential of a requirement with a single scheme
+	Override string
+
+	// OAuth2 obtains tokens for oauth2 schemes that have no static credential
+	OAuth2 *OAuth2Config
+
+	// TokenStore caches the OAuth2 tokens
+	TokenStore TokenStore
+
+	// HTTPClient is used for OAuth2 token requests
+	HTTPClient *http.Client
+}
+
+// credential returns the credential for a security scheme
+func (c Credentials) credential(name string, single bool) (string, error) {
+	if single && c.Override != "" {
+		return secrets.Resolve(c.Override)
+	}
+	if value := c.schemeCredential(name); value != "" {
+		return secrets.Resolve(value)
+	}
+	if single {
+		if c.Default == "" && c.DefaultCommand != "" {
+			return secrets.RunCommand(c.DefaultCommand)
+		}
+		return secrets.Resolve(c.Default)
+	}
+	return "", nil
+}
+
+// schemeCredential returns the configured credential for a security scheme. Scheme
+// names are matched case-insensitively since config keys are lowercased when loaded.
+func (c Credentials) schemeCredential(name string) string {
+	if value, ok := c.Schemes[name]; ok {
+		return value
+	}
+	for key, value := range c.Schemes {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// ResolveAuth returns the auth providers for the first security requirement of an
+// operation that can be satisfied with the configured credentials. Requirements are
+// alternatives, and all schemes of a requirement must be satisfied together.
+func ResolveAuth(requirements []map[string][]string, schemes map[string]*openapi.SecurityScheme, credentials Credentials) ([]AuthProvider, error) {
+	// Operations that declare no requirements need no auth
+	if len(requirements) == 0 {
+		return []AuthProvider{}, nil
+	}
+
+	var failures []string
+	for _, requirement := range requirements {
+		providers, err := resolveRequirement(requirement, schemes, credentials)
+		if err == nil {
+			return providers, nil
+		}
+		failures = append(failures, err.Error())
+	}
+
+	return nil, fmt.Errorf("no credentials for the security required by this operation (configure one of the following under credentials):\n  %s", strings.Join(failures, "\n  "))
+}
+
+// resolveRequirement creates the auth providers for all schemes of a security requirement
+func resolveRequirement(requirement map[string][]string, schemes map[string]*openapi.SecurityScheme, credentials Credentials) ([]AuthProvider, error) {
+	// Sort the scheme names so providers are applied in a stable order
+	names := make([]string, 0, len(requirement))
+	for name := range requirement {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	providers := []AuthProvider{}
+	for _, name := range names {
+		scheme, ok := schemes[name]
+		if !ok || scheme == nil {
+			return nil, fmt.Errorf("%s: security scheme is not defined in the spec", name)
+		}
+
+		credential, err := credentials.credential(name, len(names) == 1)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		// Obtain tokens for OAuth2 schemes unless a token was given
+		if credential == "" && credentials.OAuth2 != nil && isTokenScheme(scheme) {
+			provider, err := credentials.oauth2Provider(scheme, requirement[name])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			providers = append(providers, provider)
+			continue
+		}
+
+		if credential == "" {
+			return nil, fmt.Errorf("%s: %s", name, DescribeScheme(scheme))
+		}
+
+		provider, err := NewSchemeAuthProvider(scheme, credential)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
+
+// oauth2Provider creates the OAuth2 provider for a scheme, taking the token URL
+// and scopes from the spec unless they are configured
+func (c Credentials) oauth2Provider(scheme *openapi.SecurityScheme, scopes []string) (*OAuth2Provider, error) {
+	config := c.OAuth2.WithScheme(scheme, scopes)
+	if config.TokenURL == "" {
+		return nil, fmt.Errorf("no token URL for the %s grant in the spec; set token_url in the oauth2 config", config.Grant)
+	}
+
+	return NewOAuth2Provider(config, c.TokenStore, c.HTTPClient), nil
+}
+
+// isTokenScheme checks if a security scheme uses OAuth2 access tokens
+func isTokenScheme(scheme *openapi.SecurityScheme) bool {
+	schemeType := strings.ToLower(scheme.Type)
+	return schemeType == "oauth2" || schemeType == "openidconnect"
+}
+
+// NewSchemeAuthProvider creates the AuthProvider for a security scheme and a credential
+func NewSchemeAuthProvider(scheme *openapi.SecurityScheme, credential string) (AuthProvider, error) {
+	switch strings.ToLower(scheme.Type) {
+	case "apikey":
+		in := strings.ToLower(scheme.In)
+		if in != "header" && in != "query" && in != "cookie" {
+			return nil, fmt.Errorf("unsupported API key location: %s", scheme.In)
+		}
+		return NewAPIKeyAuth(credential, scheme.Name, in), nil
+	case "http":
+		switch strings.ToLower(scheme.Scheme) {
+		case "basic":
+			return NewBasicAuthFromString(credential)
+		case "bearer":
+			return NewBearerAuthFromString(credential)
+		default:
+			return nil, fmt.Errorf("unsupported HTTP auth scheme: %s", scheme.Scheme)
+		}
+	case "oauth2", "openidconnect":
+		// Credentials for token-based schemes are access tokens
+		return NewBearerAuthFromString(credential)
+	default:
+		return nil, fmt.Errorf("unsupported security scheme type: %s", scheme.Type)
+	}
+}
+
+// DescribeScheme returns a short description of a security scheme and the credential it expects
+func DescribeScheme(scheme *openapi.SecurityScheme) string {
+	switch strings.ToLower(scheme.Type) {
+	case "apikey":
+		return fmt.Sprintf("API key in %s %q", scheme.In, scheme.Name)
+	case "http":
+		if strings.EqualFold(scheme.Scheme, "basic") {
+			return "HTTP basic auth (username:password)"
+		}
+		return fmt.Sprintf("HTTP %s token", strings.ToLower(scheme.Scheme))
+	case "oauth2":
+		return "OAuth2 access token"
+	case "openidconnect":
+		return "OpenID Connect access token"
+	default:
+		return scheme.Type
+	}
+}
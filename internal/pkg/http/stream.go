@@ -0,0 +1,276 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// maxStreamLineSize is the maximum size of a line of a streamed response
+const maxStreamLineSize = 16 * 1024 * 1024
+
+// StreamType is how the body of a response is read as it arrives
+type StreamType string
+
+const (
+	// StreamNone is a body read as a whole
+	StreamNone StreamType = ""
+
+	// StreamEvents is a body of Server-Sent Events (text/event-stream)
+	StreamEvents StreamType = "events"
+
+	// StreamRecords is a body of newline-delimited JSON records (application/x-ndjson)
+	StreamRecords StreamType = "records"
+
+	// StreamBinary is a binary body, such as a file download
+	StreamBinary StreamType = "binary"
+)
+
+// StreamResponse is an HTTP response whose body is read as it arrives
+type StreamResponse struct {
+	// StatusCode is the HTTP status code
+	StatusCode int
+
+	// Headers are the response headers
+	Headers http.Header
+
+	// Body is the response body, which must be closed
+	Body io.ReadCloser
+
+	// ContentLength is the size of the body, or -1 when unknown
+	ContentLength int64
+
+	// Request is the original request
+	Request *Request
+
+	// start is when the request was sent
+	start time.Time
+}
+
+// Stream executes an HTTP request without reading the response body. The timeout of
+// the client only applies until the response headers are received, so the body can be
+// read for as long as the server sends it. Dry runs return a nil response.
+func (c *Client) Stream(req *Request) (*StreamResponse, error) {
+	start := time.Now()
+
+	// Create the HTTP request
+	httpReq, err := c.newHTTPRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Log the request
+	if c.Verbose || req.DryRun {
+		c.logRequest(httpReq, req)
+	}
+	if req.DryRun {
+		return nil, nil
+	}
+
+	// Execute the request, retrying failed attempts
+	httpResp, err := c.do(c.streamClient(), req, httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Verbose {
+		log.Info("Response", "status", httpResp.StatusCode, "duration", time.Since(start))
+		log.Info("Response Headers", "headers", httpResp.Header)
+	}
+
+	return &StreamResponse{
+		StatusCode:    httpResp.StatusCode,
+		Headers:       httpResp.Header,
+		Body:          httpResp.Body,
+		ContentLength: httpResp.ContentLength,
+		Request:       req,
+		start:         start,
+	}, nil
+}
+
+// Type returns how the body of the response is read as it arrives, from its content type
+func (r *StreamResponse) Type() StreamType {
+	return ContentStreamType(r.Headers.Get("Content-Type"))
+}
+
+// Read reads the whole body of the response and closes it
+func (r *StreamResponse) Read() (*Response, error) {
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return &Response{
+		StatusCode: r.StatusCode,
+		Headers:    r.Headers,
+		Body:       body,
+		Request:    r.Request,
+		Duration:   time.Since(r.start),
+	}, nil
+}
+
+// streamClient returns a copy of the HTTP client without an overall timeout, whose
+// transport waits for the response headers for the timeout of the client instead
+func (c *Client) streamClient() *http.Client {
+	client := *c.HTTPClient
+	client.Timeout = 0
+
+	transport, ok := client.Transport.(*http.Transport)
+	if client.Transport == nil {
+		transport, ok = http.DefaultTransport.(*http.Transport), true
+	}
+	if ok {
+		transport = transport.Clone()
+		transport.ResponseHeaderTimeout = c.Timeout
+		client.Transport = transport
+	}
+
+	return &client
+}
+
+// ContentStreamType returns how a body of a content type is read as it arrives
+func ContentStreamType(contentType string) StreamType {
+	mediaType := MediaTypeOf(contentType)
+	switch {
+	case mediaType == "":
+		return StreamNone
+	case mediaType == "text/event-stream":
+		return StreamEvents
+	case mediaType == "application/x-ndjson", mediaType == "application/ndjson",
+		mediaType == "application/jsonl", mediaType == "application/x-jsonlines",
+		mediaType == "application/json-seq":
+		return StreamRecords
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json", strings.HasSuffix(mediaType, "+json"),
+		mediaType == "application/xml", strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/yaml", mediaType == "application/x-yaml",
+		mediaType == "application/x-www-form-urlencoded":
+		return StreamNone
+	default:
+		return StreamBinary
+	}
+}
+
+// Event is a Server-Sent Event
+type Event struct {
+	// ID is the ID of the event
+	ID string
+
+	// Type is the type of the event; "message" when not set
+	Type string
+
+	// Data is the data of the event, with the lines of multi-line data joined by newlines
+	Data string
+
+	// Retry is the reconnection time requested by the server, or 0
+	Retry time.Duration
+}
+
+// EventReader reads Server-Sent Events from a text/event-stream body
+type EventReader struct {
+	scanner *bufio.Scanner
+}
+
+// NewEventReader creates a new EventReader
+func NewEventReader(r io.Reader) *EventReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLineSize)
+	return &EventReader{scanner: scanner}
+}
+
+// Next returns the next event, or io.EOF at the end of the stream. An incomplete
+// event at the end of the stream is discarded.
+func (r *EventReader) Next() (*Event, error) {
+	event := &Event{}
+	var data []string
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+
+		// Dispatch the event at a blank line, skipping events without data
+		if line == "" {
+			if data == nil {
+				event = &Event{}
+				continue
+			}
+			if event.Type == "" {
+				event.Type = "message"
+			}
+			event.Data = strings.Join(data, "\n")
+			return event, nil
+		}
+
+		// Skip comments, which are often sent to keep the connection open
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Type = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			event.ID = value
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				event.Retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read event stream: %w", err)
+	}
+	return nil, io.EOF
+}
+
+// RecordReader reads the records of a newline-delimited JSON body
+type RecordReader struct {
+	scanner *bufio.Scanner
+}
+
+// NewRecordReader creates a new RecordReader
+func NewRecordReader(r io.Reader) *RecordReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLineSize)
+	return &RecordReader{scanner: scanner}
+}
+
+// Next returns the next record, or io.EOF at the end of the stream. Blank lines are
+// skipped, and lines that aren't JSON are returned as strings.
+func (r *RecordReader) Next() (interface{}, error) {
+	for r.scanner.Scan() {
+		// Strip the record separator of JSON text sequences (application/json-seq)
+		line := strings.TrimSpace(strings.TrimPrefix(r.scanner.Text(), "\x1e"))
+		if line == "" {
+			continue
+		}
+		return DecodeData(line), nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read record stream: %w", err)
+	}
+	return nil, io.EOF
+}
+
+// DecodeData decodes the JSON value of a record or event, returning data that isn't
+// JSON as a string
+func DecodeData(data string) interface{} {
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return data
+	}
+	return value
+}
@@ -0,0 +1,191 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// tokenExpiryDelta is how long before its expiry a token is considered expired
+const tokenExpiryDelta = 30 * time.Second
+
+// Token represents an OAuth2 token
+type Token struct {
+	// AccessToken is the access token
+	AccessToken string `json:"access_token"`
+
+	// TokenType is the type of the token (usually Bearer)
+	TokenType string `json:"token_type,omitempty"`
+
+	// RefreshToken is the token used to get a new access token
+	RefreshToken string `json:"refresh_token,omitempty"`
+
+	// IDToken is the OpenID Connect ID token, if any
+	IDToken string `json:"id_token,omitempty"`
+
+	// Scope is the scope granted to the token
+	Scope string `json:"scope,omitempty"`
+
+	// ExpiresAt is when the access token expires (zero if unknown)
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// Valid checks if the token has an access token that is not about to expire
+func (t *Token) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.ExpiresAt.IsZero() || time.Now().Add(tokenExpiryDelta).Before(t.ExpiresAt)
+}
+
+// TokenStore is the interface for storing OAuth2 tokens
+type TokenStore interface {
+	// Load loads a token, returning nil if there is none
+	Load(api, key string) (*Token, error)
+
+	// Save saves a token
+	Save(api, key string, token *Token) error
+
+	// Delete deletes a token
+	Delete(api, key string) error
+
+	// List lists the keys of the tokens stored for an API
+	List(api string) ([]string, error)
+}
+
+// FileTokenStore stores tokens as files readable only by the current user
+type FileTokenStore struct {
+	// Dir is the directory where tokens are stored
+	Dir string
+}
+
+// NewFileTokenStore creates a new FileTokenStore
+func NewFileTokenStore(dir string) *FileTokenStore {
+	if dir == "" {
+		dir = DefaultTokenDir()
+	}
+	return &FileTokenStore{Dir: dir}
+}
+
+// DefaultTokenDir returns the default directory for stored tokens
+func DefaultTokenDir() string {
+	// Try to get the user config directory (platform-specific)
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		// Fall back to user home directory if UserConfigDir fails
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			log.Warn("Failed to get user home directory", "error", err)
+			return ".ontap/tokens"
+		}
+		return filepath.Join(homeDir, ".ontap", "tokens")
+	}
+
+	return filepath.Join(configDir, "ontap", "tokens")
+}
+
+// Load loads a token, returning nil if there is none
+func (s *FileTokenStore) Load(api, key string) (*Token, error) {
+	data, err := os.ReadFile(s.path(api, key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read token: %w", err)
+	}
+
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// Save saves a token
+func (s *FileTokenStore) Save(api, key string, token *Token) error {
+	dir := filepath.Join(s.Dir, safeFileName(api))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create token directory: %w", err)
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+
+	// Write to a temporary file first so a token is never half-written
+	tmp, err := os.CreateTemp(dir, ".token-*")
+	if err != nil {
+		return fmt.Errorf("failed to create token file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set token file permissions: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write token: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write token: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path(api, key)); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+
+	return nil
+}
+
+// Delete deletes a token
+func (s *FileTokenStore) Delete(api, key string) error {
+	if err := os.Remove(s.path(api, key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete token: %w", err)
+	}
+	return nil
+}
+
+// List lists the keys of the tokens stored for an API
+func (s *FileTokenStore) List(api string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.Dir, safeFileName(api)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list tokens: %w", err)
+	}
+
+	var keys []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			keys = append(keys, strings.TrimSuffix(entry.Name(), ".json"))
+		}
+	}
+
+	return keys, nil
+}
+
+// path returns the path of a token file
+func (s *FileTokenStore) path(api, key string) string {
+	return filepath.Join(s.Dir, safeFileName(api), safeFileName(key)+".json")
+}
+
+// safeFileName replaces characters that are not safe in file names
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
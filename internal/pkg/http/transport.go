@@ -0,0 +1,167 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// unixSocketHost is the host of the base URL of an API reached over a Unix domain socket
+const unixSocketHost = "unix"
+
+// TLSConfig configures the TLS connections to an API
+type TLSConfig struct {
+	// CAFile is a PEM bundle of CA certificates trusted in addition to the system's
+	CAFile string
+
+	// CertFile is the PEM client certificate for mutual TLS
+	CertFile string
+
+	// KeyFile is the PEM private key of the client certificate
+	KeyFile string
+
+	// ServerName is the name to verify the server certificate against, instead of the URL's host
+	ServerName string
+
+	// MinVersion is the minimum TLS version (1.0, 1.1, 1.2, 1.3)
+	MinVersion string
+
+	// InsecureSkipVerify disables the verification of the server certificate
+	InsecureSkipVerify bool
+}
+
+// TransportConfig configures how the client connects to an API
+type TransportConfig struct {
+	// TLS configures the TLS connections
+	TLS TLSConfig
+
+	// Proxy is the URL of the proxy to use (http, https or socks5), or "none" to not
+	// use one. When empty, the proxy is taken from the HTTP_PROXY, HTTPS_PROXY and
+	// NO_PROXY environment variables.
+	Proxy string
+}
+
+// ConfigureTransport sets up the connections of the client. A unix:// base URL
+// (unix:///path/to/socket, optionally followed by :/base/path) sends the requests
+// over a Unix domain socket.
+func (c *Client) ConfigureTransport(config TransportConfig) error {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	// Set up TLS
+	tlsConfig, err := NewTLSConfig(config.TLS)
+	if err != nil {
+		return err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	// Set up the proxy
+	switch config.Proxy {
+	case "":
+		transport.Proxy = http.ProxyFromEnvironment
+	case "none":
+		transport.Proxy = nil
+	default:
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return fmt.Errorf("invalid proxy URL: %s", config.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	// Dial the Unix domain socket for the requests to the API only, so other requests
+	// made with the client (such as OAuth2 token requests) still reach their hosts
+	if socket, baseURL, ok := parseUnixURL(c.BaseURL); ok {
+		c.BaseURL = baseURL
+		transport.Proxy = nil
+
+		dialer := &net.Dialer{}
+		dial := transport.DialContext
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if host, _, err := net.SplitHostPort(addr); err == nil && host == unixSocketHost {
+				return dialer.DialContext(ctx, "unix", socket)
+			}
+			return dial(ctx, network, addr)
+		}
+	}
+
+	c.HTTPClient.Transport = transport
+	return nil
+}
+
+// NewTLSConfig creates a tls.Config from a TLSConfig
+func NewTLSConfig(config TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         config.ServerName,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	if config.InsecureSkipVerify {
+		log.Warn("TLS certificate verification is disabled: the identity of the server is NOT checked and the connection can be intercepted")
+	}
+
+	// Trust the CA bundle in addition to the system's CAs
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			log.Debug("Failed to load the system CA certificates", "error", err)
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file: %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// Load the client certificate
+	if config.CertFile != "" || config.KeyFile != "" {
+		if config.CertFile == "" || config.KeyFile == "" {
+			return nil, fmt.Errorf("both a client certificate and key file are required")
+		}
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	// Set the minimum version
+	switch config.MinVersion {
+	case "":
+	case "1.0":
+		tlsConfig.MinVersion = tls.VersionTLS10
+	case "1.1":
+		tlsConfig.MinVersion = tls.VersionTLS11
+	case "1.2":
+		tlsConfig.MinVersion = tls.VersionTLS12
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported TLS version: %s (supported: 1.0, 1.1, 1.2, 1.3)", config.MinVersion)
+	}
+
+	return tlsConfig, nil
+}
+
+// parseUnixURL splits a unix:// base URL into the path of the socket and the HTTP
+// base URL to send the requests to
+func parseUnixURL(baseURL string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(baseURL, "unix://")
+	if !ok {
+		return "", "", false
+	}
+
+	socket, basePath, _ := strings.Cut(rest, ":")
+	return socket, "http://" + unixSocketHost + basePath, true
+}
@@ -16,6 +16,9 @@ import (
 type OpenAPIVersion string
 
 const (
+	// OpenAPIV20 represents Swagger 2.0
+	OpenAPIV20 OpenAPIVersion = "2.0"
+
 	// OpenAPIV30 represents OpenAPI 3.0.x
 	OpenAPIV30 OpenAPIVersion = "3.0"
 
@@ -83,7 +86,19 @@ func (d *DefaultVersionDetector) DetectVersionFromBytes(data []byte) (OpenAPIVer
 		}
 	}
 
-	return OpenAPIUnknown, fmt.Errorf("unsupported or unrecognized OpenAPI version (only 3.0 and 3.1 are supported)")
+	// Check for Swagger 2.0, whose version YAML reads as a number when it isn't quoted
+	switch swagger := doc["swagger"].(type) {
+	case string:
+		if strings.HasPrefix(swagger, "2.") || swagger == "2" {
+			return OpenAPIV20, nil
+		}
+	case float64:
+		if swagger == 2 {
+			return OpenAPIV20, nil
+		}
+	}
+
+	return OpenAPIUnknown, fmt.Errorf("unsupported or unrecognized OpenAPI version (only Swagger 2.0 and OpenAPI 3.0 and 3.1 are supported)")
 }
 
 // detectVersionFromURL detects the OpenAPI version from a URL
@@ -0,0 +1,93 @@
+package openapi
+
+// PaginationExtension is the vendor extension declaring the pagination of an operation
+const PaginationExtension = "x-pagination"
+
+const (
+	// PaginationLink follows the rel="next" link of the Link header (RFC 8288)
+	PaginationLink = "link"
+
+	// PaginationCursor sends the cursor or follows the next page URL found in the body
+	PaginationCursor = "cursor"
+
+	// PaginationOffset advances an offset parameter by the number of items received
+	PaginationOffset = "offset"
+
+	// PaginationPage increments a page number parameter
+	PaginationPage = "page"
+)
+
+// Common names of the query parameters used for pagination
+var (
+	offsetParamNames = []string{"offset", "skip", "start"}
+	pageParamNames   = []string{"page", "page_number", "pageNumber"}
+	limitParamNames  = []string{"limit", "per_page", "perPage", "page_size", "pageSize", "size"}
+	cursorParamNames = []string{"cursor", "page_token", "pageToken", "next_token", "nextToken", "after"}
+)
+
+// DetectPagination returns the pagination of an endpoint, as declared with the
+// x-pagination extension or with its parameters filled in from the common names
+// of the endpoint's query parameters
+func DetectPagination(endpoint Endpoint) Pagination {
+	var pagination Pagination
+	if endpoint.Pagination != nil {
+		pagination = *endpoint.Pagination
+	}
+
+	params := map[string]bool{}
+	for _, param := range endpoint.Parameters {
+		if param.In == "query" {
+			params[param.Name] = true
+		}
+	}
+
+	if pagination.OffsetParam == "" {
+		pagination.OffsetParam = firstParam(params, offsetParamNames)
+	}
+	if pagination.PageParam == "" {
+		pagination.PageParam = firstParam(params, pageParamNames)
+	}
+	if pagination.LimitParam == "" {
+		pagination.LimitParam = firstParam(params, limitParamNames)
+	}
+	if pagination.CursorParam == "" {
+		pagination.CursorParam = firstParam(params, cursorParamNames)
+	}
+
+	return pagination
+}
+
+// firstParam returns the first of the names that is a parameter
+func firstParam(params map[string]bool, names []string) string {
+	for _, name := range names {
+		if params[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// Merge returns the pagination with the non-empty settings of override applied
+func (p Pagination) Merge(override Pagination) Pagination {
+	for _, field := range []struct {
+		value    *string
+		override string
+	}{
+		{&p.Style, override.Style},
+		{&p.ItemsPath, override.ItemsPath},
+		{&p.CursorPath, override.CursorPath},
+		{&p.CursorParam, override.CursorParam},
+		{&p.OffsetParam, override.OffsetParam},
+		{&p.PageParam, override.PageParam},
+		{&p.LimitParam, override.LimitParam},
+	} {
+		if field.override != "" {
+			*field.value = field.override
+		}
+	}
+	if override.Limit > 0 {
+		p.Limit = override.Limit
+	}
+
+	return p
+}
@@ -141,7 +141,7 @@ func (p *DefaultSpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error) {
 
 		// Process GET operations
 		if pathItem.Get != nil {
-			endpoint, err := p.createEndpoint(path, "GET", pathItem.Get)
+			endpoint, err := p.createEndpoint(path, "GET", pathItem, pathItem.Get)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "GET", "error", err)
 				continue
@@ -151,7 +151,7 @@ func (p *DefaultSpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error) {
 
 		// Process POST operations
 		if pathItem.Post != nil {
-			endpoint, err := p.createEndpoint(path, "POST", pathItem.Post)
+			endpoint, err := p.createEndpoint(path, "POST", pathItem, pathItem.Post)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "POST", "error", err)
 				continue
@@ -161,7 +161,7 @@ func (p *DefaultSpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error) {
 
 		// Process PUT operations
 		if pathItem.Put != nil {
-			endpoint, err := p.createEndpoint(path, "PUT", pathItem.Put)
+			endpoint, err := p.createEndpoint(path, "PUT", pathItem, pathItem.Put)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "PUT", "error", err)
 				continue
@@ -171,7 +171,7 @@ func (p *DefaultSpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error) {
 
 		// Process DELETE operations
 		if pathItem.Delete != nil {
-			endpoint, err := p.createEndpoint(path, "DELETE", pathItem.Delete)
+			endpoint, err := p.createEndpoint(path, "DELETE", pathItem, pathItem.Delete)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "DELETE", "error", err)
 				continue
@@ -181,7 +181,7 @@ func (p *DefaultSpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error) {
 
 		// Process PATCH operations
 		if pathItem.Patch != nil {
-			endpoint, err := p.createEndpoint(path, "PATCH", pathItem.Patch)
+			endpoint, err := p.createEndpoint(path, "PATCH", pathItem, pathItem.Patch)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "PATCH", "error", err)
 				continue
@@ -191,7 +191,7 @@ func (p *DefaultSpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error) {
 
 		// Process OPTIONS operations
 		if pathItem.Options != nil {
-			endpoint, err := p.createEndpoint(path, "OPTIONS", pathItem.Options)
+			endpoint, err := p.createEndpoint(path, "OPTIONS", pathItem, pathItem.Options)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "OPTIONS", "error", err)
 				continue
@@ -201,7 +201,7 @@ func (p *DefaultSpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error) {
 
 		// Process HEAD operations
 		if pathItem.Head != nil {
-			endpoint, err := p.createEndpoint(path, "HEAD", pathItem.Head)
+			endpoint, err := p.createEndpoint(path, "HEAD", pathItem, pathItem.Head)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "HEAD", "error", err)
 				continue
@@ -211,7 +211,7 @@ func (p *DefaultSpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error) {
 
 		// Process TRACE operations
 		if pathItem.Trace != nil {
-			endpoint, err := p.createEndpoint(path, "TRACE", pathItem.Trace)
+			endpoint, err := p.createEndpoint(path, "TRACE", pathItem, pathItem.Trace)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "TRACE", "error", err)
 				continue
@@ -270,11 +270,11 @@ func (p *DefaultSpecParser) GetEndpoint(doc *v3.Document, path, method string) (
 	}
 
 	// Create an endpoint
-	return p.createEndpoint(path, method, operation)
+	return p.createEndpoint(path, method, pathItem, operation)
 }
 
-// createEndpoint creates an Endpoint from an OpenAPI operation
-func (p *DefaultSpecParser) createEndpoint(path, method string, operation *v3.Operation) (*Endpoint, error) {
+// createEndpoint creates an Endpoint from an OpenAPI operation of a path item
+func (p *DefaultSpecParser) createEndpoint(path, method string, pathItem *v3.PathItem, operation *v3.Operation) (*Endpoint, error) {
 	// Check if operation is deprecated
 	deprecated := false
 	if operation.Deprecated != nil {
@@ -295,8 +295,8 @@ func (p *DefaultSpecParser) createEndpoint(path, method string, operation *v3.Op
 		Security:    []map[string][]string{},
 	}
 
-	// Add parameters
-	for _, param := range operation.Parameters {
+	// Add the parameters, of which the operation's override its path's
+	for _, param := range operationParameters(pathItem.Parameters, operation.Parameters) {
 		parameter, err := p.createParameter(param)
 		if err != nil {
 			log.Warn("Failed to create parameter", "name", param.Name, "error", err)
@@ -372,6 +372,8 @@ func (p *DefaultSpecParser) createParameter(param *v3.Parameter) (*Parameter, er
 		Required:    required,
 		Deprecated:  deprecated,
 		Example:     param.Example,
+		Style:       param.Style,
+		Explode:     param.Explode,
 	}
 
 	// Add schema
@@ -399,7 +401,19 @@ func (p *DefaultSpecParser) createSchemaFromProxy(schemaProxy *base.SchemaProxy)
 		return nil, fmt.Errorf("schema is nil")
 	}
 
-	return p.createSchema(schema)
+	s, err := p.createSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	// Name the XML element after the referenced component by default
+	if s.XMLName == "" {
+		if ref := schemaProxy.GetReference(); ref != "" {
+			s.XMLName = ref[strings.LastIndex(ref, "/")+1:]
+		}
+	}
+
+	return s, nil
 }
 
 // createSchema creates a Schema from an OpenAPI schema
@@ -418,6 +432,9 @@ func (p *DefaultSpecParser) createSchema(schema *base.Schema) (*Schema, error) {
 		Example:     schema.Example,
 		Required:    schema.Required,
 	}
+	if schema.XML != nil {
+		s.XMLName = schema.XML.Name
+	}
 
 	// Convert enum values
 	if schema.Enum != nil {
@@ -463,6 +480,7 @@ func (p *DefaultSpecParser) createSchema(schema *base.Schema) (*Schema, error) {
 			}
 
 			s.Properties[propName] = propSchemaObj
+			s.PropertyOrder = append(s.PropertyOrder, propName)
 		}
 	}
 
@@ -537,9 +555,47 @@ func (p *DefaultSpecParser) createMediaType(mediaType *v3.MediaType) (*MediaType
 		mt.Schema = schema
 	}
 
+	// Add the encoding of the properties
+	for encodingPairs := mediaType.Encoding.First(); encodingPairs != nil; encodingPairs = encodingPairs.Next() {
+		if mt.Encoding == nil {
+			mt.Encoding = map[string]*Encoding{}
+		}
+		mt.Encoding[encodingPairs.Key()] = p.createEncoding(encodingPairs.Value())
+	}
+
 	return mt, nil
 }
 
+// createEncoding creates an Encoding from an OpenAPI encoding. Header examples become
+// the examples of the header schemas.
+func (p *DefaultSpecParser) createEncoding(encoding *v3.Encoding) *Encoding {
+	e := &Encoding{ContentType: encoding.ContentType}
+
+	for headerPairs := encoding.Headers.First(); headerPairs != nil; headerPairs = headerPairs.Next() {
+		name := headerPairs.Key()
+		header := headerPairs.Value()
+		if header.Schema == nil {
+			continue
+		}
+
+		schema, err := p.createSchemaFromProxy(header.Schema)
+		if err != nil {
+			log.Warn("Failed to create header schema", "name", name, "error", err)
+			continue
+		}
+		if header.Example != nil && schema.Example == nil {
+			schema.Example = header.Example
+		}
+
+		if e.Headers == nil {
+			e.Headers = map[string]*Schema{}
+		}
+		e.Headers[name] = schema
+	}
+
+	return e
+}
+
 // createResponse creates a Response from an OpenAPI response
 func (p *DefaultSpecParser) createResponse(response *v3.Response) (*Response, error) {
 	if response == nil {
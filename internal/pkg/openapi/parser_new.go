@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/log"
@@ -14,6 +15,8 @@ import (
 	"github.com/pb33f/libopenapi/datamodel"
 	"github.com/pb33f/libopenapi/datamodel/high/base"
 	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
 )
 
 // LibOpenAPISpecParser implements SpecParser using libopenapi
@@ -30,8 +33,19 @@ func NewLibOpenAPISpecParser() *LibOpenAPISpecParser {
 
 // ParseSpec parses an OpenAPI specification from a file or URL
 func (p *LibOpenAPISpecParser) ParseSpec(specPath string) (*v3.Document, error) {
+	// Read the spec
+	data, err := LoadSpecData(specPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.ParseSpecData(data, specPath)
+}
+
+// ParseSpecData parses an OpenAPI specification that was already read from a file or URL
+func (p *LibOpenAPISpecParser) ParseSpecData(data []byte, specPath string) (*v3.Document, error) {
 	// Detect the OpenAPI version
-	version, err := p.detector.DetectVersion(specPath)
+	version, err := p.detector.DetectVersionFromBytes(data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect OpenAPI version: %w", err)
 	}
@@ -41,24 +55,48 @@ func (p *LibOpenAPISpecParser) ParseSpec(specPath string) (*v3.Document, error)
 	// Parse the spec based on the version
 	switch version {
 	case OpenAPIV30, OpenAPIV31:
-		return p.parseOpenAPIV3(specPath)
+		return p.parseOpenAPIV3(data, specPath)
+	case OpenAPIV20:
+		return nil, fmt.Errorf("Swagger 2.0 specifications have no OpenAPI 3 document; use BuildIndexData")
 	default:
 		return nil, fmt.Errorf("unsupported OpenAPI version: %s", version)
 	}
 }
 
-// parseOpenAPIV3 parses an OpenAPI 3.x specification
-func (p *LibOpenAPISpecParser) parseOpenAPIV3(specPath string) (*v3.Document, error) {
-	var data []byte
+// BuildIndexData parses an OpenAPI 3.x or Swagger 2.0 specification that was already
+// read from a file or URL and builds its index
+func (p *LibOpenAPISpecParser) BuildIndexData(data []byte, specPath string) (*SpecIndex, error) {
+	// Detect the OpenAPI version
+	version, err := p.detector.DetectVersionFromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect OpenAPI version: %w", err)
+	}
 
-	// Check if the spec is a URL
-	if strings.HasPrefix(specPath, "http://") || strings.HasPrefix(specPath, "https://") {
-		// Create a configuration with a base URL
-		baseURL, err := url.Parse(specPath)
+	log.Info("Detected OpenAPI version", "version", version)
+
+	// Parse the spec based on the version
+	switch version {
+	case OpenAPIV30, OpenAPIV31:
+		doc, err := p.parseOpenAPIV3(data, specPath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse URL: %w", err)
+			return nil, err
 		}
+		return p.BuildIndex(doc)
+	case OpenAPIV20:
+		doc, err := p.parseSwagger(data, specPath)
+		if err != nil {
+			return nil, err
+		}
+		return p.BuildSwaggerIndex(doc)
+	default:
+		return nil, fmt.Errorf("unsupported OpenAPI version: %s", version)
+	}
+}
 
+// LoadSpecData reads the raw bytes of an OpenAPI specification from a file or URL
+func LoadSpecData(specPath string) ([]byte, error) {
+	// Check if the spec is a URL
+	if isSpecURL(specPath) {
 		// Fetch the spec from the URL
 		resp, err := http.Get(specPath)
 		if err != nil {
@@ -66,64 +104,243 @@ func (p *LibOpenAPISpecParser) parseOpenAPIV3(specPath string) (*v3.Document, er
 		}
 		defer resp.Body.Close()
 
+		if resp.StatusCode >= http.StatusBadRequest {
+			return nil, fmt.Errorf("failed to fetch spec from URL: %s", resp.Status)
+		}
+
 		// Read the response body
-		data, err = io.ReadAll(resp.Body)
+		data, err := io.ReadAll(resp.Body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read response body: %w", err)
 		}
 
-		// Create a document configuration
-		config := &datamodel.DocumentConfiguration{
-			AllowRemoteReferences: true,
-			BaseURL:               baseURL,
-		}
+		return data, nil
+	}
 
-		// Create a new document
-		doc, err := libopenapi.NewDocumentWithConfiguration(data, config)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create document: %w", err)
-		}
+	// Read the spec file
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec file: %w", err)
+	}
+
+	return data, nil
+}
+
+// isSpecURL checks if a spec path is a URL
+func isSpecURL(specPath string) bool {
+	return strings.HasPrefix(specPath, "http://") || strings.HasPrefix(specPath, "https://")
+}
+
+// parseOpenAPIV3 parses an OpenAPI 3.x specification
+func (p *LibOpenAPISpecParser) parseOpenAPIV3(data []byte, specPath string) (*v3.Document, error) {
+	config, err := documentConfig(specPath)
+	if err != nil {
+		return nil, err
+	}
 
-		// Build the V3 model
-		model, errs := doc.BuildV3Model()
-		if len(errs) > 0 {
-			return nil, fmt.Errorf("failed to build model: %v", errs)
+	// Create a new document
+	doc, err := libopenapi.NewDocumentWithConfiguration(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create document: %w", err)
+	}
+
+	// Build the V3 model
+	model, errs := doc.BuildV3Model()
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to build model: %v", errs)
+	}
+
+	return &model.Model, nil
+}
+
+// documentConfig creates a document configuration that resolves references relative
+// to the spec
+func documentConfig(specPath string) (*datamodel.DocumentConfiguration, error) {
+	config := &datamodel.DocumentConfiguration{}
+	if isSpecURL(specPath) {
+		baseURL, err := url.Parse(specPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse URL: %w", err)
 		}
 
-		return &model.Model, nil
+		config.AllowRemoteReferences = true
+		config.BaseURL = baseURL
 	} else {
-		// Get the absolute path
 		absPath, err := filepath.Abs(specPath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get absolute path: %w", err)
 		}
 
-		// Read the spec file
-		data, err = os.ReadFile(absPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read spec file: %w", err)
+		config.AllowFileReferences = true
+		config.BasePath = filepath.Dir(absPath)
+	}
+
+	return config, nil
+}
+
+// BuildIndex builds a SpecIndex from an OpenAPI document
+func (p *LibOpenAPISpecParser) BuildIndex(doc *v3.Document) (*SpecIndex, error) {
+	if doc == nil {
+		return nil, fmt.Errorf("OpenAPI document is nil")
+	}
+
+	// Get the endpoints
+	endpoints, err := p.GetEndpoints(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get endpoints: %w", err)
+	}
+
+	// Create the index
+	index := &SpecIndex{
+		Version:         SpecIndexVersion,
+		Endpoints:       endpoints,
+		SecuritySchemes: map[string]*SecurityScheme{},
+		Servers:         p.createServers(doc.Servers),
+	}
+
+	if doc.Info != nil {
+		index.Title = doc.Info.Title
+	}
+
+	// Operations without their own pagination inherit the document's
+	if pagination := createPagination(doc.Extensions); pagination != nil {
+		for i := range index.Endpoints {
+			if index.Endpoints[i].Pagination == nil {
+				index.Endpoints[i].Pagination = pagination
+			}
 		}
+	}
 
-		// Create a document configuration
-		config := &datamodel.DocumentConfiguration{
-			AllowFileReferences: true,
-			BasePath:            filepath.Dir(absPath),
+	// Operations without their own security requirements inherit the document's
+	if security := createSecurityRequirements(doc.Security); security != nil {
+		for i := range index.Endpoints {
+			if index.Endpoints[i].Security == nil {
+				index.Endpoints[i].Security = security
+			}
 		}
+	}
 
-		// Create a new document
-		doc, err := libopenapi.NewDocumentWithConfiguration(data, config)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create document: %w", err)
+	// Add security schemes
+	if doc.Components != nil && doc.Components.SecuritySchemes != nil {
+		for schemePairs := doc.Components.SecuritySchemes.First(); schemePairs != nil; schemePairs = schemePairs.Next() {
+			index.SecuritySchemes[schemePairs.Key()] = p.createSecurityScheme(schemePairs.Value())
 		}
+	}
+
+	return index, nil
+}
+
+// createSecurityScheme creates a SecurityScheme from an OpenAPI security scheme
+func (p *LibOpenAPISpecParser) createSecurityScheme(scheme *v3.SecurityScheme) *SecurityScheme {
+	s := &SecurityScheme{
+		Type:             scheme.Type,
+		Description:      scheme.Description,
+		Name:             scheme.Name,
+		In:               scheme.In,
+		Scheme:           scheme.Scheme,
+		BearerFormat:     scheme.BearerFormat,
+		OpenIDConnectURL: scheme.OpenIdConnectUrl,
+	}
 
-		// Build the V3 model
-		model, errs := doc.BuildV3Model()
-		if len(errs) > 0 {
-			return nil, fmt.Errorf("failed to build model: %v", errs)
+	// Add OAuth2 flows
+	if scheme.Flows != nil {
+		s.Flows = &OAuthFlows{
+			Implicit:          p.createOAuthFlow(scheme.Flows.Implicit),
+			Password:          p.createOAuthFlow(scheme.Flows.Password),
+			ClientCredentials: p.createOAuthFlow(scheme.Flows.ClientCredentials),
+			AuthorizationCode: p.createOAuthFlow(scheme.Flows.AuthorizationCode),
 		}
+	}
 
-		return &model.Model, nil
+	return s
+}
+
+// createOAuthFlow creates an OAuthFlow from an OpenAPI OAuth flow
+func (p *LibOpenAPISpecParser) createOAuthFlow(flow *v3.OAuthFlow) *OAuthFlow {
+	if flow == nil {
+		return nil
 	}
+
+	f := &OAuthFlow{
+		AuthorizationURL: flow.AuthorizationUrl,
+		TokenURL:         flow.TokenUrl,
+		RefreshURL:       flow.RefreshUrl,
+		Scopes:           map[string]string{},
+	}
+
+	if flow.Scopes != nil {
+		for scopePairs := flow.Scopes.First(); scopePairs != nil; scopePairs = scopePairs.Next() {
+			f.Scopes[scopePairs.Key()] = scopePairs.Value()
+		}
+	}
+
+	return f
+}
+
+// createServers creates a list of Servers from OpenAPI servers
+func (p *LibOpenAPISpecParser) createServers(servers []*v3.Server) []Server {
+	var result []Server
+	for _, server := range servers {
+		if server == nil {
+			continue
+		}
+
+		s := Server{
+			URL:         server.URL,
+			Description: server.Description,
+		}
+
+		// Add server variables
+		if server.Variables != nil && server.Variables.Len() > 0 {
+			s.Variables = map[string]ServerVariable{}
+			for varPairs := server.Variables.First(); varPairs != nil; varPairs = varPairs.Next() {
+				variable := varPairs.Value()
+				s.Variables[varPairs.Key()] = ServerVariable{
+					Enum:        variable.Enum,
+					Default:     variable.Default,
+					Description: variable.Description,
+				}
+			}
+		}
+
+		result = append(result, s)
+	}
+
+	return result
+}
+
+// createPagination decodes the x-pagination extension, returning nil when it isn't declared
+func createPagination(extensions *orderedmap.Map[string, *yaml.Node]) *Pagination {
+	if extensions == nil {
+		return nil
+	}
+	node := extensions.GetOrZero(PaginationExtension)
+	if node == nil {
+		return nil
+	}
+
+	pagination := &Pagination{}
+	if err := node.Decode(pagination); err != nil {
+		log.Warn("Failed to decode pagination extension", "extension", PaginationExtension, "error", err)
+		return nil
+	}
+
+	return pagination
+}
+
+// nodeValue decodes a YAML node into a plain Go value
+func nodeValue(node *yaml.Node) interface{} {
+	if node == nil {
+		return nil
+	}
+
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		log.Debug("Failed to decode value", "error", err)
+		return nil
+	}
+
+	return value
 }
 
 // GetEndpoints returns a list of endpoints from an OpenAPI document
@@ -138,10 +355,11 @@ func (p *LibOpenAPISpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error
 	for pathPairs := doc.Paths.PathItems.First(); pathPairs != nil; pathPairs = pathPairs.Next() {
 		path := pathPairs.Key()
 		pathItem := pathPairs.Value()
+		first := len(endpoints)
 
 		// Process GET operations
 		if pathItem.Get != nil {
-			endpoint, err := p.createEndpoint(path, "GET", pathItem.Get)
+			endpoint, err := p.createEndpoint(path, "GET", pathItem, pathItem.Get)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "GET", "error", err)
 				continue
@@ -151,7 +369,7 @@ func (p *LibOpenAPISpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error
 
 		// Process POST operations
 		if pathItem.Post != nil {
-			endpoint, err := p.createEndpoint(path, "POST", pathItem.Post)
+			endpoint, err := p.createEndpoint(path, "POST", pathItem, pathItem.Post)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "POST", "error", err)
 				continue
@@ -161,7 +379,7 @@ func (p *LibOpenAPISpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error
 
 		// Process PUT operations
 		if pathItem.Put != nil {
-			endpoint, err := p.createEndpoint(path, "PUT", pathItem.Put)
+			endpoint, err := p.createEndpoint(path, "PUT", pathItem, pathItem.Put)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "PUT", "error", err)
 				continue
@@ -171,7 +389,7 @@ func (p *LibOpenAPISpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error
 
 		// Process DELETE operations
 		if pathItem.Delete != nil {
-			endpoint, err := p.createEndpoint(path, "DELETE", pathItem.Delete)
+			endpoint, err := p.createEndpoint(path, "DELETE", pathItem, pathItem.Delete)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "DELETE", "error", err)
 				continue
@@ -181,7 +399,7 @@ func (p *LibOpenAPISpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error
 
 		// Process PATCH operations
 		if pathItem.Patch != nil {
-			endpoint, err := p.createEndpoint(path, "PATCH", pathItem.Patch)
+			endpoint, err := p.createEndpoint(path, "PATCH", pathItem, pathItem.Patch)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "PATCH", "error", err)
 				continue
@@ -191,7 +409,7 @@ func (p *LibOpenAPISpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error
 
 		// Process OPTIONS operations
 		if pathItem.Options != nil {
-			endpoint, err := p.createEndpoint(path, "OPTIONS", pathItem.Options)
+			endpoint, err := p.createEndpoint(path, "OPTIONS", pathItem, pathItem.Options)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "OPTIONS", "error", err)
 				continue
@@ -201,7 +419,7 @@ func (p *LibOpenAPISpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error
 
 		// Process HEAD operations
 		if pathItem.Head != nil {
-			endpoint, err := p.createEndpoint(path, "HEAD", pathItem.Head)
+			endpoint, err := p.createEndpoint(path, "HEAD", pathItem, pathItem.Head)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "HEAD", "error", err)
 				continue
@@ -211,18 +429,53 @@ func (p *LibOpenAPISpecParser) GetEndpoints(doc *v3.Document) ([]Endpoint, error
 
 		// Process TRACE operations
 		if pathItem.Trace != nil {
-			endpoint, err := p.createEndpoint(path, "TRACE", pathItem.Trace)
+			endpoint, err := p.createEndpoint(path, "TRACE", pathItem, pathItem.Trace)
 			if err != nil {
 				log.Warn("Failed to create endpoint", "path", path, "method", "TRACE", "error", err)
 				continue
 			}
 			endpoints = append(endpoints, *endpoint)
 		}
+
+		// Operations without their own servers or pagination use those of their path
+		servers := p.createServers(pathItem.Servers)
+		pagination := createPagination(pathItem.Extensions)
+		for i := first; i < len(endpoints); i++ {
+			if endpoints[i].Servers == nil {
+				endpoints[i].Servers = servers
+			}
+			if endpoints[i].Pagination == nil {
+				endpoints[i].Pagination = pagination
+			}
+		}
 	}
 
 	return endpoints, nil
 }
 
+// operationParameters merges the parameters of a path item with those of one of its
+// operations, which override the path's parameters of the same name and location
+func operationParameters(pathParams, operationParams []*v3.Parameter) []*v3.Parameter {
+	var params []*v3.Parameter
+	for _, param := range pathParams {
+		if param == nil {
+			continue
+		}
+		overridden := slices.ContainsFunc(operationParams, func(op *v3.Parameter) bool {
+			return op != nil && op.Name == param.Name && op.In == param.In
+		})
+		if !overridden {
+			params = append(params, param)
+		}
+	}
+	for _, param := range operationParams {
+		if param != nil {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
 // GetEndpoint returns a specific endpoint from an OpenAPI document
 func (p *LibOpenAPISpecParser) GetEndpoint(doc *v3.Document, path, method string) (*Endpoint, error) {
 	if doc == nil {
@@ -270,11 +523,11 @@ func (p *LibOpenAPISpecParser) GetEndpoint(doc *v3.Document, path, method string
 	}
 
 	// Create an endpoint
-	return p.createEndpoint(path, method, operation)
+	return p.createEndpoint(path, method, pathItem, operation)
 }
 
-// createEndpoint creates an Endpoint from an OpenAPI operation
-func (p *LibOpenAPISpecParser) createEndpoint(path, method string, operation *v3.Operation) (*Endpoint, error) {
+// createEndpoint creates an Endpoint from an OpenAPI operation of a path item
+func (p *LibOpenAPISpecParser) createEndpoint(path, method string, pathItem *v3.PathItem, operation *v3.Operation) (*Endpoint, error) {
 	// Create the endpoint
 	endpoint := &Endpoint{
 		Path:        path,
@@ -285,7 +538,9 @@ func (p *LibOpenAPISpecParser) createEndpoint(path, method string, operation *v3
 		Tags:        operation.Tags,
 		Parameters:  []Parameter{},
 		Responses:   map[string]Response{},
-		Security:    []map[string][]string{},
+		Security:    createSecurityRequirements(operation.Security),
+		Servers:     p.createServers(operation.Servers),
+		Pagination:  createPagination(operation.Extensions),
 	}
 
 	// Set deprecated
@@ -293,8 +548,8 @@ func (p *LibOpenAPISpecParser) createEndpoint(path, method string, operation *v3
 		endpoint.Deprecated = *operation.Deprecated
 	}
 
-	// Add parameters
-	for _, param := range operation.Parameters {
+	// Add the parameters, of which the operation's override its path's
+	for _, param := range operationParameters(pathItem.Parameters, operation.Parameters) {
 		parameter, err := p.createParameter(param)
 		if err != nil {
 			log.Warn("Failed to create parameter", "name", param.Name, "error", err)
@@ -328,23 +583,31 @@ func (p *LibOpenAPISpecParser) createEndpoint(path, method string, operation *v3
 		endpoint.Responses[code] = *resp
 	}
 
-	// Add security
-	if operation.Security != nil {
-		for _, securityRequirement := range operation.Security {
-			securityMap := make(map[string][]string)
+	return endpoint, nil
+}
+
+// createSecurityRequirements converts OpenAPI security requirements. The result is nil
+// when no requirements are declared, and empty when they are declared as empty.
+func createSecurityRequirements(requirements []*base.SecurityRequirement) []map[string][]string {
+	if requirements == nil {
+		return nil
+	}
+
+	security := []map[string][]string{}
+	for _, securityRequirement := range requirements {
+		securityMap := make(map[string][]string)
 
-			// Iterate through the security requirements
+		// Iterate through the security requirements
+		if securityRequirement != nil && securityRequirement.Requirements != nil {
 			for reqPairs := securityRequirement.Requirements.First(); reqPairs != nil; reqPairs = reqPairs.Next() {
-				name := reqPairs.Key()
-				scopes := reqPairs.Value()
-				securityMap[name] = scopes
+				securityMap[reqPairs.Key()] = reqPairs.Value()
 			}
-
-			endpoint.Security = append(endpoint.Security, securityMap)
 		}
+
+		security = append(security, securityMap)
 	}
 
-	return endpoint, nil
+	return security
 }
 
 // createParameter creates a Parameter from an OpenAPI parameter
@@ -358,7 +621,9 @@ func (p *LibOpenAPISpecParser) createParameter(param *v3.Parameter) (*Parameter,
 		Name:        param.Name,
 		In:          param.In,
 		Description: param.Description,
-		Example:     param.Example,
+		Example:     nodeValue(param.Example),
+		Style:       param.Style,
+		Explode:     param.Explode,
 	}
 
 	// Set required
@@ -394,7 +659,19 @@ func (p *LibOpenAPISpecParser) createSchemaFromProxy(schemaProxy *base.SchemaPro
 		return nil, fmt.Errorf("schema is nil")
 	}
 
-	return p.createSchema(schema)
+	s, err := p.createSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	// Name the XML element after the referenced component by default
+	if s.XMLName == "" {
+		if ref := schemaProxy.GetReference(); ref != "" {
+			s.XMLName = ref[strings.LastIndex(ref, "/")+1:]
+		}
+	}
+
+	return s, nil
 }
 
 // createSchema creates a Schema from an OpenAPI schema
@@ -408,17 +685,23 @@ func (p *LibOpenAPISpecParser) createSchema(schema *base.Schema) (*Schema, error
 		Type:        strings.Join(schema.Type, ","), // Convert []string to string
 		Format:      schema.Format,
 		Description: schema.Description,
-		Default:     schema.Default,
+		Default:     nodeValue(schema.Default),
 		Pattern:     schema.Pattern,
-		Example:     schema.Example,
+		Example:     nodeValue(schema.Example),
 		Required:    schema.Required,
+		MinItems:    schema.MinItems,
+		MaxItems:    schema.MaxItems,
+		Nullable:    schema.Nullable != nil && *schema.Nullable,
+	}
+	if schema.XML != nil {
+		s.XMLName = schema.XML.Name
 	}
 
 	// Convert enum values
 	if schema.Enum != nil {
 		for _, enum := range schema.Enum {
 			if enum != nil {
-				s.Enum = append(s.Enum, enum)
+				s.Enum = append(s.Enum, nodeValue(enum))
 			}
 		}
 	}
@@ -458,6 +741,7 @@ func (p *LibOpenAPISpecParser) createSchema(schema *base.Schema) (*Schema, error
 			}
 
 			s.Properties[propName] = propSchemaObj
+			s.PropertyOrder = append(s.PropertyOrder, propName)
 		}
 	}
 
@@ -517,7 +801,7 @@ func (p *LibOpenAPISpecParser) createMediaType(mediaType *v3.MediaType) (*MediaT
 
 	// Create the media type
 	mt := &MediaType{
-		Example: mediaType.Example,
+		Example: nodeValue(mediaType.Example),
 	}
 
 	// Add schema
@@ -530,9 +814,47 @@ func (p *LibOpenAPISpecParser) createMediaType(mediaType *v3.MediaType) (*MediaT
 		mt.Schema = schema
 	}
 
+	// Add the encoding of the properties
+	for encodingPairs := mediaType.Encoding.First(); encodingPairs != nil; encodingPairs = encodingPairs.Next() {
+		if mt.Encoding == nil {
+			mt.Encoding = map[string]*Encoding{}
+		}
+		mt.Encoding[encodingPairs.Key()] = p.createEncoding(encodingPairs.Value())
+	}
+
 	return mt, nil
 }
 
+// createEncoding creates an Encoding from an OpenAPI encoding. Header examples become
+// the examples of the header schemas.
+func (p *LibOpenAPISpecParser) createEncoding(encoding *v3.Encoding) *Encoding {
+	e := &Encoding{ContentType: encoding.ContentType}
+
+	for headerPairs := encoding.Headers.First(); headerPairs != nil; headerPairs = headerPairs.Next() {
+		name := headerPairs.Key()
+		header := headerPairs.Value()
+		if header.Schema == nil {
+			continue
+		}
+
+		schema, err := p.createSchemaFromProxy(header.Schema)
+		if err != nil {
+			log.Warn("Failed to create header schema", "name", name, "error", err)
+			continue
+		}
+		if header.Example != nil && schema.Example == nil {
+			schema.Example = nodeValue(header.Example)
+		}
+
+		if e.Headers == nil {
+			e.Headers = map[string]*Schema{}
+		}
+		e.Headers[name] = schema
+	}
+
+	return e
+}
+
 // createResponse creates a Response from an OpenAPI response
 func (p *LibOpenAPISpecParser) createResponse(response *v3.Response) (*Response, error) {
 	if response == nil {
@@ -0,0 +1,499 @@
+package openapi
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/pb33f/libopenapi"
+	v2 "github.com/pb33f/libopenapi/datamodel/high/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Media types of Swagger 2.0 request bodies
+const (
+	swaggerJSON      = "application/json"
+	swaggerForm      = "application/x-www-form-urlencoded"
+	swaggerMultipart = "multipart/form-data"
+)
+
+// parseSwagger parses a Swagger 2.0 specification
+func (p *LibOpenAPISpecParser) parseSwagger(data []byte, specPath string) (*v2.Swagger, error) {
+	config, err := documentConfig(specPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new document
+	doc, err := libopenapi.NewDocumentWithConfiguration(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create document: %w", err)
+	}
+
+	// Build the V2 model
+	model, errs := doc.BuildV2Model()
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to build model: %v", errs)
+	}
+
+	return &model.Model, nil
+}
+
+// BuildSwaggerIndex builds a SpecIndex from a Swagger 2.0 document, mapping it onto the
+// OpenAPI 3 model: host, basePath and schemes become servers, body and formData
+// parameters become request bodies for the consumed media types, responses have
+// content for the produced media types, and security definitions become schemes.
+func (p *LibOpenAPISpecParser) BuildSwaggerIndex(doc *v2.Swagger) (*SpecIndex, error) {
+	if doc == nil {
+		return nil, fmt.Errorf("Swagger document is nil")
+	}
+
+	index := &SpecIndex{
+		Version:         SpecIndexVersion,
+		Endpoints:       []Endpoint{},
+		SecuritySchemes: map[string]*SecurityScheme{},
+		Servers:         swaggerServers(doc.Host, doc.BasePath, doc.Schemes),
+	}
+
+	if doc.Info != nil {
+		index.Title = doc.Info.Title
+	}
+
+	// Add the operations of each path
+	if doc.Paths != nil {
+		for pathPairs := doc.Paths.PathItems.First(); pathPairs != nil; pathPairs = pathPairs.Next() {
+			path := pathPairs.Key()
+			pathItem := pathPairs.Value()
+
+			operations := []struct {
+				method    string
+				operation *v2.Operation
+			}{
+				{"GET", pathItem.Get},
+				{"POST", pathItem.Post},
+				{"PUT", pathItem.Put},
+				{"DELETE", pathItem.Delete},
+				{"PATCH", pathItem.Patch},
+				{"OPTIONS", pathItem.Options},
+				{"HEAD", pathItem.Head},
+			}
+			for _, op := range operations {
+				if op.operation == nil {
+					continue
+				}
+
+				endpoint := p.createSwaggerEndpoint(doc, path, op.method, pathItem, op.operation)
+				if endpoint.Pagination == nil {
+					endpoint.Pagination = createPagination(pathItem.Extensions)
+				}
+				index.Endpoints = append(index.Endpoints, *endpoint)
+			}
+		}
+	}
+
+	// Operations without their own pagination inherit the document's
+	if pagination := createPagination(doc.Extensions); pagination != nil {
+		for i := range index.Endpoints {
+			if index.Endpoints[i].Pagination == nil {
+				index.Endpoints[i].Pagination = pagination
+			}
+		}
+	}
+
+	// Operations without their own security requirements inherit the document's
+	if security := createSecurityRequirements(doc.Security); security != nil {
+		for i := range index.Endpoints {
+			if index.Endpoints[i].Security == nil {
+				index.Endpoints[i].Security = security
+			}
+		}
+	}
+
+	// Add security schemes
+	if doc.SecurityDefinitions != nil {
+		for schemePairs := doc.SecurityDefinitions.Definitions.First(); schemePairs != nil; schemePairs = schemePairs.Next() {
+			index.SecuritySchemes[schemePairs.Key()] = createSwaggerSecurityScheme(schemePairs.Value())
+		}
+	}
+
+	return index, nil
+}
+
+// swaggerServers creates the servers of a Swagger 2.0 document or operation, one for
+// each scheme. Without schemes, the host is relative to the scheme of the spec, and
+// without a host, the base path (/ by default) is relative to the spec's URL.
+func swaggerServers(host, basePath string, schemes []string) []Server {
+	basePath = strings.TrimRight(basePath, "/")
+	if host == "" {
+		if basePath == "" {
+			basePath = "/"
+		}
+		return []Server{{URL: basePath}}
+	}
+
+	if len(schemes) == 0 {
+		return []Server{{URL: "//" + host + basePath}}
+	}
+
+	var servers []Server
+	for _, scheme := range schemes {
+		servers = append(servers, Server{
+			URL:         scheme + "://" + host + basePath,
+			Description: strings.ToUpper(scheme),
+		})
+	}
+	return servers
+}
+
+// createSwaggerEndpoint creates an Endpoint from a Swagger 2.0 operation and the
+// parameters of its path
+func (p *LibOpenAPISpecParser) createSwaggerEndpoint(doc *v2.Swagger, path, method string, pathItem *v2.PathItem, operation *v2.Operation) *Endpoint {
+	endpoint := &Endpoint{
+		Path:        path,
+		Method:      method,
+		OperationID: operation.OperationId,
+		Summary:     operation.Summary,
+		Description: operation.Description,
+		Tags:        operation.Tags,
+		Deprecated:  operation.Deprecated,
+		Parameters:  []Parameter{},
+		Responses:   map[string]Response{},
+		Security:    createSecurityRequirements(operation.Security),
+		Pagination:  createPagination(operation.Extensions),
+	}
+
+	// Operations with their own schemes are served from those
+	if len(operation.Schemes) > 0 {
+		endpoint.Servers = swaggerServers(doc.Host, doc.BasePath, operation.Schemes)
+	}
+
+	// The operation's media types override the document's
+	consumes := operation.Consumes
+	if consumes == nil {
+		consumes = doc.Consumes
+	}
+	produces := operation.Produces
+	if produces == nil {
+		produces = doc.Produces
+	}
+	if len(produces) == 0 {
+		produces = []string{swaggerJSON}
+	}
+
+	// Add the parameters, of which the operation's override its path's
+	var formParams []*v2.Parameter
+	for _, param := range swaggerParameters(pathItem.Parameters, operation.Parameters) {
+		switch param.In {
+		case "body":
+			endpoint.RequestBody = p.createSwaggerBody(param, consumes)
+		case "formData":
+			formParams = append(formParams, param)
+		default:
+			endpoint.Parameters = append(endpoint.Parameters, *createSwaggerParameter(param))
+		}
+	}
+	if formParams != nil {
+		endpoint.RequestBody = createSwaggerForm(formParams, consumes)
+	}
+
+	// Add responses
+	if operation.Responses != nil {
+		for codePairs := operation.Responses.Codes.First(); codePairs != nil; codePairs = codePairs.Next() {
+			endpoint.Responses[codePairs.Key()] = p.createSwaggerResponse(codePairs.Value(), produces)
+		}
+		if operation.Responses.Default != nil {
+			endpoint.Responses["default"] = p.createSwaggerResponse(operation.Responses.Default, produces)
+		}
+	}
+
+	return endpoint
+}
+
+// swaggerParameters merges the parameters of a path with those of an operation, which
+// override the path's parameters of the same name and location
+func swaggerParameters(pathParams, operationParams []*v2.Parameter) []*v2.Parameter {
+	var params []*v2.Parameter
+	for _, param := range pathParams {
+		if param == nil {
+			continue
+		}
+		overridden := slices.ContainsFunc(operationParams, func(op *v2.Parameter) bool {
+			return op != nil && op.Name == param.Name && op.In == param.In
+		})
+		if !overridden {
+			params = append(params, param)
+		}
+	}
+	for _, param := range operationParams {
+		if param != nil {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// createSwaggerParameter creates a Parameter from a Swagger 2.0 path, query or header
+// parameter. The collection format of arrays becomes their style and explode.
+func createSwaggerParameter(param *v2.Parameter) *Parameter {
+	parameter := &Parameter{
+		Name:        param.Name,
+		In:          param.In,
+		Description: param.Description,
+		Required:    param.Required != nil && *param.Required,
+		Schema:      createSwaggerParameterSchema(param),
+	}
+
+	if param.Type == "array" {
+		explode := false
+		switch param.CollectionFormat {
+		case "multi":
+			explode = true
+		case "ssv":
+			parameter.Style = "spaceDelimited"
+		case "pipes":
+			parameter.Style = "pipeDelimited"
+		case "tsv":
+			log.Warn("Sending tab-separated array parameter as comma-separated", "name", param.Name)
+		}
+		parameter.Explode = &explode
+	}
+
+	return parameter
+}
+
+// createSwaggerParameterSchema creates the Schema of a Swagger 2.0 parameter that isn't
+// a body parameter
+func createSwaggerParameterSchema(param *v2.Parameter) *Schema {
+	schema := &Schema{
+		Type:        param.Type,
+		Format:      param.Format,
+		Description: param.Description,
+		Default:     nodeValue(param.Default),
+		Pattern:     param.Pattern,
+		Items:       createSwaggerItemsSchema(param.Items),
+	}
+
+	// Files are binary strings, as in OpenAPI 3
+	if schema.Type == "file" {
+		schema.Type, schema.Format = "string", "binary"
+	}
+
+	for _, enum := range param.Enum {
+		if enum != nil {
+			schema.Enum = append(schema.Enum, nodeValue(enum))
+		}
+	}
+
+	// Handle the constraints
+	if param.Minimum != nil {
+		minimum := float64(*param.Minimum)
+		schema.Minimum = &minimum
+	}
+	if param.Maximum != nil {
+		maximum := float64(*param.Maximum)
+		schema.Maximum = &maximum
+	}
+	if param.MinLength != nil {
+		minLength := uint64(*param.MinLength)
+		schema.MinLength = &minLength
+	}
+	if param.MaxLength != nil {
+		maxLength := uint64(*param.MaxLength)
+		schema.MaxLength = &maxLength
+	}
+	if param.MinItems != nil {
+		minItems := int64(*param.MinItems)
+		schema.MinItems = &minItems
+	}
+	if param.MaxItems != nil {
+		maxItems := int64(*param.MaxItems)
+		schema.MaxItems = &maxItems
+	}
+
+	return schema
+}
+
+// createSwaggerItemsSchema creates the Schema of the items of a Swagger 2.0 array parameter
+func createSwaggerItemsSchema(items *v2.Items) *Schema {
+	if items == nil {
+		return nil
+	}
+
+	schema := &Schema{
+		Type:    items.Type,
+		Format:  items.Format,
+		Default: nodeValue(items.Default),
+		Pattern: items.Pattern,
+		Items:   createSwaggerItemsSchema(items.Items),
+	}
+	for _, enum := range items.Enum {
+		if enum != nil {
+			schema.Enum = append(schema.Enum, nodeValue(enum))
+		}
+	}
+
+	return schema
+}
+
+// createSwaggerBody creates a RequestBody from a Swagger 2.0 body parameter, with the
+// schema of the parameter for each consumed media type (JSON by default)
+func (p *LibOpenAPISpecParser) createSwaggerBody(param *v2.Parameter, consumes []string) *RequestBody {
+	body := &RequestBody{
+		Description: param.Description,
+		Required:    param.Required != nil && *param.Required,
+		Content:     map[string]*MediaType{},
+	}
+
+	var schema *Schema
+	if param.Schema != nil {
+		var err error
+		schema, err = p.createSchemaFromProxy(param.Schema)
+		if err != nil {
+			log.Warn("Failed to create body schema", "name", param.Name, "error", err)
+		}
+	}
+
+	if len(consumes) == 0 {
+		consumes = []string{swaggerJSON}
+	}
+	for _, mediaType := range consumes {
+		body.Content[mediaType] = &MediaType{Schema: schema}
+	}
+
+	return body
+}
+
+// createSwaggerForm creates a RequestBody from Swagger 2.0 formData parameters, as an
+// object with a property for each parameter. It is sent as a multipart form when the
+// operation consumes one or uploads files, and as a URL-encoded form otherwise.
+func createSwaggerForm(params []*v2.Parameter, consumes []string) *RequestBody {
+	schema := &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{},
+	}
+
+	hasFiles := false
+	for _, param := range params {
+		property := createSwaggerParameterSchema(param)
+		if param.Type == "file" {
+			hasFiles = true
+		}
+
+		schema.Properties[param.Name] = property
+		schema.PropertyOrder = append(schema.PropertyOrder, param.Name)
+		if param.Required != nil && *param.Required {
+			schema.Required = append(schema.Required, param.Name)
+		}
+	}
+
+	body := &RequestBody{
+		Required: len(schema.Required) > 0,
+		Content:  map[string]*MediaType{},
+	}
+	for _, mediaType := range consumes {
+		if mediaType == swaggerForm || mediaType == swaggerMultipart {
+			body.Content[mediaType] = &MediaType{Schema: schema}
+		}
+	}
+	if len(body.Content) == 0 {
+		mediaType := swaggerForm
+		if hasFiles {
+			mediaType = swaggerMultipart
+		}
+		body.Content[mediaType] = &MediaType{Schema: schema}
+	}
+
+	return body
+}
+
+// createSwaggerResponse creates a Response from a Swagger 2.0 response, with its schema
+// for each produced media type
+func (p *LibOpenAPISpecParser) createSwaggerResponse(response *v2.Response, produces []string) Response {
+	r := Response{
+		Content: map[string]*MediaType{},
+		Headers: map[string]*Schema{},
+	}
+	if response == nil {
+		return r
+	}
+	r.Description = response.Description
+
+	// Add content
+	if response.Schema != nil {
+		schema, err := p.createSchemaFromProxy(response.Schema)
+		if err != nil {
+			log.Warn("Failed to create response schema", "error", err)
+		} else {
+			// File responses are binary strings, as in OpenAPI 3
+			if schema.Type == "file" {
+				schema.Type, schema.Format = "string", "binary"
+			}
+			for _, mediaType := range produces {
+				r.Content[mediaType] = &MediaType{Schema: schema}
+			}
+		}
+	}
+
+	// Add headers
+	for headerPairs := response.Headers.First(); headerPairs != nil; headerPairs = headerPairs.Next() {
+		header := headerPairs.Value()
+		if header == nil {
+			continue
+		}
+		// The default of a header is held as an untyped YAML node
+		defaultNode, _ := header.Default.(*yaml.Node)
+		r.Headers[headerPairs.Key()] = &Schema{
+			Type:        header.Type,
+			Format:      header.Format,
+			Description: header.Description,
+			Default:     nodeValue(defaultNode),
+			Pattern:     header.Pattern,
+			Items:       createSwaggerItemsSchema(header.Items),
+		}
+	}
+
+	return r
+}
+
+// createSwaggerSecurityScheme creates a SecurityScheme from a Swagger 2.0 security
+// definition: basic becomes HTTP basic auth, and the OAuth2 flows are mapped onto
+// their OpenAPI 3 names
+func createSwaggerSecurityScheme(scheme *v2.SecurityScheme) *SecurityScheme {
+	s := &SecurityScheme{
+		Type:        scheme.Type,
+		Description: scheme.Description,
+		Name:        scheme.Name,
+		In:          scheme.In,
+	}
+
+	switch scheme.Type {
+	case "basic":
+		s.Type, s.Scheme = "http", "basic"
+	case "oauth2":
+		flow := &OAuthFlow{
+			AuthorizationURL: scheme.AuthorizationUrl,
+			TokenURL:         scheme.TokenUrl,
+			Scopes:           map[string]string{},
+		}
+		if scheme.Scopes != nil {
+			for scopePairs := scheme.Scopes.Values.First(); scopePairs != nil; scopePairs = scopePairs.Next() {
+				flow.Scopes[scopePairs.Key()] = scopePairs.Value()
+			}
+		}
+
+		s.Flows = &OAuthFlows{}
+		switch scheme.Flow {
+		case "implicit":
+			s.Flows.Implicit = flow
+		case "password":
+			s.Flows.Password = flow
+		case "application":
+			s.Flows.ClientCredentials = flow
+		case "accessCode":
+			s.Flows.AuthorizationCode = flow
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,117 @@
+package openapi
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxPropertyDepth limits how deep PropertyPaths descends into nested objects
+const maxPropertyDepth = 5
+
+// SuccessSchema returns the schema of the JSON body of the endpoint's success
+// response (the first declared 2xx response, or the default response)
+func (e Endpoint) SuccessSchema() *Schema {
+	codes := make([]string, 0, len(e.Responses))
+	for code := range e.Responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	codes = append(codes, "default")
+
+	for _, code := range codes {
+		response, ok := e.Responses[code]
+		if !ok {
+			continue
+		}
+		for _, contentType := range sortedContentTypes(response.Content) {
+			if isJSONContentType(contentType) && response.Content[contentType].Schema != nil {
+				return response.Content[contentType].Schema
+			}
+		}
+	}
+	return nil
+}
+
+// PropertyPaths returns the properties of an object schema, or of the items of an
+// array schema, in the order they are declared. The properties of nested objects
+// follow their parent as dotted paths (metadata.name).
+func (s *Schema) PropertyPaths() []string {
+	if s == nil {
+		return nil
+	}
+	if s.Items != nil && len(s.Properties) == 0 {
+		return s.Items.PropertyPaths()
+	}
+
+	var paths []string
+	s.appendPropertyPaths("", 0, &paths)
+	return paths
+}
+
+// appendPropertyPaths appends the property paths of the schema under a prefix
+func (s *Schema) appendPropertyPaths(prefix string, depth int, paths *[]string) {
+	if depth >= maxPropertyDepth {
+		return
+	}
+
+	for _, name := range s.propertyNames() {
+		path := prefix + name
+		*paths = append(*paths, path)
+		if property := s.Properties[name]; property != nil && len(property.Properties) > 0 {
+			property.appendPropertyPaths(path+".", depth+1, paths)
+		}
+	}
+}
+
+// propertyNames returns the names of the properties in declaration order, falling
+// back to alphabetical order for schemas without one
+func (s *Schema) propertyNames() []string {
+	if len(s.PropertyOrder) == len(s.Properties) {
+		return s.PropertyOrder
+	}
+
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedContentTypes returns the content types of a response in alphabetical order
+func sortedContentTypes(content map[string]*MediaType) []string {
+	types := make([]string, 0, len(content))
+	for contentType := range content {
+		types = append(types, contentType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// isJSONContentType reports whether a content type is JSON (including +json types)
+func isJSONContentType(contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return contentType == "application/json" || strings.HasSuffix(contentType, "+json")
+}
+
+// SuccessContentTypes returns the content types of the endpoint's success responses
+// (the 2xx and default responses)
+func (e Endpoint) SuccessContentTypes() []string {
+	seen := make(map[string]bool)
+	var types []string
+	for code, response := range e.Responses {
+		if !strings.HasPrefix(code, "2") && code != "default" {
+			continue
+		}
+		for contentType := range response.Content {
+			if !seen[contentType] {
+				seen[contentType] = true
+				types = append(types, contentType)
+			}
+		}
+	}
+	sort.Strings(types)
+	return types
+}
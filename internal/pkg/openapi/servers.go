@@ -0,0 +1,146 @@
+package openapi
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// serverVariablePattern matches the {variables} of a server URL template
+var serverVariablePattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// SelectServer returns the server matching a selector, which is either an index into
+// the list or a case-insensitive match of a server's description or URL. A selector
+// that is contained in a single description also matches. An empty selector selects
+// the first server.
+func SelectServer(servers []Server, selector string) (Server, error) {
+	if len(servers) == 0 {
+		return Server{}, fmt.Errorf("no servers are declared")
+	}
+	if selector == "" {
+		return servers[0], nil
+	}
+
+	// Select by index
+	if i, err := strconv.Atoi(selector); err == nil {
+		if i < 0 || i >= len(servers) {
+			return Server{}, fmt.Errorf("server index %d out of range (available: %s)", i, describeServers(servers))
+		}
+		return servers[i], nil
+	}
+
+	// Select by description or URL
+	for _, server := range servers {
+		if strings.EqualFold(server.Description, selector) || strings.EqualFold(server.URL, selector) {
+			return server, nil
+		}
+	}
+
+	// Select by part of the description, as long as it is unambiguous
+	var matches []Server
+	for _, server := range servers {
+		if strings.Contains(strings.ToLower(server.Description), strings.ToLower(selector)) {
+			matches = append(matches, server)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	if len(matches) > 1 {
+		return Server{}, fmt.Errorf("server %q is ambiguous (matches: %s)", selector, describeServers(matches))
+	}
+
+	return Server{}, fmt.Errorf("unknown server %q (available: %s)", selector, describeServers(servers))
+}
+
+// describeServers returns a one-line description of a list of servers
+func describeServers(servers []Server) string {
+	descriptions := make([]string, len(servers))
+	for i, server := range servers {
+		descriptions[i] = fmt.Sprintf("%d: %s", i, server.URL)
+		if server.Description != "" {
+			descriptions[i] += fmt.Sprintf(" (%s)", server.Description)
+		}
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// Expand returns the URL of the server with its variables filled in. Variables
+// without a given value use their declared default, and values are checked
+// against the declared enum.
+func (s Server) Expand(values map[string]string) (string, error) {
+	// Check that the given values are for variables of the server
+	for name := range values {
+		if _, ok := s.Variables[name]; !ok {
+			return "", fmt.Errorf("unknown server variable %q for %s (variables: %s)", name, s.URL, strings.Join(s.VariableNames(), ", "))
+		}
+	}
+
+	var errs []string
+	expanded := serverVariablePattern.ReplaceAllStringFunc(s.URL, func(match string) string {
+		name := match[1 : len(match)-1]
+		variable, declared := s.Variables[name]
+
+		value, ok := values[name]
+		if !ok {
+			value = variable.Default
+		}
+		if !declared && !ok {
+			errs = append(errs, fmt.Sprintf("server variable %q has no value", name))
+			return match
+		}
+		if len(variable.Enum) > 0 && !slices.Contains(variable.Enum, value) {
+			errs = append(errs, fmt.Sprintf("invalid value %q for server variable %q (allowed: %s)", value, name, strings.Join(variable.Enum, ", ")))
+			return match
+		}
+		return value
+	})
+
+	if len(errs) > 0 {
+		return "", fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+
+	return expanded, nil
+}
+
+// VariableNames returns the sorted names of the variables of the server
+func (s Server) VariableNames() []string {
+	names := make([]string, 0, len(s.Variables))
+	for name := range s.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveServerURL resolves a server URL against the location of the spec, since
+// server URLs may be relative to the document
+func ResolveServerURL(serverURL, specPath string) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL %q: %w", serverURL, err)
+	}
+	if u.IsAbs() {
+		return serverURL, nil
+	}
+
+	if !isSpecURL(specPath) {
+		// A host without a scheme, as in Swagger 2.0 documents without schemes, uses HTTPS
+		if u.Host != "" {
+			u.Scheme = "https"
+			return u.String(), nil
+		}
+		return "", fmt.Errorf("server URL %q is relative to a local spec", serverURL)
+	}
+
+	base, err := url.Parse(specPath)
+	if err != nil {
+		return "", fmt.Errorf("invalid spec URL %q: %w", specPath, err)
+	}
+
+	return base.ResolveReference(u).String(), nil
+}
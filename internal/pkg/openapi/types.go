@@ -9,139 +9,313 @@ import (
 // Endpoint represents an API endpoint
 type Endpoint struct {
 	// Path is the URL path of the endpoint
-	Path string
+	Path string `json:"path"`
 
 	// Method is the HTTP method (GET, POST, etc.)
-	Method string
+	Method string `json:"method"`
 
 	// OperationID is the unique identifier for the operation
-	OperationID string
+	OperationID string `json:"operationId"`
 
 	// Summary is a brief description of the endpoint
-	Summary string
+	Summary string `json:"summary,omitempty"`
 
 	// Description is a detailed description of the endpoint
-	Description string
+	Description string `json:"description,omitempty"`
 
 	// Parameters is a list of parameters for the endpoint
-	Parameters []Parameter
+	Parameters []Parameter `json:"parameters,omitempty"`
 
 	// RequestBody is the request body schema
-	RequestBody *RequestBody
+	RequestBody *RequestBody `json:"requestBody,omitempty"`
 
 	// Responses is a map of response codes to response schemas
-	Responses map[string]Response
+	Responses map[string]Response `json:"responses,omitempty"`
 
 	// Tags is a list of tags for the endpoint
-	Tags []string
+	Tags []string `json:"tags,omitempty"`
 
-	// Security is a list of security requirements for the endpoint
-	Security []map[string][]string
+	// Security is a list of alternative security requirements for the endpoint.
+	// It is nil when the spec declares none, and empty when the endpoint needs no auth.
+	Security []map[string][]string `json:"security"`
+
+	// Servers are the servers declared for the operation or its path, overriding the document's servers
+	Servers []Server `json:"servers,omitempty"`
+
+	// Pagination is the pagination declared with the x-pagination extension
+	Pagination *Pagination `json:"pagination,omitempty"`
 
 	// Deprecated indicates if the endpoint is deprecated
-	Deprecated bool
+	Deprecated bool `json:"deprecated,omitempty"`
 }
 
 // Parameter represents an API parameter
 type Parameter struct {
 	// Name is the name of the parameter
-	Name string
+	Name string `json:"name"`
 
 	// In is the location of the parameter (path, query, header, cookie)
-	In string
+	In string `json:"in"`
 
 	// Description is a description of the parameter
-	Description string
+	Description string `json:"description,omitempty"`
 
 	// Required indicates if the parameter is required
-	Required bool
+	Required bool `json:"required,omitempty"`
 
 	// Schema is the schema of the parameter
-	Schema *Schema
+	Schema *Schema `json:"schema,omitempty"`
 
 	// Example is an example value for the parameter
-	Example interface{}
+	Example interface{} `json:"example,omitempty"`
 
 	// Deprecated indicates if the parameter is deprecated
-	Deprecated bool
+	Deprecated bool `json:"deprecated,omitempty"`
+
+	// Style is how the parameter is serialized (form, simple, label, matrix,
+	// spaceDelimited, pipeDelimited, deepObject); the default of its location when empty
+	Style string `json:"style,omitempty"`
+
+	// Explode indicates that arrays and objects are serialized as separate values; the
+	// default of the style when nil
+	Explode *bool `json:"explode,omitempty"`
 }
 
 // Schema represents a JSON Schema
 type Schema struct {
 	// Type is the type of the schema (string, number, integer, boolean, array, object)
-	Type string
+	Type string `json:"type,omitempty"`
 
 	// Format is the format of the schema (date-time, email, etc.)
-	Format string
+	Format string `json:"format,omitempty"`
 
 	// Description is a description of the schema
-	Description string
+	Description string `json:"description,omitempty"`
 
 	// Default is the default value for the schema
-	Default interface{}
+	Default interface{} `json:"default,omitempty"`
 
 	// Enum is a list of allowed values for the schema
-	Enum []interface{}
+	Enum []interface{} `json:"enum,omitempty"`
 
 	// Minimum is the minimum value for the schema
-	Minimum *float64
+	Minimum *float64 `json:"minimum,omitempty"`
 
 	// Maximum is the maximum value for the schema
-	Maximum *float64
+	Maximum *float64 `json:"maximum,omitempty"`
 
 	// MinLength is the minimum length for the schema
-	MinLength *uint64
+	MinLength *uint64 `json:"minLength,omitempty"`
 
 	// MaxLength is the maximum length for the schema
-	MaxLength *uint64
+	MaxLength *uint64 `json:"maxLength,omitempty"`
 
 	// Pattern is a regex pattern for the schema
-	Pattern string
+	Pattern string `json:"pattern,omitempty"`
 
 	// Properties is a map of property names to schemas (for object types)
-	Properties map[string]*Schema
+	Properties map[string]*Schema `json:"properties,omitempty"`
+
+	// PropertyOrder is the names of the properties in the order they are declared
+	PropertyOrder []string `json:"propertyOrder,omitempty"`
 
 	// Items is the schema for array items (for array types)
-	Items *Schema
+	Items *Schema `json:"items,omitempty"`
+
+	// MinItems is the minimum number of items (for array types)
+	MinItems *int64 `json:"minItems,omitempty"`
+
+	// MaxItems is the maximum number of items (for array types)
+	MaxItems *int64 `json:"maxItems,omitempty"`
+
+	// Nullable indicates if null is an allowed value
+	Nullable bool `json:"nullable,omitempty"`
+
+	// XMLName is the name of the XML element of the schema: its xml.name, or the name
+	// of the component it references
+	XMLName string `json:"xmlName,omitempty"`
 
 	// Required is a list of required properties (for object types)
-	Required []string
+	Required []string `json:"required,omitempty"`
 
 	// Example is an example value for the schema
-	Example interface{}
+	Example interface{} `json:"example,omitempty"`
 }
 
 // RequestBody represents a request body
 type RequestBody struct {
 	// Description is a description of the request body
-	Description string
+	Description string `json:"description,omitempty"`
 
 	// Required indicates if the request body is required
-	Required bool
+	Required bool `json:"required,omitempty"`
 
 	// Content is a map of media types to schemas
-	Content map[string]*MediaType
+	Content map[string]*MediaType `json:"content,omitempty"`
 }
 
 // MediaType represents a media type
 type MediaType struct {
 	// Schema is the schema of the media type
-	Schema *Schema
+	Schema *Schema `json:"schema,omitempty"`
 
 	// Example is an example value for the media type
-	Example interface{}
+	Example interface{} `json:"example,omitempty"`
+
+	// Encoding is how the properties of a multipart body are sent, by property name
+	Encoding map[string]*Encoding `json:"encoding,omitempty"`
+}
+
+// Encoding describes how a property of a multipart body is sent
+type Encoding struct {
+	// ContentType is the content type of the parts of the property, or a
+	// comma-separated list of the allowed ones
+	ContentType string `json:"contentType,omitempty"`
+
+	// Headers are the schemas of the headers of the parts, by header name
+	Headers map[string]*Schema `json:"headers,omitempty"`
 }
 
 // Response represents an API response
 type Response struct {
 	// Description is a description of the response
-	Description string
+	Description string `json:"description,omitempty"`
 
 	// Content is a map of media types to schemas
-	Content map[string]*MediaType
+	Content map[string]*MediaType `json:"content,omitempty"`
 
 	// Headers is a map of header names to schemas
-	Headers map[string]*Schema
+	Headers map[string]*Schema `json:"headers,omitempty"`
+}
+
+// SpecIndexVersion is the version of the SpecIndex layout, bumped whenever it changes
+const SpecIndexVersion = 12
+
+// SpecIndex is a compact, serializable representation of an OpenAPI document
+// holding everything needed to build commands without re-parsing the spec
+type SpecIndex struct {
+	// Version is the layout version of the index
+	Version int `json:"version"`
+
+	// Title is the title of the API
+	Title string `json:"title,omitempty"`
+
+	// Endpoints is the list of endpoints in the document
+	Endpoints []Endpoint `json:"endpoints"`
+
+	// SecuritySchemes is a map of security scheme names to schemes
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+
+	// Servers is the list of servers declared by the document
+	Servers []Server `json:"servers,omitempty"`
+}
+
+// Pagination describes how the results of a list operation are paginated
+type Pagination struct {
+	// Style is the pagination style (link, cursor, offset, page), detected from the responses when empty
+	Style string `json:"style,omitempty" yaml:"style"`
+
+	// ItemsPath is the dotted path of the items in the response body
+	ItemsPath string `json:"itemsPath,omitempty" yaml:"itemsPath"`
+
+	// CursorPath is the dotted path of the next cursor or next page URL in the response body
+	CursorPath string `json:"cursorPath,omitempty" yaml:"cursorPath"`
+
+	// CursorParam is the query parameter the cursor is sent in
+	CursorParam string `json:"cursorParam,omitempty" yaml:"cursorParam"`
+
+	// OffsetParam is the query parameter holding the offset of the first item
+	OffsetParam string `json:"offsetParam,omitempty" yaml:"offsetParam"`
+
+	// PageParam is the query parameter holding the page number
+	PageParam string `json:"pageParam,omitempty" yaml:"pageParam"`
+
+	// LimitParam is the query parameter holding the page size
+	LimitParam string `json:"limitParam,omitempty" yaml:"limitParam"`
+
+	// Limit is the page size to request
+	Limit int `json:"limit,omitempty" yaml:"limit"`
+}
+
+// SecurityScheme represents a security scheme
+type SecurityScheme struct {
+	// Type is the type of the scheme (apiKey, http, oauth2, openIdConnect)
+	Type string `json:"type"`
+
+	// Description is a description of the scheme
+	Description string `json:"description,omitempty"`
+
+	// Name is the name of the header, query or cookie parameter (apiKey)
+	Name string `json:"name,omitempty"`
+
+	// In is the location of the API key (header, query, cookie)
+	In string `json:"in,omitempty"`
+
+	// Scheme is the HTTP authorization scheme (basic, bearer, etc.)
+	Scheme string `json:"scheme,omitempty"`
+
+	// BearerFormat is a hint for the format of a bearer token
+	BearerFormat string `json:"bearerFormat,omitempty"`
+
+	// Flows are the OAuth2 flows supported by the scheme
+	Flows *OAuthFlows `json:"flows,omitempty"`
+
+	// OpenIDConnectURL is the OpenID Connect discovery URL
+	OpenIDConnectURL string `json:"openIdConnectUrl,omitempty"`
+}
+
+// OAuthFlows represents the OAuth2 flows of a security scheme
+type OAuthFlows struct {
+	// Implicit is the implicit flow
+	Implicit *OAuthFlow `json:"implicit,omitempty"`
+
+	// Password is the resource owner password flow
+	Password *OAuthFlow `json:"password,omitempty"`
+
+	// ClientCredentials is the client credentials flow
+	ClientCredentials *OAuthFlow `json:"clientCredentials,omitempty"`
+
+	// AuthorizationCode is the authorization code flow
+	AuthorizationCode *OAuthFlow `json:"authorizationCode,omitempty"`
+}
+
+// OAuthFlow represents a single OAuth2 flow
+type OAuthFlow struct {
+	// AuthorizationURL is the authorization endpoint
+	AuthorizationURL string `json:"authorizationUrl,omitempty"`
+
+	// TokenURL is the token endpoint
+	TokenURL string `json:"tokenUrl,omitempty"`
+
+	// RefreshURL is the endpoint for refreshing tokens
+	RefreshURL string `json:"refreshUrl,omitempty"`
+
+	// Scopes is a map of scope names to descriptions
+	Scopes map[string]string `json:"scopes,omitempty"`
+}
+
+// Server represents a server
+type Server struct {
+	// URL is the URL of the server, which may contain {variables}
+	URL string `json:"url"`
+
+	// Description is a description of the server
+	Description string `json:"description,omitempty"`
+
+	// Variables is a map of variable names to server variables
+	Variables map[string]ServerVariable `json:"variables,omitempty"`
+}
+
+// ServerVariable represents a server URL template variable
+type ServerVariable struct {
+	// Enum is a list of allowed values for the variable
+	Enum []string `json:"enum,omitempty"`
+
+	// Default is the default value for the variable
+	Default string `json:"default"`
+
+	// Description is a description of the variable
+	Description string `json:"description,omitempty"`
 }
 
 // SpecParser is the interface for parsing OpenAPI specifications
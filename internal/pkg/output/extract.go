@@ -0,0 +1,488 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v2"
+)
+
+// Record is a set of named values kept in the order of its fields
+type Record struct {
+	// Fields are the names of the values, in order
+	Fields []string
+
+	// Values are the values by name
+	Values map[string]interface{}
+}
+
+// MarshalJSON encodes the record as a JSON object with its fields in order
+func (r Record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range r.Fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(r.Values[field])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// MarshalYAML encodes the record as a YAML mapping with its fields in order
+func (r Record) MarshalYAML() (interface{}, error) {
+	items := make(yaml.MapSlice, len(r.Fields))
+	for i, field := range r.Fields {
+		items[i] = yaml.MapItem{Key: field, Value: r.Values[field]}
+	}
+	return items, nil
+}
+
+// String formats the record like a map, with its fields in order
+func (r Record) String() string {
+	parts := make([]string, len(r.Fields))
+	for i, field := range r.Fields {
+		parts[i] = fmt.Sprintf("%s:%v", field, r.Values[field])
+	}
+	return "map[" + strings.Join(parts, " ") + "]"
+}
+
+// pathSegment is a step of a field path
+type pathSegment struct {
+	// key is the name of an object field, or the index of an array element when numeric
+	key string
+
+	// index is the index of an array element given in brackets; negative counts from the end
+	index *int
+
+	// wildcard selects all the elements of an array or values of an object
+	wildcard bool
+
+	// recursive selects the matches of the segment at any depth (..)
+	recursive bool
+}
+
+// fans reports whether the segment can select several values
+func (s pathSegment) fans() bool {
+	return s.wildcard || s.recursive
+}
+
+// extractPath is a field to extract
+type extractPath struct {
+	// name is the name of the field in the records
+	name string
+
+	// expression is the path as given
+	expression string
+
+	// segments are the steps of the path
+	segments []pathSegment
+}
+
+// Extractor extracts fields from data into records
+type Extractor struct {
+	paths []extractPath
+}
+
+// NewExtractor parses field paths to extract. A path is a dot notation or JSONPath
+// expression (items.0.name, $.items[*].name, items.*.name, ..id), optionally prefixed
+// with a name for the field (name=items.*.metadata.name).
+func NewExtractor(expressions []string) (*Extractor, error) {
+	extractor := &Extractor{}
+	names := make(map[string]int)
+
+	for _, expression := range expressions {
+		expression = strings.TrimSpace(expression)
+		if expression == "" {
+			continue
+		}
+
+		// Split off the name of the field
+		name, path, renamed := strings.Cut(expression, "=")
+		if !renamed {
+			path = expression
+		}
+		name = strings.TrimSpace(name)
+		path = strings.TrimSpace(path)
+
+		segments, err := parsePath(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extract path %q: %w", path, err)
+		}
+
+		if !renamed {
+			name = defaultFieldName(path, segments)
+			names[name]++
+		}
+		extractor.paths = append(extractor.paths, extractPath{name: name, expression: path, segments: segments})
+	}
+
+	// Name fields whose default names collide by their whole path
+	for i, p := range extractor.paths {
+		if names[p.name] > 1 {
+			extractor.paths[i].name = p.expression
+		}
+	}
+
+	return extractor, nil
+}
+
+// Extract extracts the fields from data. Paths that select a single value produce a
+// record; paths that select several values (wildcards, recursive descent) produce a
+// list of records, one per value, with the single values repeated in each. Fields of a
+// list response are extracted from each of its items.
+func (e *Extractor) Extract(data interface{}) (interface{}, error) {
+	if len(e.paths) == 0 {
+		return data, nil
+	}
+
+	// Convert the data to JSON and back to ensure a consistent structure
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal data: %w", err)
+	}
+
+	var plain interface{}
+	if err := json.Unmarshal(jsonData, &plain); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data: %w", err)
+	}
+
+	// Extract the fields of a list from each of its items
+	paths := make([]extractPath, len(e.paths))
+	copy(paths, e.paths)
+	if _, ok := plain.([]interface{}); ok {
+		for i, p := range paths {
+			if len(p.segments) > 0 && p.segments[0].key != "" && !p.segments[0].recursive && !isIndex(p.segments[0].key) {
+				paths[i].segments = append([]pathSegment{{wildcard: true}}, p.segments...)
+			}
+		}
+	}
+
+	fields := make([]string, len(paths))
+	var fanned []extractPath
+	for i, p := range paths {
+		fields[i] = p.name
+		if fanIndex(p.segments) >= 0 {
+			fanned = append(fanned, p)
+		}
+	}
+
+	// Extract a single record
+	if len(fanned) == 0 {
+		record := Record{Fields: fields, Values: make(map[string]interface{})}
+		for _, p := range paths {
+			record.Values[p.name] = extractSingle(plain, p)
+		}
+		return record, nil
+	}
+
+	// Extract a record for each of the values selected by the paths
+	var records []Record
+	if prefix, ok := commonPrefix(fanned); ok {
+		// The paths select from the same values: extract the rest of each path from each
+		for _, element := range evaluatePath(plain, prefix) {
+			record := Record{Fields: fields, Values: make(map[string]interface{})}
+			for _, p := range paths {
+				if fanIndex(p.segments) < 0 {
+					record.Values[p.name] = extractSingle(plain, p)
+					continue
+				}
+				rest := p.segments[len(prefix):]
+				values := evaluatePath(element, rest)
+				switch {
+				case fanIndex(rest) >= 0:
+					record.Values[p.name] = values
+				case len(values) > 0:
+					record.Values[p.name] = values[0]
+				default:
+					record.Values[p.name] = nil
+				}
+			}
+			records = append(records, record)
+		}
+	} else {
+		// The paths select from different values: pair up the values by position
+		columns := make(map[string][]interface{})
+		count := 0
+		for _, p := range fanned {
+			columns[p.name] = evaluatePath(plain, p.segments)
+			count = max(count, len(columns[p.name]))
+		}
+		for i := 0; i < count; i++ {
+			record := Record{Fields: fields, Values: make(map[string]interface{})}
+			for _, p := range paths {
+				if column, ok := columns[p.name]; ok {
+					if i < len(column) {
+						record.Values[p.name] = column[i]
+					} else {
+						record.Values[p.name] = nil
+					}
+					continue
+				}
+				record.Values[p.name] = extractSingle(plain, p)
+			}
+			records = append(records, record)
+		}
+	}
+
+	if records == nil {
+		records = []Record{}
+	}
+	return records, nil
+}
+
+// ExtractFields extracts fields from data using dot notation or JSONPath paths
+func ExtractFields(data interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+
+	extractor, err := NewExtractor(fields)
+	if err != nil {
+		return nil, err
+	}
+	return extractor.Extract(data)
+}
+
+// extractSingle returns the value a path without wildcards selects, or nil with a
+// warning when it selects none
+func extractSingle(data interface{}, p extractPath) interface{} {
+	values := evaluatePath(data, p.segments)
+	if len(values) == 0 {
+		log.Warn("Failed to extract field", "field", p.expression, "error", "field not found")
+		return nil
+	}
+	return values[0]
+}
+
+// evaluatePath returns the values a path selects from data
+func evaluatePath(data interface{}, segments []pathSegment) []interface{} {
+	current := []interface{}{data}
+	for _, segment := range segments {
+		var next []interface{}
+		for _, value := range current {
+			if segment.recursive {
+				for _, descendant := range descendants(value) {
+					next = append(next, selectSegment(descendant, segment)...)
+				}
+				continue
+			}
+			next = append(next, selectSegment(value, segment)...)
+		}
+		current = next
+	}
+	return current
+}
+
+// selectSegment returns the values a segment selects from a value
+func selectSegment(value interface{}, segment pathSegment) []interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if segment.wildcard {
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			values := make([]interface{}, len(keys))
+			for i, key := range keys {
+				values[i] = v[key]
+			}
+			return values
+		}
+		if segment.index == nil {
+			if field, ok := v[segment.key]; ok {
+				return []interface{}{field}
+			}
+		}
+	case []interface{}:
+		if segment.wildcard {
+			return v
+		}
+		index := segment.index
+		if index == nil && !segment.recursive {
+			if i, err := strconv.Atoi(segment.key); err == nil {
+				index = &i
+			}
+		}
+		if index != nil {
+			i := *index
+			if i < 0 {
+				i += len(v)
+			}
+			if i >= 0 && i < len(v) {
+				return []interface{}{v[i]}
+			}
+		}
+	}
+	return nil
+}
+
+// descendants returns a value and all the values nested in it, depth first
+func descendants(value interface{}) []interface{} {
+	values := []interface{}{value}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			values = append(values, descendants(v[key])...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			values = append(values, descendants(item)...)
+		}
+	}
+	return values
+}
+
+// parsePath parses a dot notation or JSONPath path into segments
+func parsePath(path string) ([]pathSegment, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	// Strip the JSONPath root
+	rest := strings.TrimPrefix(path, "$")
+	if rest != path && rest != "" && rest[0] != '.' && rest[0] != '[' {
+		rest = path
+	}
+
+	var segments []pathSegment
+	recursive := false
+	for i := 0; i < len(rest); {
+		switch {
+		case strings.HasPrefix(rest[i:], ".."):
+			if recursive {
+				return nil, fmt.Errorf("unexpected '..'")
+			}
+			recursive = true
+			i += 2
+		case rest[i] == '.':
+			if i == len(rest)-1 {
+				return nil, fmt.Errorf("trailing '.'")
+			}
+			i++
+		case rest[i] == '[':
+			end := strings.IndexByte(rest[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '['")
+			}
+			segment, err := parseBracket(rest[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			segment.recursive = recursive
+			segments = append(segments, segment)
+			recursive = false
+			i += end + 1
+		default:
+			end := strings.IndexAny(rest[i:], ".[")
+			if end < 0 {
+				end = len(rest) - i
+			}
+			key := rest[i : i+end]
+			segment := pathSegment{key: key, recursive: recursive}
+			if key == "*" {
+				segment = pathSegment{wildcard: true, recursive: recursive}
+			}
+			segments = append(segments, segment)
+			recursive = false
+			i += end
+		}
+	}
+
+	if recursive {
+		return nil, fmt.Errorf("missing field after '..'")
+	}
+	return segments, nil
+}
+
+// parseBracket parses the contents of a bracket segment: an index, a quoted key or a wildcard
+func parseBracket(contents string) (pathSegment, error) {
+	contents = strings.TrimSpace(contents)
+	switch {
+	case contents == "" || contents == "*":
+		return pathSegment{wildcard: true}, nil
+	case len(contents) >= 2 && (contents[0] == '\'' || contents[0] == '"') && contents[len(contents)-1] == contents[0]:
+		return pathSegment{key: contents[1 : len(contents)-1]}, nil
+	}
+
+	index, err := strconv.Atoi(contents)
+	if err != nil {
+		return pathSegment{}, fmt.Errorf("invalid index [%s]", contents)
+	}
+	return pathSegment{index: &index}, nil
+}
+
+// defaultFieldName returns the name of an extracted field: its last key, or the path
+// when it has none
+func defaultFieldName(path string, segments []pathSegment) string {
+	for i := len(segments) - 1; i >= 0; i-- {
+		if segments[i].key != "" && !isIndex(segments[i].key) {
+			return segments[i].key
+		}
+	}
+	return path
+}
+
+// fanIndex returns the index of the first segment that can select several values, or -1
+func fanIndex(segments []pathSegment) int {
+	for i, segment := range segments {
+		if segment.fans() {
+			return i
+		}
+	}
+	return -1
+}
+
+// commonPrefix returns the segments up to and including the first one selecting several
+// values, when they are the same for all paths
+func commonPrefix(paths []extractPath) ([]pathSegment, bool) {
+	first := paths[0].segments[:fanIndex(paths[0].segments)+1]
+	for _, p := range paths[1:] {
+		prefix := p.segments[:fanIndex(p.segments)+1]
+		if len(prefix) != len(first) {
+			return nil, false
+		}
+		for i := range prefix {
+			if !sameSegment(prefix[i], first[i]) {
+				return nil, false
+			}
+		}
+	}
+	return first, true
+}
+
+// sameSegment reports whether two segments select the same values
+func sameSegment(a, b pathSegment) bool {
+	if (a.index == nil) != (b.index == nil) || (a.index != nil && *a.index != *b.index) {
+		return false
+	}
+	return a.key == b.key && a.wildcard == b.wildcard && a.recursive == b.recursive
+}
+
+// isIndex reports whether a key is an array index
+func isIndex(key string) bool {
+	_, err := strconv.Atoi(key)
+	return err == nil
+}
@@ -0,0 +1,86 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/itchyny/gojq"
+)
+
+// Filter is a compiled jq filter
+type Filter struct {
+	expression string
+	code       *gojq.Code
+}
+
+// NewFilter compiles a jq filter expression
+func NewFilter(expression string) (*Filter, error) {
+	query, err := gojq.Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expression, err)
+	}
+
+	code, err := gojq.Compile(query, gojq.WithEnvironLoader(os.Environ))
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expression, err)
+	}
+
+	return &Filter{expression: expression, code: code}, nil
+}
+
+// Apply runs the filter on data. A filter producing a single result returns it, and one
+// producing several results returns them as a list. One producing none returns false, so
+// that it can be told apart from one producing null.
+func (f *Filter) Apply(data interface{}) (interface{}, bool, error) {
+	// Convert the data to JSON and back to the plain values the filter works on
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal data: %w", err)
+	}
+
+	var input interface{}
+	if err := json.Unmarshal(jsonData, &input); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal data: %w", err)
+	}
+
+	// Collect the results
+	var results []interface{}
+	iter := f.code.Run(input)
+	for {
+		result, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := result.(error); ok {
+			if haltErr, ok := err.(*gojq.HaltError); ok && haltErr.Value() == nil {
+				break
+			}
+			return nil, false, fmt.Errorf("filter %q failed: %w", f.expression, err)
+		}
+		results = append(results, result)
+	}
+
+	switch len(results) {
+	case 0:
+		return nil, false, nil
+	case 1:
+		return results[0], true, nil
+	default:
+		return results, true, nil
+	}
+}
+
+// FilterData filters data with a jq expression. A filter producing no results returns nil.
+func FilterData(data interface{}, filter string) (interface{}, error) {
+	if filter == "" {
+		return data, nil
+	}
+
+	f, err := NewFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	result, _, err := f.Apply(data)
+	return result, err
+}
@@ -93,6 +93,9 @@ type CSVFormatter struct {
 
 	// Delimiter is the CSV delimiter
 	Delimiter rune
+
+	// Options select, order and sort the columns
+	Options TableOptions
 }
 
 // NewCSVFormatter creates a new CSVFormatter
@@ -108,38 +111,10 @@ func NewCSVFormatter(header bool, delimiter rune) *CSVFormatter {
 
 // Format formats the data as CSV
 func (f *CSVFormatter) Format(data interface{}) ([]byte, error) {
-	// Convert the data to a slice of maps
-	var rows []map[string]interface{}
-	switch v := data.(type) {
-	case []map[string]interface{}:
-		rows = v
-	case map[string]interface{}:
-		rows = []map[string]interface{}{v}
-	case []interface{}:
-		rows = make([]map[string]interface{}, len(v))
-		for i, item := range v {
-			if m, ok := item.(map[string]interface{}); ok {
-				rows[i] = m
-			} else {
-				rows[i] = map[string]interface{}{"value": item}
-			}
-		}
-	default:
-		return nil, fmt.Errorf("unsupported data type for CSV: %T", data)
-	}
-
-	// Get all the column names
-	columns := make(map[string]bool)
-	for _, row := range rows {
-		for k := range row {
-			columns[k] = true
-		}
-	}
-
-	// Convert the column names to a slice
-	var columnNames []string
-	for k := range columns {
-		columnNames = append(columnNames, k)
+	// Convert the data to rows of flattened fields
+	columns, rows, err := tabulate(data, f.Options)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create a buffer to write the CSV
@@ -149,19 +124,14 @@ func (f *CSVFormatter) Format(data interface{}) ([]byte, error) {
 
 	// Write the header row
 	if f.Header {
-		if err := writer.Write(columnNames); err != nil {
+		if err := writer.Write(columns); err != nil {
 			return nil, fmt.Errorf("failed to write CSV header: %w", err)
 		}
 	}
 
 	// Write the data rows
 	for _, row := range rows {
-		var values []string
-		for _, col := range columnNames {
-			val := row[col]
-			values = append(values, fmt.Sprintf("%v", val))
-		}
-		if err := writer.Write(values); err != nil {
+		if err := writer.Write(row); err != nil {
 			return nil, fmt.Errorf("failed to write CSV row: %w", err)
 		}
 	}
@@ -197,48 +167,28 @@ func (f *TextFormatter) GetFormat() Format {
 	return FormatText
 }
 
-// TableFormatter formats output as a table
-type TableFormatter struct {
-	// Header indicates whether to include a header row
-	Header bool
-
-	// Columns are the columns to include in the table
-	Columns []string
-}
-
-// NewTableFormatter creates a new TableFormatter
-func NewTableFormatter(header bool, columns []string) *TableFormatter {
-	return &TableFormatter{
-		Header:  header,
-		Columns: columns,
+// NewFormatter creates a new Formatter based on the format. The options apply to
+// the csv and table formats; template=<template> and template=@<file> render a Go template.
+func NewFormatter(format string, options TableOptions) (Formatter, error) {
+	if text, ok := strings.CutPrefix(format, "template="); ok {
+		return NewTemplateFormatter(text)
 	}
-}
-
-// Format formats the data as a table
-func (f *TableFormatter) Format(data interface{}) ([]byte, error) {
-	// For now, just use the CSV formatter
-	csvFormatter := NewCSVFormatter(f.Header, '|')
-	return csvFormatter.Format(data)
-}
-
-// GetFormat returns the format
-func (f *TableFormatter) GetFormat() Format {
-	return FormatTable
-}
 
-// NewFormatter creates a new Formatter based on the format
-func NewFormatter(format string) (Formatter, error) {
 	switch strings.ToLower(format) {
 	case "json":
 		return NewJSONFormatter(true), nil
 	case "yaml", "yml":
 		return NewYAMLFormatter(), nil
 	case "csv":
-		return NewCSVFormatter(true, ','), nil
+		formatter := NewCSVFormatter(true, ',')
+		formatter.Options = options
+		return formatter, nil
 	case "text", "txt":
 		return NewTextFormatter(), nil
 	case "table":
-		return NewTableFormatter(true, nil), nil
+		return NewTableFormatter(true, options), nil
+	case "template":
+		return nil, fmt.Errorf("the template output format requires a template (template=<template>, template=@<file> or template=<name>)")
 	default:
 		return nil, fmt.Errorf("unsupported output format: %s", format)
 	}
@@ -276,84 +226,36 @@ func WriteOutput(data interface{}, formatter Formatter, output string) error {
 	return nil
 }
 
-// ExtractFields extracts fields from data using a dot notation path
-func ExtractFields(data interface{}, fields []string) (interface{}, error) {
-	if len(fields) == 0 {
-		return data, nil
-	}
-
-	// Convert the data to JSON and back to a map to ensure a consistent structure
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal data: %w", err)
-	}
-
-	var mapData interface{}
-	if err := json.Unmarshal(jsonData, &mapData); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal data: %w", err)
+// WriteError writes an error response body to stderr using a formatter.
+// Bodies that are not structured data are written as is.
+func WriteError(data interface{}, formatter Formatter) error {
+	if data == nil {
+		return nil
 	}
 
-	// Extract the fields
-	result := make(map[string]interface{})
-	for _, field := range fields {
-		value, err := extractField(mapData, field)
-		if err != nil {
-			log.Warn("Failed to extract field", "field", field, "error", err)
-			continue
+	// Write plain text bodies without formatting
+	if s, ok := data.(string); ok {
+		if !strings.HasSuffix(s, "\n") {
+			s += "\n"
 		}
-		result[field] = value
+		_, err := os.Stderr.WriteString(s)
+		return err
 	}
 
-	return result, nil
-}
-
-// extractField extracts a field from data using a dot notation path
-func extractField(data interface{}, field string) (interface{}, error) {
-	parts := strings.Split(field, ".")
-	current := data
-
-	for _, part := range parts {
-		switch v := current.(type) {
-		case map[string]interface{}:
-			var ok bool
-			current, ok = v[part]
-			if !ok {
-				return nil, fmt.Errorf("field not found: %s", part)
-			}
-		case []interface{}:
-			// If the part is a number, use it as an index
-			if part == "[]" {
-				// Return the entire array
-				return v, nil
-			}
-			return nil, fmt.Errorf("array indexing not supported: %s", part)
-		default:
-			return nil, fmt.Errorf("cannot access field %s of %T", part, current)
-		}
-	}
-
-	return current, nil
-}
-
-// FilterData filters data using a JQ-like syntax
-func FilterData(data interface{}, filter string) (interface{}, error) {
-	if filter == "" {
-		return data, nil
-	}
-
-	// Convert the data to JSON and back to a map to ensure a consistent structure
-	jsonData, err := json.Marshal(data)
+	// Format the data
+	formattedData, err := formatter.Format(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal data: %w", err)
+		return fmt.Errorf("failed to format data: %w", err)
 	}
 
-	var mapData interface{}
-	if err := json.Unmarshal(jsonData, &mapData); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal data: %w", err)
+	if !bytes.HasSuffix(formattedData, []byte("\n")) {
+		formattedData = append(formattedData, '\n')
+	}
+	if _, err := os.Stderr.Write(formattedData); err != nil {
+		return fmt.Errorf("failed to write to stderr: %w", err)
 	}
 
-	// For now, just support simple field extraction
-	return extractField(mapData, filter)
+	return nil
 }
 
 // PrettyPrint prints data in a pretty format
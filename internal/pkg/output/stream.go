@@ -0,0 +1,348 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"golang.org/x/term"
+)
+
+// progressInterval is how often the progress of a download is redrawn
+const progressInterval = 100 * time.Millisecond
+
+// StreamWriter writes the records of a streamed response as they arrive, formatting
+// each on its own
+type StreamWriter struct {
+	formatter Formatter
+	writer    io.Writer
+	file      *os.File
+	path      string
+	count     int
+}
+
+// NewStreamWriter creates a StreamWriter writing to stdout, or to the file at the output
+// path. JSON records are written compactly, one per line; YAML records are separate
+// documents and the CSV header is only written before the first record.
+func NewStreamWriter(formatter Formatter, output string) (*StreamWriter, error) {
+	if jsonFormatter, ok := formatter.(*JSONFormatter); ok && jsonFormatter.Pretty {
+		formatter = NewJSONFormatter(false)
+	}
+
+	writer, file, err := openOutput(output)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamWriter{formatter: formatter, writer: writer, file: file, path: output}, nil
+}
+
+// openOutput opens the output written to: stdout, or the file at the output path
+func openOutput(output string) (io.Writer, *os.File, error) {
+	if output == "" || output == "-" {
+		return os.Stdout, nil, nil
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	return file, file, nil
+}
+
+// Write formats a record and writes it
+func (w *StreamWriter) Write(data interface{}) error {
+	formattedData, err := w.formatter.Format(data)
+	if err != nil {
+		return fmt.Errorf("failed to format data: %w", err)
+	}
+
+	if w.count > 0 && w.formatter.GetFormat() == FormatYAML {
+		formattedData = append([]byte("---\n"), formattedData...)
+	}
+	if !bytes.HasSuffix(formattedData, []byte("\n")) {
+		formattedData = append(formattedData, '\n')
+	}
+	if _, err := w.writer.Write(formattedData); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	// Write the CSV header once
+	if csv, ok := w.formatter.(*CSVFormatter); ok && csv.Header {
+		next := *csv
+		next.Header = false
+		w.formatter = &next
+	}
+	w.count++
+
+	return nil
+}
+
+// Close closes the file written to. Closing it again does nothing.
+func (w *StreamWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	file := w.file
+	w.file = nil
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	log.Info("Output written to file", "path", w.path, "records", w.count)
+	return nil
+}
+
+// ListWriter writes the items of a list as they arrive, with the same output as
+// formatting the whole list at once
+type ListWriter struct {
+	formatter Formatter
+	writer    io.Writer
+	file      *os.File
+	path      string
+	count     int
+}
+
+// CanWriteList checks if the output of a formatter can be written as the items of a list
+// arrive: JSON, YAML, and CSV with selected columns that aren't sorted. Tables, text,
+// templates and CSV with columns taken from the items need the whole list.
+func CanWriteList(formatter Formatter) bool {
+	switch f := formatter.(type) {
+	case *JSONFormatter, *YAMLFormatter:
+		return true
+	case *CSVFormatter:
+		return len(f.Options.Columns) > 0 && f.Options.SortBy == ""
+	default:
+		return false
+	}
+}
+
+// NewListWriter creates a ListWriter writing to stdout, or to the file at the output path
+func NewListWriter(formatter Formatter, output string) (*ListWriter, error) {
+	if !CanWriteList(formatter) {
+		return nil, fmt.Errorf("the %s output format cannot be written as a list arrives", formatter.GetFormat())
+	}
+
+	writer, file, err := openOutput(output)
+	if err != nil {
+		return nil, err
+	}
+	return &ListWriter{formatter: formatter, writer: writer, file: file, path: output}, nil
+}
+
+// Write formats items and writes them after the items written before
+func (w *ListWriter) Write(items []interface{}) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	switch f := w.formatter.(type) {
+	case *JSONFormatter:
+		// Items are separated by commas, and indented by one level in pretty JSON
+		for _, item := range items {
+			switch {
+			case w.count == 0 && f.Pretty:
+				buf.WriteString("[\n  ")
+			case w.count == 0:
+				buf.WriteString("[")
+			case f.Pretty:
+				buf.WriteString(",\n  ")
+			default:
+				buf.WriteString(",")
+			}
+
+			var data []byte
+			var err error
+			if f.Pretty {
+				data, err = json.MarshalIndent(item, "  ", "  ")
+			} else {
+				data, err = json.Marshal(item)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to format data: %w", err)
+			}
+			buf.Write(data)
+			w.count++
+		}
+	case *CSVFormatter:
+		// The columns of the first items are kept for the next ones, and the header is
+		// only written before the first row
+		columns, _, err := tabulate(items, f.Options)
+		if err != nil {
+			return err
+		}
+		data, err := f.Format(items)
+		if err != nil {
+			return fmt.Errorf("failed to format data: %w", err)
+		}
+		buf.Write(data)
+		next := *f
+		next.Header = false
+		next.Options.Columns = columns
+		w.formatter = &next
+		w.count += len(items)
+	default:
+		// YAML lists are the items of the list one after the other
+		data, err := w.formatter.Format(items)
+		if err != nil {
+			return fmt.Errorf("failed to format data: %w", err)
+		}
+		buf.Write(data)
+		w.count += len(items)
+	}
+
+	if _, err := w.writer.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// Close ends the list and closes the file written to. Closing it again does nothing.
+func (w *ListWriter) Close() error {
+	if w.formatter == nil {
+		return nil
+	}
+
+	// Write the end of the list, or an empty list when there were no items
+	var end []byte
+	switch f := w.formatter.(type) {
+	case *JSONFormatter:
+		switch {
+		case w.count == 0:
+			end = []byte("[]")
+		case f.Pretty:
+			end = []byte("\n]")
+		default:
+			end = []byte("]")
+		}
+	default:
+		if w.count == 0 {
+			var err error
+			if end, err = w.formatter.Format([]interface{}{}); err != nil {
+				return fmt.Errorf("failed to format data: %w", err)
+			}
+		}
+	}
+	w.formatter = nil
+	_, err := w.writer.Write(end)
+
+	if w.file != nil {
+		if closeErr := w.file.Close(); err == nil && closeErr != nil {
+			return fmt.Errorf("failed to close file: %w", closeErr)
+		}
+		log.Info("Output written to file", "path", w.path, "items", w.count)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// Download copies a body to the file at a path as it arrives, showing its progress on
+// stderr when it is a terminal. The size is the expected size of the body, or -1 when
+// unknown. A partially written file is removed when the download fails.
+func Download(body io.Reader, size int64, path string) (int64, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	var writer io.Writer = file
+	var progress *Progress
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		progress = NewProgress(os.Stderr, size)
+		writer = io.MultiWriter(file, progress)
+	}
+
+	written, err := io.Copy(writer, body)
+	if progress != nil {
+		progress.Done()
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return written, fmt.Errorf("failed to download to file: %w", err)
+	}
+
+	log.Info("Output written to file", "path", path, "size", FormatBytes(written))
+	return written, nil
+}
+
+// Progress draws the progress of a download as the bytes written to it
+type Progress struct {
+	// Total is the expected number of bytes, or -1 when unknown
+	Total int64
+
+	writer  io.Writer
+	written int64
+	start   time.Time
+	drawn   time.Time
+}
+
+// NewProgress creates a new Progress drawing to a writer
+func NewProgress(w io.Writer, total int64) *Progress {
+	return &Progress{
+		Total:  total,
+		writer: w,
+		start:  time.Now(),
+	}
+}
+
+// Write counts the bytes written, redrawing the progress at most every progressInterval
+func (p *Progress) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if time.Since(p.drawn) >= progressInterval {
+		p.draw()
+	}
+	return len(b), nil
+}
+
+// Done draws the final progress and ends its line
+func (p *Progress) Done() {
+	p.draw()
+	fmt.Fprintln(p.writer)
+}
+
+// draw draws the progress over the current line: a bar with the percentage when the
+// total is known, the bytes written and the rate
+func (p *Progress) draw() {
+	p.drawn = time.Now()
+
+	rate := ""
+	if elapsed := time.Since(p.start).Seconds(); elapsed > 0 {
+		rate = FormatBytes(int64(float64(p.written)/elapsed)) + "/s"
+	}
+
+	var line string
+	if p.Total > 0 {
+		const width = 30
+		fraction := min(float64(p.written)/float64(p.Total), 1)
+		filled := int(fraction * width)
+		line = fmt.Sprintf("[%s%s] %3.0f%% %s / %s  %s",
+			strings.Repeat("=", filled), strings.Repeat(" ", width-filled),
+			fraction*100, FormatBytes(p.written), FormatBytes(p.Total), rate)
+	} else {
+		line = fmt.Sprintf("%s  %s", FormatBytes(p.written), rate)
+	}
+
+	fmt.Fprintf(p.writer, "\r%s\x1b[K", line)
+}
+
+// FormatBytes formats a number of bytes with a binary unit (KiB, MiB, ...)
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,381 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"golang.org/x/term"
+)
+
+// TableOptions select, order and sort the columns of tabular output
+type TableOptions struct {
+	// Columns are the columns to show, in order. A column of a nested object selects
+	// all of its fields (metadata selects metadata.name and metadata.namespace).
+	Columns []string
+
+	// SortBy is the column to sort the rows by, prefixed with - for descending order
+	SortBy string
+
+	// Order is the preferred order of the columns when they aren't selected, such as
+	// the order of the properties in the response schema
+	Order []string
+
+	// Width is the maximum width of a table: 0 for the width of the terminal, or
+	// negative for no limit
+	Width int
+
+	// Truncate truncates the cells of a table that is too wide instead of wrapping them
+	Truncate bool
+}
+
+// TableFormatter formats output as a table
+type TableFormatter struct {
+	// Header indicates whether to include a header row
+	Header bool
+
+	// Options select, order and sort the columns
+	Options TableOptions
+}
+
+// NewTableFormatter creates a new TableFormatter
+func NewTableFormatter(header bool, options TableOptions) *TableFormatter {
+	return &TableFormatter{
+		Header:  header,
+		Options: options,
+	}
+}
+
+// Format formats the data as a table
+func (f *TableFormatter) Format(data interface{}) ([]byte, error) {
+	// Convert the data to rows of flattened fields
+	columns, rows, err := tabulate(data, f.Options)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	// Render without colors, which would need querying the terminal
+	renderer := lipgloss.NewRenderer(io.Discard)
+	cell := renderer.NewStyle().Padding(0, 1)
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(renderer.NewStyle()).
+		StyleFunc(func(_, _ int) lipgloss.Style { return cell }).
+		Rows(rows...).
+		Wrap(!f.Options.Truncate)
+	if f.Header {
+		t.Headers(columns...)
+	}
+
+	// Shrink the table to the available width, wrapping or truncating the cells
+	rendered := t.String()
+	width := f.Options.Width
+	if width == 0 {
+		width = terminalWidth()
+	}
+	if width > 0 && lipgloss.Width(rendered) > width {
+		rendered = t.Width(width).String()
+	}
+
+	return []byte(rendered + "\n"), nil
+}
+
+// GetFormat returns the format
+func (f *TableFormatter) GetFormat() Format {
+	return FormatTable
+}
+
+// terminalWidth returns the width of the terminal, or 0 when the output isn't a terminal
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// tabulate converts data to the columns and rows of cells of tabular output. Nested
+// objects are flattened into columns with dotted names.
+func tabulate(data interface{}, options TableOptions) ([]string, [][]string, error) {
+	// Flatten the data into rows
+	var rows []map[string]interface{}
+	var fields []string
+	switch v := data.(type) {
+	case nil:
+	case Record:
+		row, names := flattenRecord(v)
+		rows, fields = []map[string]interface{}{row}, names
+	case []Record:
+		for _, record := range v {
+			row, names := flattenRecord(record)
+			rows = append(rows, row)
+			fields = mergeNames(fields, names)
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			rows = append(rows, flattenMap(item))
+		}
+	case map[string]interface{}:
+		rows = []map[string]interface{}{flattenMap(v)}
+	case []interface{}:
+		for _, item := range v {
+			switch item := item.(type) {
+			case map[string]interface{}:
+				rows = append(rows, flattenMap(item))
+			case Record:
+				row, names := flattenRecord(item)
+				rows = append(rows, row)
+				fields = mergeNames(fields, names)
+			default:
+				rows = append(rows, map[string]interface{}{"value": item})
+			}
+		}
+	default:
+		rows = []map[string]interface{}{{"value": v}}
+	}
+
+	// Get the columns: the selected ones, or all the fields of the rows in the order
+	// of the records or the preferred order
+	var all []string
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		for name := range row {
+			if !seen[name] {
+				seen[name] = true
+				all = append(all, name)
+			}
+		}
+	}
+	if fields != nil {
+		all = mergeNames(fields, all)
+	} else {
+		sortColumns(all, options.Order)
+	}
+
+	columns := all
+	if len(options.Columns) > 0 {
+		columns = selectColumns(all, options.Columns)
+	}
+
+	// Sort the rows
+	if options.SortBy != "" {
+		column := strings.TrimPrefix(options.SortBy, "-")
+		if !seen[column] && len(rows) > 0 {
+			return nil, nil, fmt.Errorf("cannot sort by unknown column %q (available: %s)", column, strings.Join(all, ", "))
+		}
+		sortRows(rows, column, strings.HasPrefix(options.SortBy, "-"))
+	}
+
+	// Format the cells
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		cells[i] = make([]string, len(columns))
+		for j, column := range columns {
+			cells[i][j] = cellValue(row[column])
+		}
+	}
+
+	return columns, cells, nil
+}
+
+// flattenRecord flattens the values of a record, returning the flattened field names in order
+func flattenRecord(record Record) (map[string]interface{}, []string) {
+	row := make(map[string]interface{})
+	var names []string
+	for _, field := range record.Fields {
+		flattenValue(field, record.Values[field], row, &names)
+	}
+	return row, names
+}
+
+// flattenMap flattens the nested objects of a map into fields with dotted names
+func flattenMap(m map[string]interface{}) map[string]interface{} {
+	row := make(map[string]interface{})
+	var names []string
+	for key, value := range m {
+		flattenValue(key, value, row, &names)
+	}
+	return row
+}
+
+// flattenValue adds a value to a row, flattening nested objects under the name
+func flattenValue(name string, value interface{}, row map[string]interface{}, names *[]string) {
+	var nested map[string]interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		nested = v
+	case Record:
+		for _, field := range v.Fields {
+			flattenValue(name+"."+field, v.Values[field], row, names)
+		}
+		if len(v.Fields) > 0 {
+			return
+		}
+	}
+
+	if len(nested) == 0 {
+		row[name] = value
+		*names = append(*names, name)
+		return
+	}
+
+	keys := make([]string, 0, len(nested))
+	for key := range nested {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		flattenValue(name+"."+key, nested[key], row, names)
+	}
+}
+
+// mergeNames returns the names with the other names not among them appended
+func mergeNames(names, others []string) []string {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		seen[name] = true
+	}
+	for _, name := range others {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// sortColumns sorts columns by their position, or the position of their closest
+// parent, in the preferred order; other columns follow alphabetically
+func sortColumns(columns []string, order []string) {
+	rank := make(map[string]int, len(order))
+	for i, name := range order {
+		if _, ok := rank[name]; !ok {
+			rank[name] = i
+		}
+	}
+
+	position := func(column string) int {
+		for name := column; ; {
+			if i, ok := rank[name]; ok {
+				return i
+			}
+			dot := strings.LastIndexByte(name, '.')
+			if dot < 0 {
+				return len(order)
+			}
+			name = name[:dot]
+		}
+	}
+
+	sort.SliceStable(columns, func(i, j int) bool {
+		pi, pj := position(columns[i]), position(columns[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return columns[i] < columns[j]
+	})
+}
+
+// selectColumns returns the selected columns, expanding the columns of nested objects
+// into their fields
+func selectColumns(all, selected []string) []string {
+	available := make(map[string]bool, len(all))
+	for _, name := range all {
+		available[name] = true
+	}
+
+	var columns []string
+	for _, column := range selected {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			continue
+		}
+		if available[column] {
+			columns = append(columns, column)
+			continue
+		}
+
+		// Expand a nested object, or keep an unknown column to show it empty
+		var fields []string
+		for _, name := range all {
+			if strings.HasPrefix(name, column+".") {
+				fields = append(fields, name)
+			}
+		}
+		if len(fields) == 0 {
+			fields = []string{column}
+		}
+		columns = append(columns, fields...)
+	}
+	return columns
+}
+
+// sortRows sorts rows by the values of a column, numerically when they are numbers.
+// Rows without a value come last.
+func sortRows(rows []map[string]interface{}, column string, descending bool) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i][column], rows[j][column]
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+
+		var less, greater bool
+		na, aok := number(a)
+		nb, bok := number(b)
+		if aok && bok {
+			less, greater = na < nb, na > nb
+		} else {
+			sa, sb := cellValue(a), cellValue(b)
+			less, greater = sa < sb, sa > sb
+		}
+		if descending {
+			return greater
+		}
+		return less
+	})
+}
+
+// number returns a value as a float64 when it is a number
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// cellValue formats a value for a cell: nothing for null, JSON for objects and lists
+func cellValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}, Record, []Record:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
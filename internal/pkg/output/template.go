@@ -0,0 +1,256 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/term"
+)
+
+// FormatTemplate represents output rendered with a Go template
+const FormatTemplate Format = "template"
+
+// ansiColors are the SGR codes of the colors of the color helper
+var ansiColors = map[string]string{
+	"black":   "30",
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"white":   "37",
+	"gray":    "90",
+	"grey":    "90",
+}
+
+// TemplateFormatter formats output with a Go text/template
+type TemplateFormatter struct {
+	// Color enables the color and bold helpers; they return the text unchanged otherwise
+	Color bool
+
+	template *template.Template
+}
+
+// NewTemplateFormatter creates a new TemplateFormatter from a template, or from the
+// file it names when prefixed with @. In a template given inline, \n and \t outside
+// actions are a newline and a tab. Colors are enabled when writing to a terminal and
+// NO_COLOR is not set.
+func NewTemplateFormatter(text string) (*TemplateFormatter, error) {
+	if path, ok := strings.CutPrefix(text, "@"); ok {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template file: %w", err)
+		}
+		text = string(content)
+	} else {
+		text = unescapeText(text)
+	}
+
+	f := &TemplateFormatter{
+		Color: term.IsTerminal(int(os.Stdout.Fd())) && os.Getenv("NO_COLOR") == "",
+	}
+
+	tmpl, err := template.New("output").Funcs(f.funcs()).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	f.template = tmpl
+
+	return f, nil
+}
+
+// Format formats the data with the template
+func (f *TemplateFormatter) Format(data interface{}) ([]byte, error) {
+	// Convert the data to plain values, keeping numbers as written
+	if _, ok := data.(string); !ok && data != nil {
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal data: %w", err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(jsonData))
+		decoder.UseNumber()
+		if err := decoder.Decode(&data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal data: %w", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := f.template.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// GetFormat returns the format
+func (f *TemplateFormatter) GetFormat() Format {
+	return FormatTemplate
+}
+
+// funcs returns the helpers available in templates
+func (f *TemplateFormatter) funcs() template.FuncMap {
+	return template.FuncMap{
+		// json formats a value as compact JSON
+		"json": func(value interface{}) (string, error) {
+			data, err := json.Marshal(value)
+			return string(data), err
+		},
+
+		// prettyjson formats a value as indented JSON
+		"prettyjson": func(value interface{}) (string, error) {
+			data, err := json.MarshalIndent(value, "", "  ")
+			return string(data), err
+		},
+
+		// date formats a timestamp (RFC 3339, a date or Unix seconds) with a Go layout
+		"date": func(layout string, value interface{}) (string, error) {
+			t, err := parseTime(value)
+			if err != nil {
+				return "", err
+			}
+			return t.Format(layout), nil
+		},
+
+		// pad pads a value with spaces on the right to a width
+		"pad": func(width int, value interface{}) string {
+			s := toString(value)
+			return s + strings.Repeat(" ", max(width-lipgloss.Width(s), 0))
+		},
+
+		// padleft pads a value with spaces on the left to a width
+		"padleft": func(width int, value interface{}) string {
+			s := toString(value)
+			return strings.Repeat(" ", max(width-lipgloss.Width(s), 0)) + s
+		},
+
+		// join joins the items of a list with a separator
+		"join": func(sep string, value interface{}) string {
+			switch list := value.(type) {
+			case []interface{}:
+				items := make([]string, len(list))
+				for i, item := range list {
+					items[i] = toString(item)
+				}
+				return strings.Join(items, sep)
+			case []string:
+				return strings.Join(list, sep)
+			default:
+				return toString(value)
+			}
+		},
+
+		// color colors a value (black, red, green, yellow, blue, magenta, cyan, white, gray)
+		"color": func(name string, value interface{}) (string, error) {
+			code, ok := ansiColors[strings.ToLower(name)]
+			if !ok {
+				return "", fmt.Errorf("unknown color %q", name)
+			}
+			return f.style(code, value), nil
+		},
+
+		// bold makes a value bold
+		"bold": func(value interface{}) string {
+			return f.style("1", value)
+		},
+
+		"upper": func(value interface{}) string { return strings.ToUpper(toString(value)) },
+		"lower": func(value interface{}) string { return strings.ToLower(toString(value)) },
+	}
+}
+
+// style wraps a value in an ANSI escape sequence when colors are enabled
+func (f *TemplateFormatter) style(code string, value interface{}) string {
+	if !f.Color {
+		return toString(value)
+	}
+	return "\x1b[" + code + "m" + toString(value) + "\x1b[0m"
+}
+
+// unescapeText replaces the \n, \t and \\ escapes outside the actions of a template
+func unescapeText(text string) string {
+	var sb strings.Builder
+	inAction := false
+	for i := 0; i < len(text); i++ {
+		switch {
+		case !inAction && strings.HasPrefix(text[i:], "{{"):
+			inAction = true
+			sb.WriteString("{{")
+			i++
+		case inAction && strings.HasPrefix(text[i:], "}}"):
+			inAction = false
+			sb.WriteString("}}")
+			i++
+		case !inAction && text[i] == '\\' && i+1 < len(text) && strings.IndexByte(`nt\`, text[i+1]) >= 0:
+			sb.WriteByte(map[byte]byte{'n': '\n', 't': '\t', '\\': '\\'}[text[i+1]])
+			i++
+		default:
+			sb.WriteByte(text[i])
+		}
+	}
+	return sb.String()
+}
+
+// toString formats a value for a template: nothing for null, JSON for objects and lists
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// parseTime parses a timestamp given as RFC 3339, a date, or Unix seconds
+// (milliseconds when too large for seconds)
+func parseTime(value interface{}) (time.Time, error) {
+	var seconds float64
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", time.DateOnly} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", v)
+		}
+		seconds = n
+	case json.Number:
+		n, err := v.Float64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", v)
+		}
+		seconds = n
+	case float64:
+		seconds = v
+	case int:
+		seconds = float64(v)
+	case int64:
+		seconds = float64(v)
+	default:
+		return time.Time{}, fmt.Errorf("invalid timestamp %v", value)
+	}
+
+	if seconds > 1e11 {
+		seconds /= 1000
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))).UTC(), nil
+}
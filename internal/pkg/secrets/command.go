@@ -0,0 +1,41 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// RunCommand runs a shell command and returns its output as the secret, without
+// the trailing newline. The command can prompt on the terminal, e.g. for a GPG
+// passphrase, since stdin and stderr are passed through.
+func RunCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	var stdout bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	log.Debug("Running auth command", "command", command)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("auth command failed: %w", err)
+	}
+
+	secret := strings.TrimRight(stdout.String(), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("auth command printed nothing")
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,326 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// PassphraseEnv is the environment variable holding the passphrase of the secret file
+const PassphraseEnv = "ONTAP_SECRETS_PASSPHRASE"
+
+// Key derivation methods of the secret file
+const (
+	// kdfPBKDF2 derives the key from the passphrase in PassphraseEnv
+	kdfPBKDF2 = "pbkdf2-sha256"
+
+	// kdfKeyFile uses a random key stored next to the secret file
+	kdfKeyFile = "keyfile"
+)
+
+// pbkdf2Iterations is the number of PBKDF2 iterations used to derive the key from a passphrase
+const pbkdf2Iterations = 600000
+
+// encryptedFile is the on-disk format of the secret file
+type encryptedFile struct {
+	// Version is the format version
+	Version int `json:"version"`
+
+	// KDF is how the encryption key is obtained
+	KDF string `json:"kdf"`
+
+	// Iterations is the number of PBKDF2 iterations
+	Iterations int `json:"iterations,omitempty"`
+
+	// Salt is the PBKDF2 salt
+	Salt []byte `json:"salt,omitempty"`
+
+	// Nonce is the AES-GCM nonce
+	Nonce []byte `json:"nonce"`
+
+	// Data is the encrypted JSON object of secrets
+	Data []byte `json:"data"`
+}
+
+// FileStore stores secrets in a file encrypted with AES-256-GCM. The key is derived
+// from the passphrase in ONTAP_SECRETS_PASSPHRASE, or is a random key kept in a
+// separate file readable only by the current user.
+type FileStore struct {
+	// Path is the path of the encrypted secret file
+	Path string
+
+	// KeyPath is the path of the key file used without a passphrase
+	KeyPath string
+}
+
+// NewFileStore creates a new FileStore
+func NewFileStore(path string) *FileStore {
+	if path == "" {
+		path = filepath.Join(defaultDir(), "secrets.enc")
+	}
+	return &FileStore{
+		Path:    path,
+		KeyPath: strings.TrimSuffix(path, filepath.Ext(path)) + ".key",
+	}
+}
+
+// defaultDir returns the default directory for the secret file
+func defaultDir() string {
+	// Try to get the user config directory (platform-specific)
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		// Fall back to user home directory if UserConfigDir fails
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			log.Warn("Failed to get user home directory", "error", err)
+			return ".ontap"
+		}
+		return filepath.Join(homeDir, ".ontap")
+	}
+
+	return filepath.Join(configDir, "ontap")
+}
+
+// Resolve returns the secret with the given name
+func (s *FileStore) Resolve(name string) (string, error) {
+	secrets, err := s.load()
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := secrets[name]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found in %s", name, s.Path)
+	}
+
+	return value, nil
+}
+
+// Set stores a secret
+func (s *FileStore) Set(name, value string) error {
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	secrets[name] = value
+	return s.save(secrets)
+}
+
+// Delete deletes a secret, reporting whether it existed
+func (s *FileStore) Delete(name string) (bool, error) {
+	secrets, err := s.load()
+	if err != nil {
+		return false, err
+	}
+
+	if _, ok := secrets[name]; !ok {
+		return false, nil
+	}
+
+	delete(secrets, name)
+	return true, s.save(secrets)
+}
+
+// List lists the names of the stored secrets
+func (s *FileStore) List() ([]string, error) {
+	secrets, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// load reads and decrypts the secret file
+func (s *FileStore) load() (map[string]string, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("failed to read secret file: %w", err)
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse secret file: %w", err)
+	}
+	if file.Version != 1 {
+		return nil, fmt.Errorf("unsupported secret file version: %d", file.Version)
+	}
+
+	// Get the key the file was encrypted with
+	var key []byte
+	switch file.KDF {
+	case kdfPBKDF2:
+		passphrase := os.Getenv(PassphraseEnv)
+		if passphrase == "" {
+			return nil, fmt.Errorf("the secret file is encrypted with a passphrase; set %s", PassphraseEnv)
+		}
+		key, err = pbkdf2.Key(sha256.New, passphrase, file.Salt, file.Iterations, 32)
+	case kdfKeyFile:
+		key, err = s.readKey()
+	default:
+		return nil, fmt.Errorf("unsupported secret file key derivation: %s", file.KDF)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret file (wrong passphrase or key?)")
+	}
+
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets: %w", err)
+	}
+
+	return secrets, nil
+}
+
+// save encrypts and writes the secret file
+func (s *FileStore) save(secrets map[string]string) error {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+
+	// Use the passphrase if one is set, otherwise the key file
+	file := encryptedFile{Version: 1}
+	var key []byte
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		file.KDF = kdfPBKDF2
+		file.Iterations = pbkdf2Iterations
+		file.Salt = make([]byte, 16)
+		if _, err := rand.Read(file.Salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+		key, err = pbkdf2.Key(sha256.New, passphrase, file.Salt, file.Iterations, 32)
+	} else {
+		file.KDF = kdfKeyFile
+		key, err = s.readOrCreateKey()
+	}
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	file.Data = gcm.Seal(nil, file.Nonce, plaintext, nil)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal secret file: %w", err)
+	}
+
+	return writePrivateFile(s.Path, data)
+}
+
+// readKey reads the key file
+func (s *FileStore) readKey() ([]byte, error) {
+	data, err := os.ReadFile(s.KeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret key: %w", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("invalid secret key in %s", s.KeyPath)
+	}
+
+	return key, nil
+}
+
+// readOrCreateKey reads the key file, creating it with a random key if it doesn't exist
+func (s *FileStore) readOrCreateKey() ([]byte, error) {
+	if _, err := os.Stat(s.KeyPath); err == nil {
+		return s.readKey()
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate secret key: %w", err)
+	}
+	if err := writePrivateFile(s.KeyPath, []byte(base64.StdEncoding.EncodeToString(key)+"\n")); err != nil {
+		return nil, err
+	}
+
+	log.Debug("Created secret key", "path", s.KeyPath)
+	return key, nil
+}
+
+// newGCM creates an AES-GCM cipher for a key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return gcm, nil
+}
+
+// writePrivateFile atomically writes a file readable only by the current user
+func writePrivateFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Write to a temporary file first so the file is never half-written
+	tmp, err := os.CreateTemp(dir, ".secret-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
+	}
+
+	return nil
+}
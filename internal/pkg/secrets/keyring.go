@@ -0,0 +1,241 @@
+package secrets
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Secret Service D-Bus names
+const (
+	secretServiceName       = "org.freedesktop.secrets"
+	secretServicePath       = "/org/freedesktop/secrets"
+	secretServiceInterface  = "org.freedesktop.Secret.Service"
+	secretItemInterface     = "org.freedesktop.Secret.Item"
+	secretPromptInterface   = "org.freedesktop.Secret.Prompt"
+	secretSessionInterface  = "org.freedesktop.Secret.Session"
+	defaultCollectionPath   = "/org/freedesktop/secrets/aliases/default"
+	secretItemLabelProperty = "org.freedesktop.Secret.Item.Label"
+	secretItemAttrsProperty = "org.freedesktop.Secret.Item.Attributes"
+)
+
+// DefaultKeyringService is the service used for keyring references without one
+const DefaultKeyringService = "ontap"
+
+// promptTimeout is how long to wait for the user to answer an unlock prompt
+const promptTimeout = 2 * time.Minute
+
+// secretServiceSecret is the Secret struct of the Secret Service API
+type secretServiceSecret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// Keyring stores secrets in the freedesktop Secret Service (GNOME Keyring, KWallet)
+// over D-Bus. Items are identified by "service" and "username" attributes, the same
+// ones used by secret-tool and most other tools, so references have the form
+// SERVICE/ACCOUNT, or just ACCOUNT for the ontap service.
+type Keyring struct{}
+
+// NewKeyring creates a new Keyring
+func NewKeyring() *Keyring {
+	return &Keyring{}
+}
+
+// Resolve returns the secret of a keyring item
+func (k *Keyring) Resolve(name string) (string, error) {
+	conn, session, err := openSecretSession()
+	if err != nil {
+		return "", err
+	}
+	defer closeSecretSession(conn, session)
+
+	// Find the item
+	items, err := searchItems(conn, keyringAttributes(name))
+	if err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "", fmt.Errorf("no keyring item for %s", name)
+	}
+
+	// Get the secret
+	var secret secretServiceSecret
+	if err := conn.Object(secretServiceName, items[0]).Call(secretItemInterface+".GetSecret", 0, session).Store(&secret); err != nil {
+		return "", fmt.Errorf("failed to get keyring secret: %w", err)
+	}
+
+	return string(secret.Value), nil
+}
+
+// Set stores a secret in the default keyring collection, replacing an existing item
+func (k *Keyring) Set(name, value string) error {
+	conn, session, err := openSecretSession()
+	if err != nil {
+		return err
+	}
+	defer closeSecretSession(conn, session)
+
+	// The default collection must be unlocked to add items
+	if err := unlock(conn, []dbus.ObjectPath{defaultCollectionPath}); err != nil {
+		return err
+	}
+
+	properties := map[string]dbus.Variant{
+		secretItemLabelProperty: dbus.MakeVariant("ontap: " + name),
+		secretItemAttrsProperty: dbus.MakeVariant(keyringAttributes(name)),
+	}
+	secret := secretServiceSecret{
+		Session:     session,
+		Value:       []byte(value),
+		ContentType: "text/plain",
+	}
+
+	var item, prompt dbus.ObjectPath
+	if err := conn.Object(secretServiceName, defaultCollectionPath).
+		Call("org.freedesktop.Secret.Collection.CreateItem", 0, properties, secret, true).
+		Store(&item, &prompt); err != nil {
+		return fmt.Errorf("failed to create keyring item: %w", err)
+	}
+
+	return runPrompt(conn, prompt)
+}
+
+// Delete deletes the keyring items of a secret, reporting whether any existed
+func (k *Keyring) Delete(name string) (bool, error) {
+	conn, session, err := openSecretSession()
+	if err != nil {
+		return false, err
+	}
+	defer closeSecretSession(conn, session)
+
+	items, err := searchItems(conn, keyringAttributes(name))
+	if err != nil {
+		return false, err
+	}
+
+	for _, item := range items {
+		var prompt dbus.ObjectPath
+		if err := conn.Object(secretServiceName, item).Call(secretItemInterface+".Delete", 0).Store(&prompt); err != nil {
+			return false, fmt.Errorf("failed to delete keyring item: %w", err)
+		}
+		if err := runPrompt(conn, prompt); err != nil {
+			return false, err
+		}
+	}
+
+	return len(items) > 0, nil
+}
+
+// keyringAttributes returns the attributes that identify the item of a secret
+func keyringAttributes(name string) map[string]string {
+	service, account, found := strings.Cut(name, "/")
+	if !found {
+		service, account = DefaultKeyringService, name
+	}
+	return map[string]string{
+		"service":  service,
+		"username": account,
+	}
+}
+
+// openSecretSession connects to the session bus and opens a Secret Service session.
+// Secrets are transferred unencrypted, which is fine on the local session bus.
+func openSecretSession() (*dbus.Conn, dbus.ObjectPath, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to connect to the session bus: %w", err)
+	}
+
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	if err := conn.Object(secretServiceName, secretServicePath).
+		Call(secretServiceInterface+".OpenSession", 0, "plain", dbus.MakeVariant("")).
+		Store(&output, &session); err != nil {
+		conn.Close()
+		return nil, "", fmt.Errorf("failed to open Secret Service session: %w", err)
+	}
+
+	return conn, session, nil
+}
+
+// closeSecretSession closes a Secret Service session and its connection
+func closeSecretSession(conn *dbus.Conn, session dbus.ObjectPath) {
+	conn.Object(secretServiceName, session).Call(secretSessionInterface+".Close", 0)
+	conn.Close()
+}
+
+// searchItems returns the items matching the attributes, unlocking them if needed
+func searchItems(conn *dbus.Conn, attributes map[string]string) ([]dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	if err := conn.Object(secretServiceName, secretServicePath).
+		Call(secretServiceInterface+".SearchItems", 0, attributes).
+		Store(&unlocked, &locked); err != nil {
+		return nil, fmt.Errorf("failed to search keyring: %w", err)
+	}
+
+	if len(locked) > 0 {
+		if err := unlock(conn, locked); err != nil {
+			return nil, err
+		}
+	}
+
+	return append(unlocked, locked...), nil
+}
+
+// unlock unlocks keyring objects, prompting the user if needed
+func unlock(conn *dbus.Conn, objects []dbus.ObjectPath) error {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	if err := conn.Object(secretServiceName, secretServicePath).
+		Call(secretServiceInterface+".Unlock", 0, objects).
+		Store(&unlocked, &prompt); err != nil {
+		return fmt.Errorf("failed to unlock keyring: %w", err)
+	}
+
+	return runPrompt(conn, prompt)
+}
+
+// runPrompt shows a Secret Service prompt and waits for the user to complete it.
+// The path "/" means no prompt is needed.
+func runPrompt(conn *dbus.Conn, prompt dbus.ObjectPath) error {
+	if prompt == "" || prompt == "/" {
+		return nil
+	}
+
+	// Subscribe to the completion signal before showing the prompt
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(secretPromptInterface),
+		dbus.WithMatchMember("Completed"),
+	); err != nil {
+		return fmt.Errorf("failed to watch keyring prompt: %w", err)
+	}
+	signals := make(chan *dbus.Signal, 1)
+	conn.Signal(signals)
+	defer conn.RemoveSignal(signals)
+
+	if err := conn.Object(secretServiceName, prompt).Call(secretPromptInterface+".Prompt", 0, "").Err; err != nil {
+		return fmt.Errorf("failed to show keyring prompt: %w", err)
+	}
+
+	timeout := time.After(promptTimeout)
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Path != prompt || len(signal.Body) == 0 {
+				continue
+			}
+			if dismissed, _ := signal.Body[0].(bool); dismissed {
+				return fmt.Errorf("keyring prompt was dismissed")
+			}
+			return nil
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for the keyring prompt")
+		}
+	}
+}
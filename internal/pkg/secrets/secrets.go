@@ -0,0 +1,111 @@
+// Package secrets resolves the secret references used in the config, so that
+// credentials don't have to be stored in the config file.
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+)
+
+// Reference schemes
+const (
+	// SchemeEnv references an environment variable (env:NAME)
+	SchemeEnv = "env"
+
+	// SchemeSecret references an entry of the encrypted secret file (secret:NAME)
+	SchemeSecret = "secret"
+
+	// SchemeKeyring references an item of the Secret Service keyring (keyring:SERVICE/ACCOUNT)
+	SchemeKeyring = "keyring"
+)
+
+// Resolver resolves the secret references of a scheme
+type Resolver interface {
+	// Resolve returns the secret with the given name
+	Resolve(name string) (string, error)
+}
+
+var (
+	// resolversMu guards resolvers
+	resolversMu sync.RWMutex
+
+	// resolvers maps the reference schemes to their resolvers
+	resolvers = map[string]Resolver{
+		SchemeEnv:     EnvResolver{},
+		SchemeSecret:  NewFileStore(""),
+		SchemeKeyring: NewKeyring(),
+	}
+)
+
+// Register registers the resolver for a reference scheme, replacing any existing one
+func Register(scheme string, resolver Resolver) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	resolvers[scheme] = resolver
+}
+
+// ParseReference splits a secret reference into its scheme and name. Both the
+// scheme:name and scheme://name forms are accepted. ok is false if the value
+// doesn't start with a registered scheme.
+func ParseReference(value string) (scheme, name string, ok bool) {
+	scheme, name, found := strings.Cut(value, ":")
+	if !found {
+		return "", "", false
+	}
+
+	resolversMu.RLock()
+	_, ok = resolvers[scheme]
+	resolversMu.RUnlock()
+	if !ok {
+		return "", "", false
+	}
+
+	return scheme, strings.TrimPrefix(name, "//"), true
+}
+
+// Resolve resolves a config value. Secret references are replaced with the secret
+// they point to, a value of the form ${VAR} is read from the environment, and any
+// other value is returned unchanged.
+func Resolve(value string) (string, error) {
+	// Expand ${VAR} values
+	if strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}") {
+		envVar := value[2 : len(value)-1]
+		envValue := os.Getenv(envVar)
+		if envValue == "" {
+			log.Warn("Environment variable not set", "var", envVar)
+		}
+		return envValue, nil
+	}
+
+	scheme, name, ok := ParseReference(value)
+	if !ok {
+		return value, nil
+	}
+
+	resolversMu.RLock()
+	resolver := resolvers[scheme]
+	resolversMu.RUnlock()
+
+	secret, err := resolver.Resolve(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret %s: %w", value, err)
+	}
+
+	return secret, nil
+}
+
+// EnvResolver resolves references to environment variables
+type EnvResolver struct{}
+
+// Resolve returns the value of an environment variable
+func (EnvResolver) Resolve(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
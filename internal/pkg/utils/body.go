@@ -0,0 +1,302 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/fynxlabs/ontap/internal/pkg/openapi"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// maxBodyFlagDepth is the deepest level of nested body properties that get their own flag
+const maxBodyFlagDepth = 3
+
+// BodyProperty represents a request body property exposed as a flag
+type BodyProperty struct {
+	// Path is the dotted path of the property in the body (e.g. address.city), which is
+	// the name of its flag
+	Path string
+
+	// Segments are the names of the property and its parent objects, outermost first.
+	// Property names can contain dots, so the body is nested by these and not by Path.
+	Segments []string
+
+	// Type is the schema type of the property
+	Type string
+
+	// ItemsType is the schema type of the items for array properties
+	ItemsType string
+
+	// Description is a description of the property
+	Description string
+
+	// Required indicates if the property must be present in the body
+	Required bool
+
+	// Enum is a list of allowed values for the property
+	Enum []interface{}
+}
+
+// BodyProperties returns the properties of a request body schema that are exposed as
+// flags, descending into nested objects up to maxBodyFlagDepth. A property is only marked
+// required when it and all of its parent objects are required.
+func BodyProperties(schema *openapi.Schema) []BodyProperty {
+	return bodyProperties(schema, nil, 1, true)
+}
+
+// bodyProperties returns the properties of an object schema nested in the named parent
+// objects
+func bodyProperties(schema *openapi.Schema, parents []string, depth int, parentRequired bool) []BodyProperty {
+	if schema == nil || len(schema.Properties) == 0 {
+		return nil
+	}
+
+	// Sort the property names so flags are generated in a stable order
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result []BodyProperty
+	for _, name := range names {
+		prop := schema.Properties[name]
+		if prop == nil {
+			continue
+		}
+
+		segments := append(slices.Clone(parents), name)
+		required := parentRequired && slices.Contains(schema.Required, name)
+
+		// Descend into nested objects with known properties
+		if len(prop.Properties) > 0 && depth < maxBodyFlagDepth {
+			result = append(result, bodyProperties(prop, segments, depth+1, required)...)
+			continue
+		}
+
+		// Objects are passed as JSON when they are too deep or free-form
+		propType := prop.Type
+		if propType == "" && len(prop.Properties) > 0 {
+			propType = "object"
+		}
+
+		bodyProp := BodyProperty{
+			Path:        strings.Join(segments, "."),
+			Segments:    segments,
+			Type:        propType,
+			Description: prop.Description,
+			Required:    required,
+			Enum:        prop.Enum,
+		}
+		if prop.Items != nil {
+			bodyProp.ItemsType = prop.Items.Type
+		}
+
+		result = append(result, bodyProp)
+	}
+
+	return result
+}
+
+// AddBodyFlags adds a flag for each request body property to a command. Properties
+// whose name is already used by a local flag or by one of the reserved flags are
+// skipped and can still be set through --data. The added properties are returned.
+func AddBodyFlags(cmd *cobra.Command, properties []BodyProperty, reserved *pflag.FlagSet) []BodyProperty {
+	var added []BodyProperty
+	for _, prop := range properties {
+		// Skip properties that clash with an existing flag
+		if cmd.Flags().Lookup(prop.Path) != nil || (reserved != nil && reserved.Lookup(prop.Path) != nil) {
+			log.Debug("Skipping body flag that clashes with an existing flag", "flag", prop.Path)
+			continue
+		}
+
+		usage := bodyFlagUsage(prop)
+		switch prop.Type {
+		case "integer":
+			cmd.Flags().Int64(prop.Path, 0, usage)
+		case "number":
+			cmd.Flags().Float64(prop.Path, 0, usage)
+		case "boolean":
+			cmd.Flags().Bool(prop.Path, false, usage)
+		case "array":
+			cmd.Flags().StringArray(prop.Path, nil, usage)
+		default:
+			cmd.Flags().String(prop.Path, "", usage)
+		}
+
+		added = append(added, prop)
+	}
+
+	return added
+}
+
+// bodyFlagUsage returns the usage string for a body property flag
+func bodyFlagUsage(prop BodyProperty) string {
+	usage := prop.Description
+	if usage == "" {
+		usage = fmt.Sprintf("Request body field %s", prop.Path)
+	}
+
+	switch {
+	case prop.Type == "object":
+		usage += " (JSON object)"
+	case prop.Type == "array" && (prop.ItemsType == "object" || prop.ItemsType == "array"):
+		usage += " (repeatable, JSON value per item)"
+	case prop.Type == "array":
+		usage += " (repeatable)"
+	}
+
+	if len(prop.Enum) > 0 {
+		values := make([]string, len(prop.Enum))
+		for i, v := range prop.Enum {
+			values[i] = fmt.Sprintf("%v", v)
+		}
+		usage += fmt.Sprintf(" [one of: %s]", strings.Join(values, ", "))
+	}
+
+	if prop.Required {
+		usage += " (required)"
+	}
+
+	return usage
+}
+
+// BuildBody merges the body property flags that were set into a base body,
+// which is typically the document given with --data
+func BuildBody(flags *pflag.FlagSet, properties []BodyProperty, base interface{}) (interface{}, error) {
+	// Collect the properties that were set
+	var changed []BodyProperty
+	for _, prop := range properties {
+		if flags.Changed(prop.Path) {
+			changed = append(changed, prop)
+		}
+	}
+
+	if len(changed) == 0 && base == nil {
+		return nil, nil
+	}
+
+	// The body properties can only be merged into an object
+	body, ok := base.(map[string]interface{})
+	if !ok {
+		if base != nil {
+			if len(changed) == 0 {
+				return base, nil
+			}
+			return nil, fmt.Errorf("cannot set body fields on a non-object body")
+		}
+		body = map[string]interface{}{}
+	}
+
+	// Set the value of each property
+	for _, prop := range changed {
+		value, err := bodyFlagValue(flags, prop)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := setBodyValue(body, prop, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return body, nil
+}
+
+// bodyFlagValue gets the typed value of a body property flag
+func bodyFlagValue(flags *pflag.FlagSet, prop BodyProperty) (interface{}, error) {
+	var value interface{}
+	var err error
+
+	switch prop.Type {
+	case "integer":
+		value, err = flags.GetInt64(prop.Path)
+	case "number":
+		value, err = flags.GetFloat64(prop.Path)
+	case "boolean":
+		value, err = flags.GetBool(prop.Path)
+	case "array":
+		var items []string
+		items, err = flags.GetStringArray(prop.Path)
+		if err == nil {
+			values := make([]interface{}, 0, len(items))
+			for _, item := range items {
+				v, convErr := convertBodyValue(item, prop.ItemsType)
+				if convErr != nil {
+					return nil, fmt.Errorf("invalid value for --%s: %w", prop.Path, convErr)
+				}
+				values = append(values, v)
+			}
+			value = values
+		}
+	default:
+		var s string
+		s, err = flags.GetString(prop.Path)
+		if err == nil {
+			value, err = convertBodyValue(s, prop.Type)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for --%s: %w", prop.Path, err)
+			}
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get body flag %s: %w", prop.Path, err)
+	}
+
+	return value, nil
+}
+
+// convertBodyValue converts a string flag value to the given schema type
+func convertBodyValue(s, schemaType string) (interface{}, error) {
+	switch schemaType {
+	case "integer":
+		return strconv.ParseInt(s, 10, 64)
+	case "number":
+		return strconv.ParseFloat(s, 64)
+	case "boolean":
+		return strconv.ParseBool(s)
+	case "object", "array":
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return nil, fmt.Errorf("expected JSON %s: %w", schemaType, err)
+		}
+		return v, nil
+	default:
+		return s, nil
+	}
+}
+
+// setBodyValue sets the value of a property in a body, creating intermediate objects
+func setBodyValue(body map[string]interface{}, prop BodyProperty, value interface{}) error {
+	segments := prop.Segments
+	if len(segments) == 0 {
+		segments = []string{prop.Path}
+	}
+
+	current := body
+	for i, segment := range segments[:len(segments)-1] {
+		next, ok := current[segment]
+		if !ok {
+			child := map[string]interface{}{}
+			current[segment] = child
+			current = child
+			continue
+		}
+
+		child, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot set body field %s: %s is not an object", prop.Path, strings.Join(segments[:i+1], "."))
+		}
+		current = child
+	}
+
+	current[segments[len(segments)-1]] = value
+	return nil
+}
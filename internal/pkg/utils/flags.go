@@ -4,25 +4,38 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/log"
+	"github.com/fynxlabs/ontap/internal/pkg/http"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
+// ParameterAnnotation is the flag annotation holding the location of the parameter a
+// flag sets
+const ParameterAnnotation = "ontap_parameter"
+
+// ParameterFlagPrefix prefixes the flags of parameters whose name clashes with another
+// flag, such as --param-timeout for a timeout parameter
+const ParameterFlagPrefix = "param-"
+
 // AddGlobalFlags adds global flags to a command
 func AddGlobalFlags(cmd *cobra.Command) {
 	// Add global flags
 	cmd.PersistentFlags().StringP("config", "c", "", "Config file (default is platform-specific user config directory)")
-	cmd.PersistentFlags().StringP("output", "o", "json", "Output format (json, yaml, csv, text, table)")
+	cmd.PersistentFlags().StringP("output", "o", "json", "Output format (json, yaml, csv, text, table, template=<template|@file|name>)")
 	cmd.PersistentFlags().StringP("log-level", "l", "info", "Log level (debug, info, warn, error)")
 	cmd.PersistentFlags().BoolP("verbose", "v", false, "Verbose output")
 	cmd.PersistentFlags().Bool("dry-run", false, "Dry run (don't execute requests)")
 	cmd.PersistentFlags().String("save", "", "Save response to file")
-	cmd.PersistentFlags().String("extract", "", "Extract fields from response (comma-separated)")
-	cmd.PersistentFlags().String("filter", "", "Filter response using JQ-like syntax")
+	cmd.PersistentFlags().String("extract", "", "Extract fields from the response (comma-separated paths, optionally name=path)")
+	cmd.PersistentFlags().String("filter", "", "Filter the response with a jq expression")
+	cmd.PersistentFlags().String("columns", "", "Columns of csv and table output, in order (comma-separated)")
+	cmd.PersistentFlags().String("sort-by", "", "Column to sort csv and table output by (prefix with - for descending order)")
+	cmd.PersistentFlags().Bool("truncate", false, "Truncate the cells of tables wider than the terminal instead of wrapping them")
 
 	// Bind flags to viper
 	if err := viper.BindPFlag("config", cmd.PersistentFlags().Lookup("config")); err != nil {
@@ -49,6 +62,15 @@ func AddGlobalFlags(cmd *cobra.Command) {
 	if err := viper.BindPFlag("filter", cmd.PersistentFlags().Lookup("filter")); err != nil {
 		log.Warn("Failed to bind flag", "flag", "filter", "error", err)
 	}
+	if err := viper.BindPFlag("columns", cmd.PersistentFlags().Lookup("columns")); err != nil {
+		log.Warn("Failed to bind flag", "flag", "columns", "error", err)
+	}
+	if err := viper.BindPFlag("sort_by", cmd.PersistentFlags().Lookup("sort-by")); err != nil {
+		log.Warn("Failed to bind flag", "flag", "sort_by", "error", err)
+	}
+	if err := viper.BindPFlag("truncate", cmd.PersistentFlags().Lookup("truncate")); err != nil {
+		log.Warn("Failed to bind flag", "flag", "truncate", "error", err)
+	}
 
 	// Bind environment variables
 	viper.SetEnvPrefix("OTAP")
@@ -59,41 +81,69 @@ func AddGlobalFlags(cmd *cobra.Command) {
 // AddRequestFlags adds request flags to a command
 func AddRequestFlags(cmd *cobra.Command) {
 	// Add request flags
-	cmd.Flags().StringP("data", "d", "", "Request body data (JSON string or @file)")
+	cmd.Flags().StringP("data", "d", "", "Request body data (JSON, or the body as is for other content types; @file to read a file, @- for stdin)")
 	cmd.Flags().StringArrayP("header", "H", nil, "Request header (key:value)")
 	cmd.Flags().StringArrayP("query", "q", nil, "Query parameter (key=value)")
-	cmd.Flags().StringArrayP("form", "F", nil, "Form data (key=value or key=@file)")
+	cmd.Flags().StringArrayP("form", "F", nil, "Form field (key=value, key=@file or key=@- for stdin; ;type= and ;filename= set the part's content type and file name)")
 	cmd.Flags().StringP("auth", "a", "", "Authentication (username:password, Bearer token, or API key)")
-	cmd.Flags().StringP("content-type", "t", "", "Content type")
+	cmd.Flags().StringP("content-type", "t", "", "Content type of the request body (default: chosen from the spec)")
+	cmd.Flags().Bool("no-validate", false, "Skip validation of the request against the OpenAPI schema")
+	cmd.Flags().String("validate-response", "", "Validate the response against the OpenAPI schema (off, warn, strict)")
+	cmd.Flags().Lookup("validate-response").NoOptDefVal = "warn"
+	cmd.Flags().String("fail-on", "4xx,5xx", "Response statuses that exit non-zero (status classes like 4xx, codes like 404, ranges like 400-403, or none)")
+	cmd.Flags().Duration("timeout", 0, "Timeout of each request attempt (default 30s)")
+	cmd.Flags().Int("retries", 0, "Number of times to retry on connection errors, 429 and 5xx")
+	cmd.Flags().String("idempotency-key", "", "Idempotency-Key header, which allows retrying POST and PATCH requests (generated when given without a value)")
+	cmd.Flags().Lookup("idempotency-key").NoOptDefVal = "auto"
+	cmd.Flags().String("server", "", "Server from the spec to send the request to (index, description, or a URL)")
+	cmd.Flags().StringArray("server-var", nil, "Server variable (name=value)")
 }
 
-// AddParameterFlags adds parameter flags to a command based on OpenAPI parameters
-func AddParameterFlags(cmd *cobra.Command, parameters []Parameter) error {
-	for _, param := range parameters {
-		// Skip parameters that are already added
-		if cmd.Flags().Lookup(param.Name) != nil {
-			continue
-		}
+// AddPaginationFlags adds the flags for following the pages of a list operation
+func AddPaginationFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("all", false, "Fetch all pages and output the merged items")
+	cmd.Flags().Int("max-pages", 0, "Maximum number of pages to fetch (implies --all; 0 for no limit)")
+}
+
+// AddParameterFlags adds parameter flags to a command based on OpenAPI parameters.
+// Parameters that clash with an existing or reserved flag get a flag prefixed with
+// ParameterFlagPrefix instead. The flags are annotated with ParameterAnnotation.
+func AddParameterFlags(cmd *cobra.Command, parameters []Parameter, reserved *pflag.FlagSet) error {
+	clashes := func(name string) bool {
+		return cmd.Flags().Lookup(name) != nil || (reserved != nil && reserved.Lookup(name) != nil)
+	}
 
+	for _, param := range parameters {
 		// Add the flag based on the parameter type
 		switch param.In {
 		case "path":
 			// Path parameters are handled by the command arguments
 			continue
-		case "query":
-			addQueryParameterFlag(cmd, param)
-		case "header":
-			addHeaderParameterFlag(cmd, param)
-		case "cookie":
-			addCookieParameterFlag(cmd, param)
+		case "query", "header", "cookie":
 		default:
 			return fmt.Errorf("unsupported parameter location: %s", param.In)
 		}
 
+		// Prefix parameters that clash with an existing flag
+		name := param.Name
+		if clashes(name) {
+			name = ParameterFlagPrefix + param.Name
+			if clashes(name) {
+				log.Warn("Skipping parameter flag that clashes with existing flags", "parameter", param.Name, "flag", name)
+				continue
+			}
+			log.Debug("Prefixing parameter flag that clashes with an existing flag", "parameter", param.Name, "flag", name)
+		}
+
+		addParameterFlag(cmd, name, param)
+		if err := cmd.Flags().SetAnnotation(name, ParameterAnnotation, []string{param.In}); err != nil {
+			return fmt.Errorf("failed to annotate parameter flag %s: %w", name, err)
+		}
+
 		// Mark the flag as required if necessary
 		if param.Required {
-			if err := cmd.MarkFlagRequired(param.Name); err != nil {
-				log.Warn("Failed to mark flag as required", "flag", param.Name, "error", err)
+			if err := cmd.MarkFlagRequired(name); err != nil {
+				log.Warn("Failed to mark flag as required", "flag", name, "error", err)
 			}
 		}
 	}
@@ -101,6 +151,18 @@ func AddParameterFlags(cmd *cobra.Command, parameters []Parameter) error {
 	return nil
 }
 
+// ParameterFlag returns the flag of a parameter in the given location: the flag named
+// after it, or the prefixed one when its name clashes with another flag
+func ParameterFlag(flags *pflag.FlagSet, name, in string) *pflag.Flag {
+	for _, flagName := range []string{name, ParameterFlagPrefix + name} {
+		flag := flags.Lookup(flagName)
+		if flag != nil && slices.Contains(flag.Annotations[ParameterAnnotation], in) {
+			return flag
+		}
+	}
+	return nil
+}
+
 // Parameter represents an OpenAPI parameter
 type Parameter struct {
 	Name        string
@@ -118,12 +180,13 @@ type ParameterSchema struct {
 	Enum    []interface{}
 }
 
-// addQueryParameterFlag adds a query parameter flag to a command
-func addQueryParameterFlag(cmd *cobra.Command, param Parameter) {
+// addParameterFlag adds a query, header or cookie parameter flag of the given name to a
+// command. Array parameters take repeated flags and object parameters a JSON object.
+func addParameterFlag(cmd *cobra.Command, name string, param Parameter) {
 	// Add the flag based on the parameter type
 	if param.Schema == nil {
 		// Default to string
-		cmd.Flags().String(param.Name, "", param.Description)
+		cmd.Flags().String(name, "", param.Description)
 		return
 	}
 
@@ -133,15 +196,23 @@ func addQueryParameterFlag(cmd *cobra.Command, param Parameter) {
 		if param.Schema.Default != nil {
 			defaultValue = fmt.Sprintf("%v", param.Schema.Default)
 		}
-		cmd.Flags().String(param.Name, defaultValue, param.Description)
-	case "integer", "number":
+		cmd.Flags().String(name, defaultValue, param.Description)
+	case "integer":
 		defaultValue := 0
 		if param.Schema.Default != nil {
 			if v, ok := param.Schema.Default.(float64); ok {
 				defaultValue = int(v)
 			}
 		}
-		cmd.Flags().Int(param.Name, defaultValue, param.Description)
+		cmd.Flags().Int(name, defaultValue, param.Description)
+	case "number":
+		defaultValue := 0.0
+		if param.Schema.Default != nil {
+			if v, ok := param.Schema.Default.(float64); ok {
+				defaultValue = v
+			}
+		}
+		cmd.Flags().Float64(name, defaultValue, param.Description)
 	case "boolean":
 		defaultValue := false
 		if param.Schema.Default != nil {
@@ -149,26 +220,16 @@ func addQueryParameterFlag(cmd *cobra.Command, param Parameter) {
 				defaultValue = v
 			}
 		}
-		cmd.Flags().Bool(param.Name, defaultValue, param.Description)
+		cmd.Flags().Bool(name, defaultValue, param.Description)
 	case "array":
-		cmd.Flags().StringArray(param.Name, nil, param.Description)
+		cmd.Flags().StringArray(name, nil, param.Description)
+	case "object":
+		cmd.Flags().String(name, "", strings.TrimSpace(param.Description+" (JSON object)"))
 	default:
-		cmd.Flags().String(param.Name, "", param.Description)
+		cmd.Flags().String(name, "", param.Description)
 	}
 }
 
-// addHeaderParameterFlag adds a header parameter flag to a command
-func addHeaderParameterFlag(cmd *cobra.Command, param Parameter) {
-	// Add the flag
-	cmd.Flags().String(param.Name, "", param.Description)
-}
-
-// addCookieParameterFlag adds a cookie parameter flag to a command
-func addCookieParameterFlag(cmd *cobra.Command, param Parameter) {
-	// Add the flag
-	cmd.Flags().String(param.Name, "", param.Description)
-}
-
 // GetFlagValue gets a flag value from a command
 func GetFlagValue(flags *pflag.FlagSet, name string) (interface{}, error) {
 	// Get the flag
@@ -181,15 +242,25 @@ func GetFlagValue(flags *pflag.FlagSet, name string) (interface{}, error) {
 	return flag.Value.String(), nil
 }
 
-// GetStringFlagValue gets a string flag value from a command
+// GetStringFlagValue gets the value of a flag of any type from a command as a string.
+// The values of array flags are joined with commas.
 func GetStringFlagValue(flags *pflag.FlagSet, name string) (string, error) {
+	// Get the flag
+	flag := flags.Lookup(name)
+	if flag == nil {
+		return "", fmt.Errorf("flag not found: %s", name)
+	}
+
 	// Get the flag value
-	value, err := flags.GetString(name)
-	if err != nil {
-		return "", fmt.Errorf("failed to get string flag %s: %w", name, err)
+	if flag.Value.Type() == "stringArray" {
+		values, err := flags.GetStringArray(name)
+		if err != nil {
+			return "", fmt.Errorf("failed to get string array flag %s: %w", name, err)
+		}
+		return strings.Join(values, ","), nil
 	}
 
-	return value, nil
+	return flag.Value.String(), nil
 }
 
 // GetBoolFlagValue gets a boolean flag value from a command
@@ -225,29 +296,42 @@ func GetStringArrayFlagValue(flags *pflag.FlagSet, name string) ([]string, error
 	return value, nil
 }
 
-// ParseDataFlag parses the data flag value
-func ParseDataFlag(value string) (interface{}, error) {
-	// Check if the value is a file path
-	if strings.HasPrefix(value, "@") {
-		// Read the file
-		filePath := value[1:]
-		data, err := os.ReadFile(filePath)
+// ReadDataFlag returns the content of the data flag: the value, or the content of the
+// file it names when prefixed with @ (@- for stdin)
+func ReadDataFlag(value string) ([]byte, error) {
+	filePath, ok := strings.CutPrefix(value, "@")
+	if !ok {
+		return []byte(value), nil
+	}
+	if filePath == "-" {
+		data, err := http.ReadStdin()
 		if err != nil {
-			return nil, fmt.Errorf("failed to read data file: %w", err)
+			return nil, fmt.Errorf("failed to read data from stdin: %w", err)
 		}
+		return data, nil
+	}
 
-		// Parse the data as JSON
-		var jsonData interface{}
-		if err := json.Unmarshal(data, &jsonData); err != nil {
-			return nil, fmt.Errorf("failed to parse data file as JSON: %w", err)
-		}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data file: %w", err)
+	}
+	return data, nil
+}
 
-		return jsonData, nil
+// ParseDataFlag parses the data flag value as JSON, reading it from a file when
+// prefixed with @
+func ParseDataFlag(value string) (interface{}, error) {
+	data, err := ReadDataFlag(value)
+	if err != nil {
+		return nil, err
 	}
 
-	// Parse the value as JSON
+	// Parse the data as JSON
 	var jsonData interface{}
-	if err := json.Unmarshal([]byte(value), &jsonData); err != nil {
+	if err := json.Unmarshal(data, &jsonData); err != nil {
+		if strings.HasPrefix(value, "@") {
+			return nil, fmt.Errorf("failed to parse data file as JSON: %w", err)
+		}
 		return nil, fmt.Errorf("failed to parse data as JSON: %w", err)
 	}
 
@@ -294,29 +378,16 @@ func ParseQueryFlags(values []string) (map[string]string, error) {
 	return params, nil
 }
 
-// ParseFormFlags parses the form flag values
-func ParseFormFlags(values []string) (map[string]string, map[string]string, error) {
-	// Create maps to store the form data and files
-	formData := make(map[string]string)
-	formFiles := make(map[string]string)
-
-	// Parse the form values
+// ParseServerVarFlags parses the server variable flag values
+func ParseServerVarFlags(values []string) (map[string]string, error) {
+	vars := make(map[string]string)
 	for _, value := range values {
-		// Split the value into key and value
 		parts := strings.SplitN(value, "=", 2)
 		if len(parts) != 2 {
-			return nil, nil, fmt.Errorf("invalid form data format: %s", value)
-		}
-
-		// Check if the value is a file path
-		if strings.HasPrefix(parts[1], "@") {
-			// Add the form file
-			formFiles[parts[0]] = parts[1]
-		} else {
-			// Add the form data
-			formData[parts[0]] = parts[1]
+			return nil, fmt.Errorf("invalid server variable format: %s", value)
 		}
+		vars[strings.TrimSpace(parts[0])] = parts[1]
 	}
 
-	return formData, formFiles, nil
+	return vars, nil
 }
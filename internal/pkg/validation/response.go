@@ -0,0 +1,117 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fynxlabs/ontap/internal/pkg/openapi"
+)
+
+// ResponseErrors is a list of response validation failures
+type ResponseErrors Errors
+
+// Error returns the error message
+func (e ResponseErrors) Error() string {
+	return Errors(e).summary("response validation failed")
+}
+
+// ValidateResponse validates a response against the responses declared for an operation.
+// The status code is matched exactly, then by range (e.g. 2XX), then against default.
+func ValidateResponse(responses map[string]openapi.Response, statusCode int, contentType string, body []byte) ResponseErrors {
+	response, ok := matchResponse(responses, statusCode)
+	if !ok {
+		return ResponseErrors{{Pointer: "/status", Message: fmt.Sprintf("status %d is not declared for this operation", statusCode)}}
+	}
+
+	// Responses without content or without a body have nothing else to check
+	if len(response.Content) == 0 || len(body) == 0 {
+		return nil
+	}
+
+	mediaTypeName, mediaType := matchMediaType(response.Content, contentType)
+	if mediaType == nil {
+		return ResponseErrors{{
+			Pointer: "/content-type",
+			Message: fmt.Sprintf("content type %q is not declared (expected %s)", contentType, strings.Join(sortedKeys(response.Content), ", ")),
+		}}
+	}
+
+	// Only JSON bodies are checked against the schema
+	if mediaType.Schema == nil || !isJSONMediaType(mediaTypeName) {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return ResponseErrors{{Pointer: "/body", Message: fmt.Sprintf("invalid JSON: %v", err)}}
+	}
+
+	return ResponseErrors(ValidateValue("/body", value, mediaType.Schema))
+}
+
+// matchResponse finds the declared response for a status code
+func matchResponse(responses map[string]openapi.Response, statusCode int) (openapi.Response, bool) {
+	code := strconv.Itoa(statusCode)
+	if response, ok := responses[code]; ok {
+		return response, true
+	}
+
+	// Check the status code range
+	for key, response := range responses {
+		if strings.EqualFold(key, code[:1]+"XX") {
+			return response, true
+		}
+	}
+
+	response, ok := responses["default"]
+	return response, ok
+}
+
+// matchMediaType finds the declared media type for a response content type,
+// falling back to wildcard media types such as application/* and */*
+func matchMediaType(content map[string]*openapi.MediaType, contentType string) (string, *openapi.MediaType) {
+	name, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		name = strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	candidates := []string{name}
+	if slash := strings.Index(name, "/"); slash > 0 {
+		candidates = append(candidates, name[:slash]+"/*")
+	}
+	candidates = append(candidates, "*/*")
+
+	for _, candidate := range candidates {
+		for key, mediaType := range content {
+			if strings.EqualFold(key, candidate) && mediaType != nil {
+				// Wildcards take the media type of the actual response
+				if strings.HasSuffix(candidate, "*") {
+					return name, mediaType
+				}
+				return key, mediaType
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// isJSONMediaType checks if a media type is JSON or uses the +json suffix
+func isJSONMediaType(name string) bool {
+	name = strings.ToLower(name)
+	return name == "application/json" || strings.HasSuffix(name, "+json")
+}
+
+// sortedKeys returns the sorted media types of a content map
+func sortedKeys(content map[string]*openapi.MediaType) []string {
+	keys := make([]string, 0, len(content))
+	for key := range content {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
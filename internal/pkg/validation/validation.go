@@ -0,0 +1,384 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/log"
+	"github.com/fynxlabs/ontap/internal/pkg/openapi"
+)
+
+// pathTemplatePattern matches the templates of the parameters in a path
+var pathTemplatePattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// Error represents a validation failure at a location identified by a JSON pointer
+type Error struct {
+	// Pointer is the JSON pointer to the invalid value (e.g. /body/address/city)
+	Pointer string
+
+	// Message describes the failure
+	Message string
+}
+
+// Error returns the error message
+func (e Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pointer, e.Message)
+}
+
+// Errors is a list of validation failures
+type Errors []Error
+
+// Error returns the error message
+func (e Errors) Error() string {
+	return e.summary("request validation failed")
+}
+
+// summary lists the errors below a title
+func (e Errors) summary(title string) string {
+	var sb strings.Builder
+	sb.WriteString(title + ":")
+	for _, err := range e {
+		sb.WriteString("\n  ")
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// Pointer builds a JSON pointer from its reference tokens
+func Pointer(tokens ...string) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString("/")
+		token = strings.ReplaceAll(token, "~", "~0")
+		sb.WriteString(strings.ReplaceAll(token, "/", "~1"))
+	}
+	return sb.String()
+}
+
+// ValidateParameter validates the raw values of a parameter against its schema
+func ValidateParameter(param openapi.Parameter, values []string) Errors {
+	pointer := Pointer(param.In, param.Name)
+
+	// Check that required parameters are present
+	if len(values) == 0 {
+		if param.Required {
+			return Errors{{Pointer: pointer, Message: "missing required parameter"}}
+		}
+		return nil
+	}
+
+	schema := param.Schema
+	if schema == nil {
+		return nil
+	}
+
+	// Array parameters are validated item by item
+	if hasType(schema, "array") {
+		items := make([]interface{}, 0, len(values))
+		var errs Errors
+		for i, raw := range values {
+			value, err := parseValue(raw, schema.Items)
+			if err != nil {
+				errs = append(errs, Error{Pointer: pointer + Pointer(strconv.Itoa(i)), Message: err.Error()})
+				continue
+			}
+			items = append(items, value)
+		}
+		if len(errs) > 0 {
+			return errs
+		}
+		return ValidateValue(pointer, items, schema)
+	}
+
+	value, err := parseValue(values[0], schema)
+	if err != nil {
+		return Errors{{Pointer: pointer, Message: err.Error()}}
+	}
+
+	return ValidateValue(pointer, value, schema)
+}
+
+// ValidatePath checks that each template of a path has a value, whether or not its
+// parameter is declared, so that no request is sent with an unexpanded template
+func ValidatePath(path string, values map[string]string) Errors {
+	var errs Errors
+	for _, match := range pathTemplatePattern.FindAllStringSubmatch(path, -1) {
+		if _, ok := values[match[1]]; !ok {
+			errs = append(errs, Error{Pointer: Pointer("path", match[1]), Message: fmt.Sprintf("missing value for %s in the path", match[0])})
+		}
+	}
+	return errs
+}
+
+// parseValue converts a raw parameter value to the type of a schema
+func parseValue(raw string, schema *openapi.Schema) (interface{}, error) {
+	if schema == nil {
+		return raw, nil
+	}
+
+	switch {
+	case hasType(schema, "integer"):
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected integer, got %q", raw)
+		}
+		return float64(v), nil
+	case hasType(schema, "number"):
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected number, got %q", raw)
+		}
+		return v, nil
+	case hasType(schema, "boolean"):
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected boolean, got %q", raw)
+		}
+		return v, nil
+	case hasType(schema, "object"):
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return nil, fmt.Errorf("expected JSON object, got %q", raw)
+		}
+		return v, nil
+	default:
+		return raw, nil
+	}
+}
+
+// ValidateValue validates a decoded JSON value against a schema
+func ValidateValue(pointer string, value interface{}, schema *openapi.Schema) Errors {
+	if schema == nil {
+		return nil
+	}
+
+	value = normalize(value)
+
+	// Check null values
+	if value == nil {
+		if schema.Type == "" || schema.Nullable || hasType(schema, "null") {
+			return nil
+		}
+		return Errors{{Pointer: pointer, Message: fmt.Sprintf("expected %s, got null", typeList(schema))}}
+	}
+
+	// Check the type before any of the constraints
+	if schema.Type != "" && !matchesType(value, schema) {
+		return Errors{{Pointer: pointer, Message: fmt.Sprintf("expected %s, got %s", typeList(schema), typeName(value))}}
+	}
+
+	var errs Errors
+
+	// Check the enum
+	if len(schema.Enum) > 0 && !inEnum(value, schema.Enum) {
+		errs = append(errs, Error{Pointer: pointer, Message: fmt.Sprintf("value %v is not one of %v", value, schema.Enum)})
+	}
+
+	switch v := value.(type) {
+	case string:
+		errs = append(errs, validateString(pointer, v, schema)...)
+	case float64:
+		errs = append(errs, validateNumber(pointer, v, schema)...)
+	case []interface{}:
+		errs = append(errs, validateArray(pointer, v, schema)...)
+	case map[string]interface{}:
+		errs = append(errs, validateObject(pointer, v, schema)...)
+	}
+
+	return errs
+}
+
+// validateString checks the string constraints of a schema
+func validateString(pointer, value string, schema *openapi.Schema) Errors {
+	var errs Errors
+	length := uint64(utf8.RuneCountInString(value))
+
+	if schema.MinLength != nil && length < *schema.MinLength {
+		errs = append(errs, Error{Pointer: pointer, Message: fmt.Sprintf("length %d is less than minLength %d", length, *schema.MinLength)})
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		errs = append(errs, Error{Pointer: pointer, Message: fmt.Sprintf("length %d is greater than maxLength %d", length, *schema.MaxLength)})
+	}
+
+	if schema.Pattern != "" {
+		re, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			log.Debug("Skipping unsupported pattern", "pattern", schema.Pattern, "error", err)
+		} else if !re.MatchString(value) {
+			errs = append(errs, Error{Pointer: pointer, Message: fmt.Sprintf("value %q does not match pattern %s", value, schema.Pattern)})
+		}
+	}
+
+	return errs
+}
+
+// validateNumber checks the numeric constraints of a schema
+func validateNumber(pointer string, value float64, schema *openapi.Schema) Errors {
+	var errs Errors
+
+	if schema.Minimum != nil && value < *schema.Minimum {
+		errs = append(errs, Error{Pointer: pointer, Message: fmt.Sprintf("value %v is less than minimum %v", value, *schema.Minimum)})
+	}
+	if schema.Maximum != nil && value > *schema.Maximum {
+		errs = append(errs, Error{Pointer: pointer, Message: fmt.Sprintf("value %v is greater than maximum %v", value, *schema.Maximum)})
+	}
+
+	return errs
+}
+
+// validateArray checks the array constraints and items of a schema
+func validateArray(pointer string, value []interface{}, schema *openapi.Schema) Errors {
+	var errs Errors
+	count := int64(len(value))
+
+	if schema.MinItems != nil && count < *schema.MinItems {
+		errs = append(errs, Error{Pointer: pointer, Message: fmt.Sprintf("%d items is fewer than minItems %d", count, *schema.MinItems)})
+	}
+	if schema.MaxItems != nil && count > *schema.MaxItems {
+		errs = append(errs, Error{Pointer: pointer, Message: fmt.Sprintf("%d items is more than maxItems %d", count, *schema.MaxItems)})
+	}
+
+	for i, item := range value {
+		errs = append(errs, ValidateValue(pointer+Pointer(strconv.Itoa(i)), item, schema.Items)...)
+	}
+
+	return errs
+}
+
+// validateObject checks the required properties and property values of a schema
+func validateObject(pointer string, value map[string]interface{}, schema *openapi.Schema) Errors {
+	var errs Errors
+
+	for _, name := range schema.Required {
+		if _, ok := value[name]; !ok {
+			errs = append(errs, Error{Pointer: pointer + Pointer(name), Message: "missing required property"})
+		}
+	}
+
+	// Validate the properties in a stable order
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if propSchema, ok := schema.Properties[name]; ok {
+			errs = append(errs, ValidateValue(pointer+Pointer(name), value[name], propSchema)...)
+		}
+	}
+
+	return errs
+}
+
+// schemaTypes returns the types allowed by a schema
+func schemaTypes(schema *openapi.Schema) []string {
+	if schema.Type == "" {
+		return nil
+	}
+	return strings.Split(schema.Type, ",")
+}
+
+// hasType checks if a schema allows a type
+func hasType(schema *openapi.Schema, t string) bool {
+	for _, st := range schemaTypes(schema) {
+		if st == t {
+			return true
+		}
+	}
+	return false
+}
+
+// typeList returns the allowed types of a schema for error messages
+func typeList(schema *openapi.Schema) string {
+	return strings.Join(schemaTypes(schema), " or ")
+}
+
+// matchesType checks if a normalized value matches one of the schema types
+func matchesType(value interface{}, schema *openapi.Schema) bool {
+	for _, t := range schemaTypes(schema) {
+		switch t {
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "integer":
+			if v, ok := value.(float64); ok && v == math.Trunc(v) {
+				return true
+			}
+		case "number":
+			if _, ok := value.(float64); ok {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		case "object":
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeName returns the JSON type name of a normalized value
+func typeName(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// inEnum checks if a normalized value is one of the enum values
+func inEnum(value interface{}, enum []interface{}) bool {
+	for _, e := range enum {
+		if reflect.DeepEqual(value, normalize(e)) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize converts numeric values to float64 so they compare like decoded JSON
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	default:
+		return value
+	}
+}
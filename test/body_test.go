@@ -0,0 +1,129 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fynxlabs/ontap/internal/pkg/openapi"
+	"github.com/fynxlabs/ontap/internal/pkg/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func TestBodyFlags(t *testing.T) {
+	schema := &openapi.Schema{
+		Type:     "object",
+		Required: []string{"name", "address"},
+		Properties: map[string]*openapi.Schema{
+			"name":        {Type: "string"},
+			"age":         {Type: "integer"},
+			"score":       {Type: "number"},
+			"active":      {Type: "boolean"},
+			"status":      {Type: "string", Enum: []interface{}{"active", "inactive"}},
+			"tags":        {Type: "array", Items: &openapi.Schema{Type: "integer"}},
+			"meta":        {Type: "object"},
+			"@odata.type": {Type: "string"},
+			"output":      {Type: "string"},
+			"address": {
+				Type:     "object",
+				Required: []string{"city"},
+				Properties: map[string]*openapi.Schema{
+					"city": {Type: "string"},
+					"zip":  {Type: "string"},
+				},
+			},
+		},
+	}
+
+	// Nested objects become dotted properties, required only when their parents are
+	properties := utils.BodyProperties(schema)
+	byPath := map[string]utils.BodyProperty{}
+	for _, prop := range properties {
+		byPath[prop.Path] = prop
+	}
+	if city := byPath["address.city"]; !city.Required || !reflect.DeepEqual(city.Segments, []string{"address", "city"}) {
+		t.Errorf("Unexpected nested property: %+v", city)
+	}
+	if byPath["age"].Required || !byPath["name"].Required {
+		t.Errorf("Unexpected required properties: %+v", properties)
+	}
+	if odata := byPath["@odata.type"]; !reflect.DeepEqual(odata.Segments, []string{"@odata.type"}) {
+		t.Errorf("Expected a dotted property name to be a single segment, got %+v", odata)
+	}
+
+	// Properties that clash with local or reserved flags are skipped
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String("age", "", "")
+	reserved := pflag.NewFlagSet("reserved", pflag.ContinueOnError)
+	reserved.String("output", "", "")
+	added := utils.AddBodyFlags(cmd, properties, reserved)
+	if len(added) != len(properties)-2 {
+		t.Fatalf("Expected the clashing flags to be skipped, got %d of %d", len(added), len(properties))
+	}
+	for _, prop := range added {
+		if prop.Path == "age" || prop.Path == "output" {
+			t.Errorf("Expected --%s to be skipped", prop.Path)
+		}
+	}
+
+	// Flag values are typed and merged into the base body
+	err := cmd.ParseFlags([]string{
+		"--name=Ada", "--score=1.5", "--active", "--tags=1", "--tags=2",
+		"--meta={\"a\":1}", "--address.city=Paris", "--@odata.type=#User",
+	})
+	if err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	body, err := utils.BuildBody(cmd.Flags(), added, map[string]interface{}{
+		"age":     float64(36),
+		"address": map[string]interface{}{"zip": "75001"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build body: %v", err)
+	}
+	expected := map[string]interface{}{
+		"name":        "Ada",
+		"age":         float64(36),
+		"score":       1.5,
+		"active":      true,
+		"tags":        []interface{}{int64(1), int64(2)},
+		"meta":        map[string]interface{}{"a": float64(1)},
+		"address":     map[string]interface{}{"city": "Paris", "zip": "75001"},
+		"@odata.type": "#User",
+	}
+	if !reflect.DeepEqual(body, expected) {
+		t.Errorf("Unexpected body:\n got %v\nwant %v", body, expected)
+	}
+
+	// Values outside the enum are left to request validation
+	cmd = &cobra.Command{Use: "test"}
+	added = utils.AddBodyFlags(cmd, properties, nil)
+	if err := cmd.ParseFlags([]string{"--status=deleted"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if body, err := utils.BuildBody(cmd.Flags(), added, nil); err != nil || body.(map[string]interface{})["status"] != "deleted" {
+		t.Errorf("Expected the value outside the enum to be set, got %v (%v)", body, err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		base interface{}
+	}{
+		{name: "integer item", args: []string{"--tags=x"}},
+		{name: "object", args: []string{"--meta=[1"}},
+		{name: "non-object parent", args: []string{"--address.city=Paris"}, base: map[string]interface{}{"address": "here"}},
+		{name: "non-object body", args: []string{"--name=Ada"}, base: []interface{}{}},
+	}
+
+	for _, tt := range tests {
+		cmd := &cobra.Command{Use: "test"}
+		added := utils.AddBodyFlags(cmd, properties, nil)
+		if err := cmd.ParseFlags(tt.args); err != nil {
+			t.Fatalf("%s: failed to parse flags: %v", tt.name, err)
+		}
+		if _, err := utils.BuildBody(cmd.Flags(), added, tt.base); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
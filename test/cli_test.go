@@ -0,0 +1,112 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fynxlabs/ontap/cmd"
+	"github.com/fynxlabs/ontap/internal/pkg/exitcode"
+)
+
+// cliEnv marks a re-executed test binary that runs the CLI instead of the tests
+const cliEnv = "ONTAP_TEST_CLI"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(cliEnv) == "1" {
+		cmd.Execute()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runCLI runs the CLI with a config file in a fresh home directory, returning its
+// output and exit code
+func runCLI(t *testing.T, home, configFile string, args ...string) (string, string, int) {
+	t.Helper()
+
+	command := exec.Command(os.Args[0], append([]string{"-c", configFile}, args...)...)
+	command.Env = append(os.Environ(), cliEnv+"=1", "HOME="+home, "XDG_CONFIG_HOME="+filepath.Join(home, ".config"), "ONTAP_ENV=")
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+
+	err := command.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatalf("Failed to run the CLI: %v", err)
+	}
+	return stdout.String(), stderr.String(), command.ProcessState.ExitCode()
+}
+
+func TestLazyAPICommands(t *testing.T) {
+	// The spec of the broken API fails to load, and counts how often it is fetched
+	var fetches atomic.Int32
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		fetches.Add(1)
+		w.WriteHeader(nethttp.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	spec, err := filepath.Abs("./fixtures/openapi.yaml")
+	if err != nil {
+		t.Fatalf("Failed to resolve spec path: %v", err)
+	}
+	home := t.TempDir()
+	configFile := filepath.Join(home, "config.yaml")
+	configData := fmt.Sprintf(`apis:
+  example:
+    apispec: %s
+    url: http://127.0.0.1:1
+  broken:
+    apispec: %s/openapi.yaml
+`, spec, server.URL)
+	if err := os.WriteFile(configFile, []byte(configData), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	// Only the spec of the invoked API is loaded
+	tests := []struct {
+		name     string
+		args     []string
+		contains string
+	}{
+		{name: "version", args: []string{"version"}},
+		{name: "operation", args: []string{"example", "users", "listUsers", "--dry-run"}, contains: "Dry run completed"},
+		{name: "help", args: []string{"help", "example"}, contains: "users"},
+		{name: "completion of the API", args: []string{"__complete", "example", ""}, contains: "users"},
+		{name: "completion of a group", args: []string{"__complete", "example", "users", ""}, contains: "listUsers"},
+	}
+	for _, tt := range tests {
+		stdout, stderr, code := runCLI(t, home, configFile, tt.args...)
+		if code != 0 {
+			t.Errorf("%s: expected exit code 0, got %d: %s", tt.name, code, stderr)
+		}
+		if !strings.Contains(stdout, tt.contains) {
+			t.Errorf("%s: expected output containing %q, got %q", tt.name, tt.contains, stdout)
+		}
+		if strings.Contains(stderr, "broken") {
+			t.Errorf("%s: expected the broken API to be left alone, got %s", tt.name, stderr)
+		}
+	}
+	if n := fetches.Load(); n != 0 {
+		t.Errorf("Expected the broken spec not to be fetched, got %d requests", n)
+	}
+
+	// The load error surfaces when the broken API is run
+	_, stderr, code := runCLI(t, home, configFile, "broken")
+	if code != exitcode.Config {
+		t.Errorf("Expected exit code %d, got %d: %s", exitcode.Config, code, stderr)
+	}
+	if fetches.Load() == 0 {
+		t.Errorf("Expected the broken spec to be fetched")
+	}
+}
@@ -0,0 +1,564 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fynxlabs/ontap/internal/pkg/http"
+	"github.com/fynxlabs/ontap/internal/pkg/utils"
+)
+
+func TestClientRetries(t *testing.T) {
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		mu.Lock()
+		attempts[r.Method+r.URL.Path]++
+		n := attempts[r.Method+r.URL.Path]
+		mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/unavailable" && n < 3:
+			w.WriteHeader(nethttp.StatusServiceUnavailable)
+		case r.URL.Path == "/ratelimited" && n < 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(nethttp.StatusTooManyRequests)
+		case r.URL.Path == "/overloaded":
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(nethttp.StatusServiceUnavailable)
+		case r.URL.Path == "/notimplemented":
+			w.WriteHeader(nethttp.StatusNotImplemented)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		method   string
+		path     string
+		headers  map[string]string
+		status   int
+		attempts int
+	}{
+		{method: "GET", path: "/unavailable", status: 200, attempts: 3},
+		{method: "GET", path: "/ratelimited", status: 200, attempts: 2},
+		{method: "GET", path: "/notimplemented", status: 501, attempts: 1},
+		{method: "GET", path: "/overloaded", status: 503, attempts: 1},
+		{method: "POST", path: "/unavailable", status: 503, attempts: 1},
+		{method: "PATCH", path: "/unavailable", headers: map[string]string{"Idempotency-Key": "abc"}, status: 200, attempts: 3},
+	}
+
+	for _, tt := range tests {
+		client := http.NewClient(server.URL, "")
+		client.Retry = http.RetryPolicy{MaxRetries: 3, MinWait: time.Millisecond, MaxWait: 10 * time.Millisecond}
+
+		resp, err := client.Execute(&http.Request{Method: tt.method, Path: tt.path, Headers: tt.headers})
+		if err != nil {
+			t.Fatalf("%s %s: request failed: %v", tt.method, tt.path, err)
+		}
+		if resp.StatusCode != tt.status || attempts[tt.method+tt.path] != tt.attempts {
+			t.Errorf("%s %s: expected status %d after %d attempts, got %d after %d", tt.method, tt.path, tt.status, tt.attempts, resp.StatusCode, attempts[tt.method+tt.path])
+		}
+	}
+
+	// Requests are not retried by default
+	client := http.NewClient(server.URL, "")
+	resp, err := client.Execute(&http.Request{Method: "DELETE", Path: "/unavailable"})
+	if err != nil || resp.StatusCode != 503 {
+		t.Errorf("Expected a single attempt with status 503, got %v (%v)", resp, err)
+	}
+
+	key, err := http.NewIdempotencyKey()
+	if err != nil || len(key) != 36 {
+		t.Errorf("Expected a UUID idempotency key, got %q (%v)", key, err)
+	}
+}
+
+func TestClientTransport(t *testing.T) {
+	dir := t.TempDir()
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	handler := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Write([]byte(r.Host + r.URL.Path))
+	})
+
+	// Trust the test server's certificate with a CA file
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+	caFile := writePEM("ca.pem", "CERTIFICATE", server.Certificate().Raw)
+
+	client := http.NewClient(server.URL, "")
+	if err := client.ConfigureTransport(http.TransportConfig{}); err != nil {
+		t.Fatalf("Failed to configure transport: %v", err)
+	}
+	if _, err := client.Execute(&http.Request{Method: "GET", Path: "/"}); err == nil {
+		t.Errorf("Expected the server certificate to be rejected without the CA file")
+	}
+
+	client = http.NewClient(server.URL, "")
+	if err := client.ConfigureTransport(http.TransportConfig{TLS: http.TLSConfig{CAFile: caFile, MinVersion: "1.2"}}); err != nil {
+		t.Fatalf("Failed to configure transport: %v", err)
+	}
+	if resp, err := client.Execute(&http.Request{Method: "GET", Path: "/"}); err != nil || resp.StatusCode != 200 {
+		t.Errorf("Expected the server certificate to be trusted with the CA file, got %v (%v)", resp, err)
+	}
+
+	// Send a client certificate to a server that requires one
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	certFile := writePEM("client.pem", "CERTIFICATE", der)
+	keyFile := writePEM("client-key.pem", "EC PRIVATE KEY", keyDER)
+	clientCert, _ := x509.ParseCertificate(der)
+
+	mtlsServer := httptest.NewUnstartedServer(handler)
+	mtlsServer.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: x509.NewCertPool()}
+	mtlsServer.TLS.ClientCAs.AddCert(clientCert)
+	mtlsServer.StartTLS()
+	defer mtlsServer.Close()
+	mtlsCAFile := writePEM("mtls-ca.pem", "CERTIFICATE", mtlsServer.Certificate().Raw)
+
+	client = http.NewClient(mtlsServer.URL, "")
+	if err := client.ConfigureTransport(http.TransportConfig{TLS: http.TLSConfig{CAFile: mtlsCAFile, CertFile: certFile, KeyFile: keyFile}}); err != nil {
+		t.Fatalf("Failed to configure transport: %v", err)
+	}
+	if resp, err := client.Execute(&http.Request{Method: "GET", Path: "/"}); err != nil || resp.StatusCode != 200 {
+		t.Errorf("Expected the client certificate to be accepted, got %v (%v)", resp, err)
+	}
+
+	// Reach a server listening on a Unix domain socket
+	socket := filepath.Join(dir, "api.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to listen on socket: %v", err)
+	}
+	unixServer := httptest.NewUnstartedServer(handler)
+	unixServer.Listener = listener
+	unixServer.Start()
+	defer unixServer.Close()
+
+	client = http.NewClient("unix://"+socket+":/v1", "")
+	if err := client.ConfigureTransport(http.TransportConfig{}); err != nil {
+		t.Fatalf("Failed to configure transport: %v", err)
+	}
+	resp, err := client.Execute(&http.Request{Method: "GET", Path: "/users"})
+	if err != nil || string(resp.Body) != "unix/v1/users" {
+		t.Errorf("Expected the request to reach the socket, got %v (%v)", resp, err)
+	}
+
+	// Send requests through a proxy
+	proxy := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Write([]byte("proxied " + r.URL.String()))
+	}))
+	defer proxy.Close()
+
+	client = http.NewClient("http://api.internal", "")
+	if err := client.ConfigureTransport(http.TransportConfig{Proxy: proxy.URL}); err != nil {
+		t.Fatalf("Failed to configure transport: %v", err)
+	}
+	resp, err = client.Execute(&http.Request{Method: "GET", Path: "/users"})
+	if err != nil || string(resp.Body) != "proxied http://api.internal/users" {
+		t.Errorf("Expected the request to go through the proxy, got %v (%v)", resp, err)
+	}
+
+	// Invalid settings are errors
+	for _, config := range []http.TransportConfig{
+		{TLS: http.TLSConfig{MinVersion: "2.0"}},
+		{TLS: http.TLSConfig{CertFile: certFile}},
+		{TLS: http.TLSConfig{CAFile: filepath.Join(dir, "missing.pem")}},
+		{Proxy: "not a url"},
+	} {
+		if err := http.NewClient(server.URL, "").ConfigureTransport(config); err == nil {
+			t.Errorf("Expected an error for %+v", config)
+		}
+	}
+}
+
+func TestClientStream(t *testing.T) {
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		flusher := w.(nethttp.Flusher)
+		switch r.URL.Path {
+		case "/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte(": connected\n\nid: 1\nevent: log\ndata: {\"n\": 1}\n\n"))
+			flusher.Flush()
+			// Outlast the timeout of the client, which only applies to the headers
+			time.Sleep(150 * time.Millisecond)
+			w.Write([]byte("data: first\r\ndata: second\r\n\ndata: incomplete\n"))
+		case "/logs":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Write([]byte("{\"n\": 1}\n\nnot json\n"))
+		case "/export":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte("binary"))
+		}
+	}))
+	defer server.Close()
+
+	client := http.NewClient(server.URL, "")
+	client.Timeout = 50 * time.Millisecond
+	client.HTTPClient.Timeout = client.Timeout
+
+	// Read Server-Sent Events, discarding the incomplete last one
+	resp, err := client.Stream(&http.Request{Method: "GET", Path: "/events"})
+	if err != nil {
+		t.Fatalf("Failed to stream events: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Type() != http.StreamEvents {
+		t.Errorf("Expected an event stream, got %q", resp.Type())
+	}
+
+	var events []http.Event
+	eventReader := http.NewEventReader(resp.Body)
+	for {
+		event, err := eventReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read events: %v", err)
+		}
+		events = append(events, *event)
+	}
+	expected := []http.Event{
+		{ID: "1", Type: "log", Data: `{"n": 1}`},
+		{Type: "message", Data: "first\nsecond"},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected events %+v, got %+v", expected, events)
+	}
+
+	// Read newline-delimited JSON records
+	resp, err = client.Stream(&http.Request{Method: "GET", Path: "/logs"})
+	if err != nil {
+		t.Fatalf("Failed to stream records: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Type() != http.StreamRecords {
+		t.Errorf("Expected a record stream, got %q", resp.Type())
+	}
+
+	var records []interface{}
+	recordReader := http.NewRecordReader(resp.Body)
+	for {
+		record, err := recordReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read records: %v", err)
+		}
+		records = append(records, record)
+	}
+	if !reflect.DeepEqual(records, []interface{}{map[string]interface{}{"n": 1.0}, "not json"}) {
+		t.Errorf("Unexpected records %v", records)
+	}
+
+	// Read binary bodies as they arrive
+	resp, err = client.Stream(&http.Request{Method: "GET", Path: "/export"})
+	if err != nil {
+		t.Fatalf("Failed to stream download: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || resp.Type() != http.StreamBinary || string(body) != "binary" {
+		t.Errorf("Expected a binary body, got %q (%q)", resp.Type(), body)
+	}
+
+	for contentType, expected := range map[string]http.StreamType{
+		"application/json; charset=utf-8": http.StreamNone,
+		"text/csv":                        http.StreamNone,
+		"application/problem+json":        http.StreamNone,
+		"application/jsonl":               http.StreamRecords,
+		"application/pdf":                 http.StreamBinary,
+		"":                                http.StreamNone,
+	} {
+		if got := http.ContentStreamType(contentType); got != expected {
+			t.Errorf("Expected %q to be read as %q, got %q", contentType, expected, got)
+		}
+	}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	// Choose the request content type and the Accept header from the declared types
+	if got := http.RequestContentType([]string{"text/plain", "application/xml", "application/x-www-form-urlencoded"}); got != "application/x-www-form-urlencoded" {
+		t.Errorf("Expected a URL-encoded form to be preferred, got %q", got)
+	}
+	if got := http.RequestContentType([]string{"*/*"}); got != "" {
+		t.Errorf("Expected no content type for a wildcard, got %q", got)
+	}
+	if got := http.AcceptHeader([]string{"text/plain", "application/xml", "application/json"}); got != "application/json, application/xml;q=0.9, text/plain;q=0.8" {
+		t.Errorf("Unexpected Accept header %q", got)
+	}
+
+	// Encode request bodies
+	encodings := []struct {
+		body        interface{}
+		contentType string
+		expected    string
+	}{
+		{map[string]interface{}{"a": 1.0}, "", `{"a":1}`},
+		{map[string]interface{}{"name": "x y", "tags": []interface{}{"a", "b"}}, "application/x-www-form-urlencoded", "name=x+y&tags=a&tags=b"},
+		{map[string]interface{}{"Pet": map[string]interface{}{"@id": "1", "name": "Rex", "tags": []interface{}{"a", "b"}}}, "application/xml", `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<Pet id="1"><name>Rex</name><tags>a</tags><tags>b</tags></Pet>`},
+		{map[string]interface{}{"a": "<&>", "b": nil}, "text/xml", `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<root><a>&lt;&amp;&gt;</a><b></b></root>`},
+		{"hello", "text/plain", "hello"},
+		{[]byte{0, 1}, "application/octet-stream", "\x00\x01"},
+	}
+	for _, tt := range encodings {
+		reader, err := http.EncodeBody(tt.body, tt.contentType)
+		if err != nil {
+			t.Errorf("Failed to encode %v as %q: %v", tt.body, tt.contentType, err)
+			continue
+		}
+		data, _ := io.ReadAll(reader)
+		if string(data) != tt.expected {
+			t.Errorf("Expected %v encoded as %q to be %q, got %q", tt.body, tt.contentType, tt.expected, data)
+		}
+	}
+	if _, err := http.EncodeBody(map[string]interface{}{"a": 1.0}, "application/octet-stream"); err == nil {
+		t.Errorf("Expected an error encoding an object as a binary body")
+	}
+
+	// Decode response bodies into structured data
+	decodings := []struct {
+		body        string
+		contentType string
+		expected    interface{}
+	}{
+		{`{"a": 1}`, "application/json", map[string]interface{}{"a": 1.0}},
+		{"a: 1\nb: [x]\n", "application/yaml", map[string]interface{}{"a": 1.0, "b": []interface{}{"x"}}},
+		{`<users count="2"><user>Ann</user><user>Bob</user><note lang="en">hi</note></users>`, "application/xml; charset=utf-8", map[string]interface{}{
+			"users": map[string]interface{}{
+				"@count": "2",
+				"user":   []interface{}{"Ann", "Bob"},
+				"note":   map[string]interface{}{"@lang": "en", "#text": "hi"},
+			},
+		}},
+		{"name,age\nAnn,31\nBob\n", "text/csv", []interface{}{
+			map[string]interface{}{"name": "Ann", "age": "31"},
+			map[string]interface{}{"name": "Bob", "age": nil},
+		}},
+		{"<not xml", "application/xml", "<not xml"},
+		{"plain text", "text/plain", "plain text"},
+		{"", "application/json", nil},
+	}
+	for _, tt := range decodings {
+		if got := http.DecodeBody([]byte(tt.body), tt.contentType); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Expected %q (%s) to decode to %v, got %v", tt.body, tt.contentType, tt.expected, got)
+		}
+	}
+}
+
+func TestMultipart(t *testing.T) {
+	// Describe the parts each field of the form was sent as
+	type part struct {
+		Filename    string
+		ContentType string
+		Header      string
+		Value       string
+	}
+	var received map[string][]part
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		received = map[string][]part{}
+		reader, err := r.MultipartReader()
+		if err != nil {
+			t.Errorf("Expected a multipart body: %v", err)
+			return
+		}
+		for {
+			p, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			value, _ := io.ReadAll(p)
+			received[p.FormName()] = append(received[p.FormName()], part{p.FileName(), p.Header.Get("Content-Type"), p.Header.Get("X-Source"), string(value)})
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	avatar := filepath.Join(dir, "me.png")
+	if err := os.WriteFile(avatar, []byte("png"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// Build parts from the data, as described by the encodings of the fields
+	encodings := map[string]http.PartEncoding{
+		"avatar": {ContentType: "image/jpeg, image/png", Headers: map[string]string{"X-Source": "cli"}, Binary: true},
+		"meta":   {ContentType: "application/json"},
+	}
+	parts, err := http.FormParts(map[string]interface{}{
+		"avatar": "@" + avatar,
+		"meta":   map[string]interface{}{"a": 1.0},
+		"tags":   []interface{}{"a", "b"},
+	}, encodings)
+	if err != nil {
+		t.Fatalf("Failed to build parts: %v", err)
+	}
+
+	// Parse a form field with attributes
+	note, err := http.ParseFormPart("note=@" + avatar + ";type=text/plain;filename=note.txt")
+	if err != nil {
+		t.Fatalf("Failed to parse form field: %v", err)
+	}
+	if _, err := http.ParseFormPart("missing=@" + filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+
+	client := http.NewClient(server.URL, "")
+	if _, err := client.Execute(&http.Request{Method: "POST", Path: "/", Parts: append(parts, note)}); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	expected := map[string][]part{
+		"avatar": {{"me.png", "image/png", "cli", "png"}},
+		"meta":   {{"", "application/json", "", `{"a":1}`}},
+		"tags":   {{"", "", "", "a"}, {"", "", "", "b"}},
+		"note":   {{"note.txt", "text/plain", "", "png"}},
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("Expected parts %v, got %v", expected, received)
+	}
+}
+
+func TestStdinReadOnce(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer reader.Close()
+	if _, err := writer.WriteString("dump"); err != nil {
+		t.Fatalf("Failed to write to pipe: %v", err)
+	}
+	writer.Close()
+
+	stdin := os.Stdin
+	os.Stdin = reader
+	defer func() { os.Stdin = stdin }()
+
+	// The first consumer gets stdin, later ones are rejected instead of getting nothing
+	part, err := http.ParseFormPart("dump=@-")
+	if err != nil {
+		t.Fatalf("Failed to parse form field: %v", err)
+	}
+	if part.Value != "dump" || part.Filename != "stdin" {
+		t.Errorf("Expected the stdin content, got %q (%q)", part.Value, part.Filename)
+	}
+	if _, err := http.ParseFormPart("other=@-"); !errors.Is(err, http.ErrStdinRead) {
+		t.Errorf("Expected a second form field to be rejected, got %v", err)
+	}
+	if _, err := utils.ReadDataFlag("@-"); !errors.Is(err, http.ErrStdinRead) {
+		t.Errorf("Expected data read from stdin to be rejected, got %v", err)
+	}
+	if _, err := http.FormParts(map[string]interface{}{"file": "@-"}, map[string]http.PartEncoding{"file": {Binary: true}}); !errors.Is(err, http.ErrStdinRead) {
+		t.Errorf("Expected a binary property read from stdin to be rejected, got %v", err)
+	}
+}
+
+func TestParameterSerialization(t *testing.T) {
+	explode, noExplode := true, false
+	object := map[string]interface{}{"R": 100.0, "G": "a b"}
+	list := []string{"blue", "a/b"}
+
+	// Fill in the default styles
+	if style, explode := http.ParameterStyle("query", "", nil); style != http.StyleForm || !explode {
+		t.Errorf("Expected exploded form style for query parameters, got %s (%v)", style, explode)
+	}
+	if style, explode := http.ParameterStyle("path", "", nil); style != http.StyleSimple || explode {
+		t.Errorf("Expected simple style for path parameters, got %s (%v)", style, explode)
+	}
+	if _, explode := http.ParameterStyle("query", http.StyleForm, &noExplode); explode {
+		t.Errorf("Expected explode to be kept")
+	}
+
+	paths := []struct {
+		value    interface{}
+		style    string
+		explode  bool
+		expected string
+	}{
+		{"a b/c", http.StyleSimple, false, "a%20b%2Fc"},
+		{list, http.StyleSimple, false, "blue,a%2Fb"},
+		{object, http.StyleSimple, false, "G,a%20b,R,100"},
+		{object, http.StyleSimple, explode, "G=a%20b,R=100"},
+		{list, http.StyleLabel, false, ".blue,a%2Fb"},
+		{list, http.StyleLabel, explode, ".blue.a%2Fb"},
+		{list, http.StyleMatrix, false, ";color=blue,a%2Fb"},
+		{list, http.StyleMatrix, explode, ";color=blue;color=a%2Fb"},
+		{object, http.StyleMatrix, explode, ";G=a%20b;R=100"},
+		{"", http.StyleMatrix, false, ";color"},
+	}
+	for _, tt := range paths {
+		if got := http.PathValue("color", tt.value, tt.style, tt.explode); got != tt.expected {
+			t.Errorf("Expected %v in %s style (explode %v) to be %q, got %q", tt.value, tt.style, tt.explode, tt.expected, got)
+		}
+	}
+
+	queries := []struct {
+		value    interface{}
+		style    string
+		explode  bool
+		expected string
+	}{
+		{list, http.StyleForm, explode, "color=blue&color=a%2Fb"},
+		{list, http.StyleForm, false, "color=blue%2Ca%2Fb"},
+		{list, http.StyleSpaceDelimited, false, "color=blue+a%2Fb"},
+		{list, http.StylePipeDelimited, false, "color=blue%7Ca%2Fb"},
+		{object, http.StyleForm, explode, "G=a+b&R=100"},
+		{object, http.StyleDeepObject, explode, "color%5BG%5D=a+b&color%5BR%5D=100"},
+	}
+	for _, tt := range queries {
+		values := url.Values{}
+		http.AddQueryValue(values, "color", tt.value, tt.style, tt.explode)
+		if got := values.Encode(); got != tt.expected {
+			t.Errorf("Expected %v in %s style (explode %v) to be %q, got %q", tt.value, tt.style, tt.explode, tt.expected, got)
+		}
+	}
+
+	if got := http.HeaderValue(object, true); got != "G=a b,R=100" {
+		t.Errorf("Unexpected header value %q", got)
+	}
+	if got := http.CookieValue("color", list, true); got != "color=blue; color=a%2Fb" {
+		t.Errorf("Unexpected cookie %q", got)
+	}
+	if got := http.CookieValue("color", "a;b", false); got != "color=a%3Bb" {
+		t.Errorf("Unexpected cookie %q", got)
+	}
+}
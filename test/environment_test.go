@@ -0,0 +1,118 @@
+package test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fynxlabs/ontap/internal/pkg/config"
+)
+
+func TestActiveEnvironment(t *testing.T) {
+	api := config.APIConfig{
+		DefaultEnvironment: "dev",
+		Environments: map[string]config.EnvironmentConfig{
+			"dev":     {},
+			"staging": {},
+			"prod":    {},
+		},
+	}
+	state := &config.State{Environments: map[string]string{"billing": "staging"}}
+
+	tests := []struct {
+		name   string
+		flag   string
+		envVar string
+		state  *config.State
+		want   string
+	}{
+		{name: "flag", flag: "prod", envVar: "staging", state: state, want: "prod"},
+		{name: "environment variable", envVar: "prod", state: state, want: "prod"},
+		{name: "state", state: state, want: "staging"},
+		{name: "default", state: &config.State{}, want: "dev"},
+		{name: "no state", want: "dev"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(config.EnvironmentVar, tt.envVar)
+			if got := api.ActiveEnvironment("billing", tt.flag, tt.state); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	// APIs without environments ignore the selection
+	t.Setenv(config.EnvironmentVar, "prod")
+	if got := (config.APIConfig{}).ActiveEnvironment("billing", "prod", state); got != "" {
+		t.Errorf("Expected no environment, got %q", got)
+	}
+
+	// The selection made with 'ontap env use' is persisted in the state file
+	path := filepath.Join(t.TempDir(), "state.yaml")
+	if err := config.SaveState(state, path); err != nil {
+		t.Fatalf("Failed to save state: %v", err)
+	}
+	loaded, err := config.LoadState(path)
+	if err != nil || !reflect.DeepEqual(loaded, state) {
+		t.Errorf("Expected %v, got %v (%v)", state, loaded, err)
+	}
+}
+
+func TestWithEnvironment(t *testing.T) {
+	api := config.APIConfig{
+		URL:         "https://api.example.com",
+		Auth:        "base-token",
+		AuthCommand: "base-command",
+		Credentials: map[string]string{"api_key": "base-key", "basic": "user:pass"},
+		Headers:     map[string]string{"X-Team": "core", "X-Env": "base"},
+		Environments: map[string]config.EnvironmentConfig{
+			"staging": {
+				URL:         "https://staging.example.com",
+				Credentials: map[string]string{"api_key": "staging-key"},
+				Headers:     map[string]string{"X-Env": "staging"},
+			},
+			"prod": {AuthCommand: "prod-command"},
+		},
+	}
+
+	// Maps are merged with the environment's entries winning, and other fields replaced
+	staging, err := api.WithEnvironment("Staging")
+	if err != nil {
+		t.Fatalf("Failed to apply environment: %v", err)
+	}
+	if staging.URL != "https://staging.example.com" || staging.Auth != "base-token" {
+		t.Errorf("Unexpected config: %+v", staging)
+	}
+	if want := map[string]string{"api_key": "staging-key", "basic": "user:pass"}; !reflect.DeepEqual(staging.Credentials, want) {
+		t.Errorf("Expected credentials %v, got %v", want, staging.Credentials)
+	}
+	if want := map[string]string{"X-Team": "core", "X-Env": "staging"}; !reflect.DeepEqual(staging.Headers, want) {
+		t.Errorf("Expected headers %v, got %v", want, staging.Headers)
+	}
+
+	// The API's maps are left untouched
+	if api.Headers["X-Env"] != "base" || api.Credentials["api_key"] != "base-key" {
+		t.Errorf("Expected the API config to be unchanged, got %+v", api)
+	}
+
+	// An environment's auth replaces both ways of setting the auth
+	prod, err := api.WithEnvironment("prod")
+	if err != nil {
+		t.Fatalf("Failed to apply environment: %v", err)
+	}
+	if prod.Auth != "" || prod.AuthCommand != "prod-command" || prod.URL != api.URL {
+		t.Errorf("Unexpected config: %+v", prod)
+	}
+
+	// No environment leaves the config unchanged, and unknown ones are errors
+	if unchanged, err := api.WithEnvironment(""); err != nil || unchanged.URL != api.URL {
+		t.Errorf("Expected the config unchanged, got %+v (%v)", unchanged, err)
+	}
+	if _, err := api.WithEnvironment("qa"); err == nil {
+		t.Errorf("Expected an error for an unknown environment")
+	}
+	if _, err := (config.APIConfig{}).WithEnvironment("qa"); err == nil {
+		t.Errorf("Expected an error for an API without environments")
+	}
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/fynxlabs/ontap/internal/pkg/exitcode"
+	"github.com/fynxlabs/ontap/internal/pkg/validation"
+)
+
+func TestParseFailOn(t *testing.T) {
+	tests := []struct {
+		value    string
+		matches  []int
+		excludes []int
+		wantErr  bool
+	}{
+		{value: "4xx,5xx", matches: []int{400, 404, 500, 599}, excludes: []int{200, 302}},
+		{value: "404, 302", matches: []int{404, 302}, excludes: []int{400, 500}},
+		{value: "400-403,5XX", matches: []int{400, 403, 503}, excludes: []int{404, 399}},
+		{value: "none", excludes: []int{200, 404, 500}},
+		{value: "", excludes: []int{404}},
+		{value: "6xx", wantErr: true},
+		{value: "99", wantErr: true},
+		{value: "abc", wantErr: true},
+		{value: "403-400", wantErr: true},
+		{value: "400-", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		matcher, err := exitcode.ParseFailOn(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		for _, code := range tt.matches {
+			if !matcher.Matches(code) {
+				t.Errorf("%q: expected %d to match", tt.value, code)
+			}
+		}
+		for _, code := range tt.excludes {
+			if matcher.Matches(code) {
+				t.Errorf("%q: expected %d not to match", tt.value, code)
+			}
+		}
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	urlErr := &url.Error{Op: "Get", URL: "http://localhost", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "redirect", err: exitcode.StatusError(302), want: exitcode.Redirect},
+		{name: "client", err: exitcode.StatusError(404), want: exitcode.Client},
+		{name: "server", err: exitcode.StatusError(503), want: exitcode.Server},
+		{name: "other status", err: exitcode.StatusError(101), want: exitcode.General},
+		{name: "wrapped status", err: fmt.Errorf("request: %w", exitcode.StatusError(500)), want: exitcode.Server},
+		{name: "network", err: exitcode.RequestError(fmt.Errorf("failed to send request: %w", urlErr)), want: exitcode.Network},
+		{name: "not a network error", err: exitcode.RequestError(fmt.Errorf("failed to create request: %w", errors.New("bad body"))), want: exitcode.General},
+		{name: "request validation", err: fmt.Errorf("invalid: %w", validation.Errors{{Pointer: "/body", Message: "bad"}}), want: exitcode.Validation},
+		{name: "response validation", err: validation.ResponseErrors{{Pointer: "/status", Message: "bad"}}, want: exitcode.Validation},
+		{name: "config", err: &exitcode.Error{Code: exitcode.Config, Err: errors.New("no config")}, want: exitcode.Config},
+		{name: "general", err: errors.New("failed"), want: exitcode.General},
+	}
+
+	for _, tt := range tests {
+		if got := exitcode.FromError(tt.err); got != tt.want {
+			t.Errorf("%s: expected exit code %d, got %d", tt.name, tt.want, got)
+		}
+	}
+
+	// Status errors describe the status
+	if msg := exitcode.StatusError(404).Error(); msg != "request failed with status 404 Not Found" {
+		t.Errorf("Unexpected status error: %s", msg)
+	}
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/fynxlabs/ontap/internal/pkg/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func TestParameterFlags(t *testing.T) {
+	reserved := pflag.NewFlagSet("root", pflag.ContinueOnError)
+	reserved.String("output", "json", "")
+
+	cmd := &cobra.Command{Use: "test"}
+	utils.AddRequestFlags(cmd)
+	err := utils.AddParameterFlags(cmd, []utils.Parameter{
+		{Name: "id", In: "path", Required: true},
+		{Name: "limit", In: "query", Schema: &utils.ParameterSchema{Type: "integer"}},
+		{Name: "limit", In: "header"},
+		{Name: "timeout", In: "query", Required: true},
+		{Name: "output", In: "query"},
+	}, reserved)
+	if err != nil {
+		t.Fatalf("Failed to add parameter flags: %v", err)
+	}
+
+	// Parameters clashing with another flag get a prefixed flag
+	tests := []struct {
+		name     string
+		in       string
+		flag     string
+		required bool
+	}{
+		{name: "limit", in: "query", flag: "limit"},
+		{name: "limit", in: "header", flag: "param-limit"},
+		{name: "timeout", in: "query", flag: "param-timeout", required: true},
+		{name: "output", in: "query", flag: "param-output"},
+	}
+	for _, tt := range tests {
+		flag := utils.ParameterFlag(cmd.Flags(), tt.name, tt.in)
+		if flag == nil || flag.Name != tt.flag {
+			t.Errorf("%s in %s: expected --%s, got %v", tt.name, tt.in, tt.flag, flag)
+			continue
+		}
+		if required := len(flag.Annotations[cobra.BashCompOneRequiredFlag]) > 0; required != tt.required {
+			t.Errorf("%s in %s: expected required %v, got %v", tt.name, tt.in, tt.required, required)
+		}
+	}
+	if flag := utils.ParameterFlag(cmd.Flags(), "id", "path"); flag != nil {
+		t.Errorf("Expected no flag for a path parameter, got --%s", flag.Name)
+	}
+
+	// The clashing flags keep their meaning
+	if err := cmd.ParseFlags([]string{"--timeout=5s", "--param-timeout=slow"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if timeout, err := cmd.Flags().GetDuration("timeout"); err != nil || timeout.String() != "5s" {
+		t.Errorf("Expected the request timeout to be 5s, got %v (%v)", timeout, err)
+	}
+	if value := utils.ParameterFlag(cmd.Flags(), "timeout", "query").Value.String(); value != "slow" {
+		t.Errorf("Expected the timeout parameter to be slow, got %q", value)
+	}
+}
+
+func TestPaginationParameterFlags(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	utils.AddPaginationFlags(cmd)
+	err := utils.AddParameterFlags(cmd, []utils.Parameter{
+		{Name: "all", In: "query", Schema: &utils.ParameterSchema{Type: "boolean"}},
+		{Name: "max-pages", In: "query", Schema: &utils.ParameterSchema{Type: "integer"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to add parameter flags: %v", err)
+	}
+
+	// The pagination flags keep their meaning, and the parameters get prefixed flags
+	if err := cmd.ParseFlags([]string{"--param-all", "--param-max-pages=2", "--max-pages=5"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if all, _ := cmd.Flags().GetBool("all"); all {
+		t.Errorf("Expected --all to be unset")
+	}
+	if maxPages, _ := cmd.Flags().GetInt("max-pages"); maxPages != 5 {
+		t.Errorf("Expected --max-pages to be 5, got %d", maxPages)
+	}
+	for name, expected := range map[string]string{"all": "true", "max-pages": "2"} {
+		flag := utils.ParameterFlag(cmd.Flags(), name, "query")
+		if flag == nil || flag.Name != "param-"+name || flag.Value.String() != expected {
+			t.Errorf("Expected --param-%s to be %s, got %v", name, expected, flag)
+		}
+	}
+}
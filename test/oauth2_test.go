@@ -0,0 +1,324 @@
+package test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/charmbracelet/log"
+	"github.com/fynxlabs/ontap/internal/pkg/http"
+)
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	// Stand-in token endpoint that issues a new token on every request
+	var issued atomic.Int32
+	tokenServer := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "secret" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(nethttp.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		n := issued.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer tokenServer.Close()
+
+	// API that only accepts the most recently issued token
+	apiServer := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token-%d", issued.Load()) {
+			w.WriteHeader(nethttp.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer apiServer.Close()
+
+	dir := t.TempDir()
+	config := http.OAuth2Config{
+		API:          "test-api",
+		Grant:        http.GrantClientCredentials,
+		TokenURL:     tokenServer.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+	}
+	execute := func() *http.Response {
+		provider := http.NewOAuth2Provider(config, http.NewFileTokenStore(dir), nil)
+		client := http.NewClient(apiServer.URL, "")
+		resp, err := client.Execute(&http.Request{
+			Method:        "GET",
+			Path:          "/",
+			AuthProviders: []http.AuthProvider{provider},
+		})
+		if err != nil {
+			t.Fatalf("Failed to execute request: %v", err)
+		}
+		return resp
+	}
+
+	// The first request fetches a token
+	if resp := execute(); resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if issued.Load() != 1 {
+		t.Fatalf("Expected 1 token request, got %d", issued.Load())
+	}
+
+	// The token is cached on disk with restricted permissions
+	files, _ := filepath.Glob(filepath.Join(dir, "test-api", "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 cached token, got %d", len(files))
+	}
+	info, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatalf("Failed to stat token file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected token file mode 0600, got %o", info.Mode().Perm())
+	}
+
+	// A new provider reuses the cached token
+	if resp := execute(); resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if issued.Load() != 1 {
+		t.Fatalf("Expected the cached token to be used, got %d token requests", issued.Load())
+	}
+
+	// A revoked token is replaced after a 401 and the request is retried
+	issued.Add(1)
+	if resp := execute(); resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200 after refresh, got %d", resp.StatusCode)
+	}
+	if issued.Load() != 3 {
+		t.Fatalf("Expected a new token after the 401, got %d token requests", issued.Load())
+	}
+}
+
+func TestOAuth2RefreshToken(t *testing.T) {
+	tokenServer := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") != "refresh-1" || r.FormValue("client_id") != "public" {
+			w.WriteHeader(nethttp.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "bad refresh token"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-1",
+			"expires_in":   "60",
+		})
+	}))
+	defer tokenServer.Close()
+
+	provider := http.NewOAuth2Provider(http.OAuth2Config{
+		API:          "test-api",
+		Grant:        http.GrantRefreshToken,
+		TokenURL:     tokenServer.URL,
+		ClientID:     "public",
+		RefreshToken: "refresh-1",
+	}, http.NewFileTokenStore(t.TempDir()), nil)
+
+	token, err := provider.Token()
+	if err != nil {
+		t.Fatalf("Failed to get token: %v", err)
+	}
+	if token.AccessToken != "access-1" || token.RefreshToken != "refresh-1" || token.ExpiresAt.IsZero() {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+
+	// Errors from the token endpoint are reported
+	provider = http.NewOAuth2Provider(http.OAuth2Config{
+		API:          "test-api",
+		Grant:        http.GrantRefreshToken,
+		TokenURL:     tokenServer.URL,
+		ClientID:     "public",
+		RefreshToken: "wrong",
+	}, nil, nil)
+	if _, err := provider.Token(); err == nil || err.Error() != "token request failed: invalid_grant: bad refresh token" {
+		t.Errorf("Expected invalid_grant error, got %v", err)
+	}
+}
+
+func TestOAuth2LoginWithBrowser(t *testing.T) {
+	// Stand-in authorization server that approves every request
+	var challenge string
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		switch r.URL.Path {
+		case "/authorize":
+			query := r.URL.Query()
+			if query.Get("client_id") != "cli" || query.Get("code_challenge_method") != "S256" || query.Get("scope") != "read write" {
+				w.WriteHeader(nethttp.StatusBadRequest)
+				return
+			}
+			challenge = query.Get("code_challenge")
+			nethttp.Redirect(w, r, query.Get("redirect_uri")+"?code=code-1&state="+query.Get("state"), nethttp.StatusFound)
+		case "/token":
+			sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+			if r.FormValue("grant_type") != "authorization_code" || r.FormValue("code") != "code-1" ||
+				base64.RawURLEncoding.EncodeToString(sum[:]) != challenge || !strings.HasPrefix(r.FormValue("redirect_uri"), "http://127.0.0.1:") {
+				w.WriteHeader(nethttp.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token":  "access-1",
+				"refresh_token": "refresh-1",
+				"expires_in":    3600,
+			})
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	provider := http.NewOAuth2Provider(http.OAuth2Config{
+		API:              "test-api",
+		Grant:            http.GrantAuthorizationCode,
+		AuthorizationURL: server.URL + "/authorize",
+		TokenURL:         server.URL + "/token",
+		ClientID:         "cli",
+		Scopes:           []string{"read", "write"},
+	}, http.NewFileTokenStore(dir), nil)
+
+	// The "browser" follows the redirect back to the loopback listener
+	token, err := provider.LoginWithBrowser(context.Background(), func(authURL string) error {
+		resp, err := nethttp.Get(authURL)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+	if token.AccessToken != "access-1" || token.RefreshToken != "refresh-1" {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+
+	// The session is stored for later commands
+	keys, err := http.NewFileTokenStore(dir).List("test-api")
+	if err != nil || len(keys) != 1 || !strings.HasPrefix(keys[0], "login-") {
+		t.Errorf("Expected one stored login session, got %v (%v)", keys, err)
+	}
+}
+
+func TestOAuth2LoginWithDevice(t *testing.T) {
+	// Stand-in authorization server that approves on the second poll
+	var polls atomic.Int32
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		switch r.URL.Path {
+		case "/device":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"device_code":      "device-1",
+				"user_code":        "ABCD-EFGH",
+				"verification_uri": "https://example.com/device",
+				"expires_in":       60,
+				"interval":         1,
+			})
+		case "/token":
+			if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:device_code" || r.FormValue("device_code") != "device-1" {
+				w.WriteHeader(nethttp.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			if polls.Add(1) == 1 {
+				w.WriteHeader(nethttp.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "authorization_pending"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "access-1"})
+		}
+	}))
+	defer server.Close()
+
+	provider := http.NewOAuth2Provider(http.OAuth2Config{
+		API:                    "test-api",
+		Grant:                  http.GrantDeviceCode,
+		DeviceAuthorizationURL: server.URL + "/device",
+		TokenURL:               server.URL + "/token",
+		ClientID:               "cli",
+	}, nil, nil)
+
+	var userCode string
+	token, err := provider.LoginWithDevice(context.Background(), func(auth *http.DeviceAuthorization) {
+		userCode = auth.UserCode
+	})
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+	if userCode != "ABCD-EFGH" || token.AccessToken != "access-1" || polls.Load() != 2 {
+		t.Errorf("Unexpected login result: user code %q, token %+v, %d polls", userCode, token, polls.Load())
+	}
+}
+
+func TestOAuth2DryRun(t *testing.T) {
+	var requested atomic.Int32
+	tokenServer := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		requested.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "secret-token", "expires_in": 3600})
+	}))
+	defer tokenServer.Close()
+
+	// Capture the logged request
+	var logged strings.Builder
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	dir := t.TempDir()
+	provider := http.NewOAuth2Provider(http.OAuth2Config{
+		API:          "test-api",
+		Grant:        http.GrantClientCredentials,
+		TokenURL:     tokenServer.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+	}, http.NewFileTokenStore(dir), nil)
+	client := http.NewClient("http://localhost", "")
+	_, err := client.Execute(&http.Request{
+		Method:  "GET",
+		Path:    "/",
+		Headers: map[string]string{"Cookie": "session=cookie-value; theme=dark"},
+		AuthProviders: []http.AuthProvider{
+			provider,
+			http.NewAPIKeyAuth("header-key", "X-Token", "header"),
+			http.NewAPIKeyAuth("query-key", "key", "query"),
+		},
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to execute dry run: %v", err)
+	}
+
+	// No token is requested or stored for a request that isn't sent
+	if requested.Load() != 0 {
+		t.Errorf("Expected no token request, got %d", requested.Load())
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*", "*")); len(files) != 0 {
+		t.Errorf("Expected no stored token, got %v", files)
+	}
+
+	// The logged request shows where the token goes and hides the other credentials
+	output := logged.String()
+	if !strings.Contains(output, "Bearer "+http.OAuth2TokenPlaceholder) {
+		t.Errorf("Expected the token placeholder in the logged request:\n%s", output)
+	}
+	for _, secret := range []string{"header-key", "query-key", "cookie-value"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("Expected %s to be redacted:\n%s", secret, output)
+		}
+	}
+	if !strings.Contains(output, "session=<redacted>") {
+		t.Errorf("Expected the cookie names to be kept:\n%s", output)
+	}
+}
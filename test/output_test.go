@@ -0,0 +1,312 @@
+package test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fynxlabs/ontap/internal/pkg/output"
+)
+
+func TestFilter(t *testing.T) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(`{
+		"users": [
+			{"id": 1, "name": "Ada", "status": "active", "tags": ["admin"]},
+			{"id": 2, "name": "Bob", "status": "inactive", "tags": []},
+			{"id": 3, "name": "Cy", "status": "active", "tags": ["ops", "dev"]}
+		],
+		"total": 3
+	}`), &data); err != nil {
+		t.Fatalf("Failed to parse data: %v", err)
+	}
+
+	tests := []struct {
+		filter   string
+		expected string
+	}{
+		{filter: ".total", expected: `3`},
+		{filter: ".users[0].name", expected: `"Ada"`},
+		{filter: ".users[] | select(.status == \"active\") | .name", expected: `["Ada","Cy"]`},
+		{filter: "[.users[] | select(.status == \"active\")] | length", expected: `2`},
+		{filter: ".users | map(.id)", expected: `[1,2,3]`},
+		{filter: ".users[1:] | map({name, count: (.tags | length)})", expected: `[{"count":0,"name":"Bob"},{"count":2,"name":"Cy"}]`},
+		{filter: ".users[0] | keys", expected: `["id","name","status","tags"]`},
+		{filter: `.users[2] | "\(.name) is \(.status)"`, expected: `"Cy is active"`},
+		{filter: ".users[] | select(.name == \"Nobody\")", expected: `null`},
+	}
+
+	for _, tt := range tests {
+		result, err := output.FilterData(data, tt.filter)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.filter, err)
+			continue
+		}
+		got, _ := json.Marshal(result)
+		if string(got) != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.filter, tt.expected, got)
+		}
+	}
+
+	// Syntax errors are reported when compiling, runtime errors when applying
+	if _, err := output.NewFilter(".users[] | select("); err == nil {
+		t.Errorf("Expected a syntax error")
+	}
+	if _, err := output.FilterData(data, ".total[]"); err == nil {
+		t.Errorf("Expected an error iterating over a number")
+	}
+
+	// A filter producing no results is told apart from one producing null
+	f, err := output.NewFilter(".users[] | select(.name == \"Nobody\")")
+	if err != nil {
+		t.Fatalf("Failed to compile filter: %v", err)
+	}
+	if result, ok, err := f.Apply(data); err != nil || ok || result != nil {
+		t.Errorf("Expected no results, got %v, %v, %v", result, ok, err)
+	}
+	f, err = output.NewFilter(".missing")
+	if err != nil {
+		t.Fatalf("Failed to compile filter: %v", err)
+	}
+	if result, ok, err := f.Apply(data); err != nil || !ok || result != nil {
+		t.Errorf("Expected a null result, got %v, %v, %v", result, ok, err)
+	}
+}
+
+func TestExtractFields(t *testing.T) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(`{
+		"total": 2,
+		"items": [
+			{"metadata": {"name": "web", "labels": {"tier": "frontend"}}, "ports": [80, 443]},
+			{"metadata": {"name": "db"}, "ports": [5432]}
+		]
+	}`), &data); err != nil {
+		t.Fatalf("Failed to parse data: %v", err)
+	}
+
+	var list interface{}
+	if err := json.Unmarshal([]byte(`[{"id": 1, "name": "Ada"}, {"id": 2, "name": "Bob"}]`), &list); err != nil {
+		t.Fatalf("Failed to parse data: %v", err)
+	}
+
+	tests := []struct {
+		data     interface{}
+		fields   []string
+		expected string
+	}{
+		{data: data, fields: []string{"total", "items.0.metadata.name"}, expected: `{"total":2,"name":"web"}`},
+		{data: data, fields: []string{"$.items[-1].ports[0]", "first=items[0]['metadata'].name"}, expected: `{"ports":5432,"first":"web"}`},
+		{data: data, fields: []string{"name=items.*.metadata.name", "tier=items.*.metadata.labels.tier", "total"}, expected: `[{"name":"web","tier":"frontend","total":2},{"name":"db","tier":null,"total":2}]`},
+		{data: data, fields: []string{"items[*].metadata.name", "items[*].ports[*]"}, expected: `[{"name":"web","ports":[80,443]},{"name":"db","ports":[5432]}]`},
+		{data: data, fields: []string{"..name"}, expected: `[{"name":"web"},{"name":"db"}]`},
+		{data: list, fields: []string{"name", "id"}, expected: `[{"name":"Ada","id":1},{"name":"Bob","id":2}]`},
+		{data: data, fields: []string{"items.*.missing"}, expected: `[{"missing":null},{"missing":null}]`},
+		{data: data, fields: []string{"metadata.name", "items.*.metadata.name"}, expected: `[{"metadata.name":null,"items.*.metadata.name":"web"},{"metadata.name":null,"items.*.metadata.name":"db"}]`},
+	}
+
+	for _, tt := range tests {
+		result, err := output.ExtractFields(tt.data, tt.fields)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.fields, err)
+			continue
+		}
+		got, _ := json.Marshal(result)
+		if string(got) != tt.expected {
+			t.Errorf("%v: expected %s, got %s", tt.fields, tt.expected, got)
+		}
+	}
+
+	// Records keep the order of the fields in CSV
+	result, _ := output.ExtractFields(list, []string{"name", "id"})
+	csv, err := output.NewCSVFormatter(true, ',').Format(result)
+	if err != nil || string(csv) != "name,id\nAda,1\nBob,2\n" {
+		t.Errorf("Expected ordered CSV columns, got %q (%v)", csv, err)
+	}
+
+	for _, field := range []string{"items[0", "items..", "items[x]"} {
+		if _, err := output.NewExtractor([]string{field}); err == nil {
+			t.Errorf("%s: expected an invalid path error", field)
+		}
+	}
+}
+
+func TestTableFormatter(t *testing.T) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(`[
+		{"name": "Zed", "id": 3, "meta": {"team": "ops"}, "bio": "Writes a lot of very long biographies"},
+		{"name": "Ada", "id": 1, "meta": {"team": "core"}},
+		{"name": "Bob", "id": 20, "meta": {"team": "core"}}
+	]`), &data); err != nil {
+		t.Fatalf("Failed to parse data: %v", err)
+	}
+
+	tests := []struct {
+		options  output.TableOptions
+		expected []string
+	}{
+		// Columns follow the preferred order, then the alphabetical order
+		{
+			options:  output.TableOptions{Order: []string{"id", "name"}, Width: -1},
+			expected: []string{"id", "name", "bio", "meta.team"},
+		},
+		// Selected columns are sorted numerically, and nested objects expanded
+		{
+			options:  output.TableOptions{Columns: []string{"name", "meta"}, SortBy: "-id", Width: -1},
+			expected: []string{"name", "meta.team", "Bob", "Zed", "Ada"},
+		},
+	}
+
+	for _, tt := range tests {
+		table, err := output.NewTableFormatter(true, tt.options).Format(data)
+		if err != nil {
+			t.Fatalf("%+v: failed to format table: %v", tt.options, err)
+		}
+		position := -1
+		for _, text := range tt.expected {
+			i := strings.Index(string(table), text)
+			if i <= position {
+				t.Errorf("%+v: expected %q after the previous values in\n%s", tt.options, text, table)
+			}
+			position = i
+		}
+	}
+
+	// Tables are shrunk to the width, truncating or wrapping the cells
+	for _, truncate := range []bool{true, false} {
+		table, err := output.NewTableFormatter(true, output.TableOptions{Width: 40, Truncate: truncate}).Format(data)
+		if err != nil {
+			t.Fatalf("Failed to format table: %v", err)
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(table)), "\n") {
+			if width := len([]rune(line)); width > 40 {
+				t.Errorf("Expected lines of at most 40 characters, got %d: %s", width, line)
+			}
+		}
+	}
+
+	if _, err := output.NewTableFormatter(true, output.TableOptions{SortBy: "missing"}).Format(data); err == nil {
+		t.Errorf("Expected an error sorting by an unknown column")
+	}
+}
+
+func TestTemplateFormatter(t *testing.T) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(`{"items": [
+		{"id": 1234567, "name": "Ada", "tags": ["admin", "ops"], "created": "2024-03-01T10:00:00Z"},
+		{"id": 2, "name": "Bob", "tags": [], "created": 1709287200}
+	]}`), &data); err != nil {
+		t.Fatalf("Failed to parse data: %v", err)
+	}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{template: `{{range .items}}{{.id}}\t{{.name}}{{"\n"}}{{end}}`, expected: "1234567\tAda\n2\tBob\n"},
+		{template: `{{range .items}}[{{pad 5 .name}}|{{padleft 3 .name}}]{{end}}`, expected: "[Ada  |Ada][Bob  |Bob]"},
+		{template: `{{range .items}}{{join "," .tags}};{{end}}`, expected: "admin,ops;;"},
+		{template: `{{range .items}}{{date "2006-01-02 15:04" .created}};{{end}}`, expected: "2024-03-01 10:00;2024-03-01 10:00;"},
+		{template: `{{json (index .items 1).tags}} {{upper (index .items 0).name}} {{color "red" "plain"}}`, expected: "[] ADA plain"},
+	}
+
+	for _, tt := range tests {
+		formatter, err := output.NewTemplateFormatter(tt.template)
+		if err != nil {
+			t.Fatalf("%s: failed to parse template: %v", tt.template, err)
+		}
+		formatter.Color = false
+		got, err := formatter.Format(data)
+		if err != nil {
+			t.Errorf("%s: failed to execute template: %v", tt.template, err)
+			continue
+		}
+		if string(got) != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.template, tt.expected, got)
+		}
+	}
+
+	// Colors are only added when enabled
+	formatter, _ := output.NewTemplateFormatter(`{{color "green" "ok"}}`)
+	formatter.Color = true
+	if got, _ := formatter.Format(nil); string(got) != "\x1b[32mok\x1b[0m" {
+		t.Errorf("Expected a green value, got %q", got)
+	}
+
+	if _, err := output.NewTemplateFormatter(`{{.unclosed`); err == nil {
+		t.Errorf("Expected a template syntax error")
+	}
+}
+
+func TestListWriter(t *testing.T) {
+	pages := [][]interface{}{
+		{
+			map[string]interface{}{"id": 1, "name": "Ada", "meta": map[string]interface{}{"team": "core"}},
+			map[string]interface{}{"id": 2, "name": "Bob"},
+		},
+		{},
+		{map[string]interface{}{"id": 3, "name": "Cy", "meta": map[string]interface{}{"team": "ops", "site": "eu"}}},
+	}
+	var all []interface{}
+	for _, page := range pages {
+		all = append(all, page...)
+	}
+
+	csv := output.NewCSVFormatter(true, ',')
+	csv.Options.Columns = []string{"id", "meta"}
+	formatters := map[string]output.Formatter{
+		"pretty JSON":  output.NewJSONFormatter(true),
+		"compact JSON": output.NewJSONFormatter(false),
+		"YAML":         output.NewYAMLFormatter(),
+		"CSV":          csv,
+	}
+
+	for name, formatter := range formatters {
+		t.Run(name, func(t *testing.T) {
+			// Writing the pages one by one has the output of formatting the whole list,
+			// except that CSV keeps the columns of the first page
+			path := filepath.Join(t.TempDir(), "out")
+			writer, err := output.NewListWriter(formatter, path)
+			if err != nil {
+				t.Fatalf("Failed to create list writer: %v", err)
+			}
+			for _, page := range pages {
+				if err := writer.Write(page); err != nil {
+					t.Fatalf("Failed to write page: %v", err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Failed to close list writer: %v", err)
+			}
+			got, _ := os.ReadFile(path)
+
+			expected, _ := formatter.Format(all)
+			if formatter == csv {
+				expected = []byte("id,meta.team\n1,core\n2,\n3,ops\n")
+			}
+			if string(got) != string(expected) {
+				t.Errorf("Unexpected output:\n got %q\nwant %q", got, expected)
+			}
+
+			// An empty list is still a list
+			empty, _ := output.NewListWriter(formatter, path)
+			empty.Close()
+			got, _ = os.ReadFile(path)
+			expected, _ = formatter.Format([]interface{}{})
+			if string(got) != string(expected) {
+				t.Errorf("Unexpected empty output:\n got %q\nwant %q", got, expected)
+			}
+		})
+	}
+
+	// Formats that need the whole list can't be written as it arrives
+	sorted := output.NewCSVFormatter(true, ',')
+	sorted.Options = output.TableOptions{Columns: []string{"id"}, SortBy: "id"}
+	for _, formatter := range []output.Formatter{output.NewCSVFormatter(true, ','), sorted, output.NewTextFormatter(), output.NewTableFormatter(true, output.TableOptions{})} {
+		if output.CanWriteList(formatter) {
+			t.Errorf("Expected %s output to need the whole list", formatter.GetFormat())
+		}
+	}
+}
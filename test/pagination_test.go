@@ -0,0 +1,90 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/fynxlabs/ontap/internal/pkg/http"
+	"github.com/fynxlabs/ontap/internal/pkg/openapi"
+)
+
+func TestPaginator(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7}
+	pageOf := func(start, size int) []int {
+		end := min(start+size, len(items))
+		return items[min(start, end):end]
+	}
+
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		query := r.URL.Query()
+		var body interface{}
+		switch r.URL.Path {
+		case "/link":
+			page, _ := strconv.Atoi(query.Get("page"))
+			page = max(page, 1)
+			if page < 3 {
+				w.Header().Set("Link", fmt.Sprintf(`</link?page=%d>; rel="next"`, page+1))
+			}
+			body = pageOf((page-1)*3, 3)
+		case "/cursor":
+			start, _ := strconv.Atoi(query.Get("cursor"))
+			var next interface{}
+			if start+3 < len(items) {
+				next = strconv.Itoa(start + 3)
+			}
+			body = map[string]interface{}{"data": pageOf(start, 3), "next_cursor": next}
+		case "/offset":
+			offset, _ := strconv.Atoi(query.Get("offset"))
+			body = pageOf(offset, 2)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path       string
+		pagination openapi.Pagination
+		maxPages   int
+		expected   int
+		pages      int
+	}{
+		{path: "/link", expected: 7, pages: 3},
+		{path: "/cursor", expected: 7, pages: 3},
+		{path: "/offset", pagination: openapi.Pagination{OffsetParam: "offset"}, expected: 7, pages: 4},
+		{path: "/link", maxPages: 2, expected: 6, pages: 2},
+	}
+
+	for _, tt := range tests {
+		client := http.NewClient(server.URL, "")
+		req := &http.Request{Method: "GET", Path: tt.path, QueryParams: url.Values{}}
+		paginator := http.NewPaginator(client, req, tt.pagination)
+		paginator.MaxPages = tt.maxPages
+
+		var got []interface{}
+		pages := 0
+		for {
+			page, err := paginator.Next()
+			if err != nil {
+				t.Fatalf("%s: failed to fetch page: %v", tt.path, err)
+			}
+			if page == nil {
+				break
+			}
+			pages++
+			got = append(got, page.Items...)
+		}
+
+		if len(got) != tt.expected || pages != tt.pages {
+			t.Errorf("%s: expected %d items in %d pages, got %d items in %d pages: %v", tt.path, tt.expected, tt.pages, len(got), pages, got)
+		}
+		if tt.maxPages > 0 && !paginator.More() {
+			t.Errorf("%s: expected more pages after the maximum", tt.path)
+		}
+	}
+}
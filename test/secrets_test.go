@@ -0,0 +1,118 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fynxlabs/ontap/internal/pkg/secrets"
+)
+
+func TestFileStore(t *testing.T) {
+	for _, passphrase := range []string{"", "correct horse"} {
+		t.Run("passphrase="+passphrase, func(t *testing.T) {
+			t.Setenv(secrets.PassphraseEnv, passphrase)
+
+			path := filepath.Join(t.TempDir(), "secrets.enc")
+			store := secrets.NewFileStore(path)
+			if err := store.Set("my-api/token", "s3cret"); err != nil {
+				t.Fatalf("Failed to set secret: %v", err)
+			}
+
+			// The secret is encrypted and the file is private
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read secret file: %v", err)
+			}
+			if strings.Contains(string(data), "s3cret") {
+				t.Errorf("Secret file contains the plaintext secret")
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("Failed to stat secret file: %v", err)
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Errorf("Expected secret file mode 0600, got %o", info.Mode().Perm())
+			}
+
+			// A new store reads the secret back
+			value, err := secrets.NewFileStore(path).Resolve("my-api/token")
+			if err != nil || value != "s3cret" {
+				t.Errorf("Expected s3cret, got %q (%v)", value, err)
+			}
+
+			names, err := store.List()
+			if err != nil || len(names) != 1 || names[0] != "my-api/token" {
+				t.Errorf("Expected [my-api/token], got %v (%v)", names, err)
+			}
+
+			deleted, err := store.Delete("my-api/token")
+			if err != nil || !deleted {
+				t.Errorf("Expected the secret to be deleted, got %v (%v)", deleted, err)
+			}
+			if _, err := store.Resolve("my-api/token"); err == nil {
+				t.Errorf("Expected an error for a deleted secret")
+			}
+		})
+	}
+
+	// A passphrase-encrypted file can't be read without the passphrase
+	t.Setenv(secrets.PassphraseEnv, "one")
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	if err := secrets.NewFileStore(path).Set("a", "b"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	t.Setenv(secrets.PassphraseEnv, "two")
+	if _, err := secrets.NewFileStore(path).Resolve("a"); err == nil {
+		t.Errorf("Expected an error with the wrong passphrase")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("ONTAP_TEST_TOKEN", "from-env")
+
+	store := secrets.NewFileStore(filepath.Join(t.TempDir(), "secrets.enc"))
+	if err := store.Set("api/token", "from-file"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	secrets.Register(secrets.SchemeSecret, store)
+	defer secrets.Register(secrets.SchemeSecret, secrets.NewFileStore(""))
+
+	tests := []struct {
+		value    string
+		expected string
+		wantErr  bool
+	}{
+		{value: "plain-token", expected: "plain-token"},
+		{value: "user:pass", expected: "user:pass"},
+		{value: "${ONTAP_TEST_TOKEN}", expected: "from-env"},
+		{value: "env:ONTAP_TEST_TOKEN", expected: "from-env"},
+		{value: "env://ONTAP_TEST_TOKEN", expected: "from-env"},
+		{value: "env:ONTAP_TEST_MISSING", wantErr: true},
+		{value: "secret:api/token", expected: "from-file"},
+		{value: "secret:api/missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		value, err := secrets.Resolve(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Resolve(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if value != tt.expected {
+			t.Errorf("Resolve(%q) = %q, expected %q", tt.value, value, tt.expected)
+		}
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	value, err := secrets.RunCommand("echo token-from-command")
+	if err != nil || value != "token-from-command" {
+		t.Errorf("Expected token-from-command, got %q (%v)", value, err)
+	}
+
+	if _, err := secrets.RunCommand("exit 3"); err == nil {
+		t.Errorf("Expected an error for a failing command")
+	}
+}
@@ -0,0 +1,172 @@
+package test
+
+import (
+	nethttp "net/http"
+	"reflect"
+	"testing"
+
+	"github.com/fynxlabs/ontap/internal/pkg/http"
+	"github.com/fynxlabs/ontap/internal/pkg/openapi"
+)
+
+func TestResolveAuth(t *testing.T) {
+	schemes := map[string]*openapi.SecurityScheme{
+		"ApiKey":  {Type: "apiKey", In: "header", Name: "X-API-Key"},
+		"query":   {Type: "apiKey", In: "query", Name: "key"},
+		"session": {Type: "apiKey", In: "cookie", Name: "sid"},
+		"basic":   {Type: "http", Scheme: "basic"},
+		"bearer":  {Type: "http", Scheme: "Bearer"},
+		"digest":  {Type: "http", Scheme: "digest"},
+		"oauth":   {Type: "oauth2"},
+		"oidc":    {Type: "openIdConnect"},
+	}
+
+	tests := []struct {
+		name         string
+		requirements []map[string][]string
+		credentials  http.Credentials
+		want         map[string]string
+		wantErr      bool
+	}{
+		{
+			name: "no requirements",
+			want: map[string]string{},
+		},
+		{
+			name:         "optional auth",
+			requirements: []map[string][]string{{}},
+			want:         map[string]string{},
+		},
+		{
+			name:         "API key header with a lowercased scheme key",
+			requirements: []map[string][]string{{"ApiKey": {}}},
+			credentials:  http.Credentials{Schemes: map[string]string{"apikey": "k1"}},
+			want:         map[string]string{"header:X-Api-Key": "k1"},
+		},
+		{
+			name:         "API key query",
+			requirements: []map[string][]string{{"query": {}}},
+			credentials:  http.Credentials{Schemes: map[string]string{"query": "k2"}},
+			want:         map[string]string{"query:key": "k2"},
+		},
+		{
+			name:         "API key cookie",
+			requirements: []map[string][]string{{"session": {}}},
+			credentials:  http.Credentials{Schemes: map[string]string{"session": "s1"}},
+			want:         map[string]string{"cookie:sid": "s1"},
+		},
+		{
+			name:         "basic",
+			requirements: []map[string][]string{{"basic": {}}},
+			credentials:  http.Credentials{Schemes: map[string]string{"basic": "user:pass"}},
+			want:         map[string]string{"header:Authorization": "Basic dXNlcjpwYXNz"},
+		},
+		{
+			name:         "OAuth2 token",
+			requirements: []map[string][]string{{"oauth": {"read"}}},
+			credentials:  http.Credentials{Schemes: map[string]string{"oauth": "t1"}},
+			want:         map[string]string{"header:Authorization": "Bearer t1"},
+		},
+		{
+			name:         "OpenID Connect token",
+			requirements: []map[string][]string{{"oidc": {}}},
+			credentials:  http.Credentials{Schemes: map[string]string{"oidc": "t2"}},
+			want:         map[string]string{"header:Authorization": "Bearer t2"},
+		},
+		{
+			name:         "first satisfied alternative",
+			requirements: []map[string][]string{{"bearer": {}}, {"basic": {}}},
+			credentials:  http.Credentials{Schemes: map[string]string{"basic": "user:pass"}},
+			want:         map[string]string{"header:Authorization": "Basic dXNlcjpwYXNz"},
+		},
+		{
+			name:         "all schemes of a requirement",
+			requirements: []map[string][]string{{"ApiKey": {}, "query": {}}},
+			credentials:  http.Credentials{Schemes: map[string]string{"ApiKey": "k1", "query": "k2"}},
+			want:         map[string]string{"header:X-Api-Key": "k1", "query:key": "k2"},
+		},
+		{
+			name:         "default is not used for a requirement with several schemes",
+			requirements: []map[string][]string{{"ApiKey": {}, "query": {}}},
+			credentials:  http.Credentials{Schemes: map[string]string{"ApiKey": "k1"}, Default: "k2"},
+			wantErr:      true,
+		},
+		{
+			name:         "default for a single scheme",
+			requirements: []map[string][]string{{"bearer": {}}},
+			credentials:  http.Credentials{Default: "t1"},
+			want:         map[string]string{"header:Authorization": "Bearer t1"},
+		},
+		{
+			name:         "default command for a single scheme",
+			requirements: []map[string][]string{{"bearer": {}}},
+			credentials:  http.Credentials{DefaultCommand: "echo t2"},
+			want:         map[string]string{"header:Authorization": "Bearer t2"},
+		},
+		{
+			name:         "scheme credential before the default",
+			requirements: []map[string][]string{{"bearer": {}}},
+			credentials:  http.Credentials{Schemes: map[string]string{"bearer": "t1"}, Default: "t2"},
+			want:         map[string]string{"header:Authorization": "Bearer t1"},
+		},
+		{
+			name:         "override before the scheme credential",
+			requirements: []map[string][]string{{"bearer": {}}},
+			credentials:  http.Credentials{Schemes: map[string]string{"bearer": "t1"}, Override: "t2"},
+			want:         map[string]string{"header:Authorization": "Bearer t2"},
+		},
+		{
+			name:         "no satisfied alternative",
+			requirements: []map[string][]string{{"bearer": {}}, {"basic": {}}},
+			credentials:  http.Credentials{Schemes: map[string]string{"query": "k2"}},
+			wantErr:      true,
+		},
+		{
+			name:         "undefined scheme",
+			requirements: []map[string][]string{{"missing": {}}},
+			credentials:  http.Credentials{Default: "t1"},
+			wantErr:      true,
+		},
+		{
+			name:         "unsupported scheme",
+			requirements: []map[string][]string{{"digest": {}}},
+			credentials:  http.Credentials{Default: "t1"},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers, err := http.ResolveAuth(tt.requirements, schemes, tt.credentials)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			// Apply the providers and collect the credentials they added
+			req, _ := nethttp.NewRequest("GET", "http://example.com/", nil)
+			for _, provider := range providers {
+				if err := provider.Apply(req); err != nil {
+					t.Fatalf("Failed to apply auth: %v", err)
+				}
+			}
+			got := map[string]string{}
+			for name := range req.Header {
+				if name != "Cookie" {
+					got["header:"+name] = req.Header.Get(name)
+				}
+			}
+			for name := range req.URL.Query() {
+				got["query:"+name] = req.URL.Query().Get(name)
+			}
+			for _, cookie := range req.Cookies() {
+				got["cookie:"+cookie.Name] = cookie.Value
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
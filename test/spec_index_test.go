@@ -0,0 +1,347 @@
+package test
+
+import (
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fynxlabs/ontap/internal/pkg/cache"
+	"github.com/fynxlabs/ontap/internal/pkg/openapi"
+)
+
+func TestSpecIndexCacheRoundTrip(t *testing.T) {
+	// Read the OpenAPI spec
+	raw, err := os.ReadFile("./fixtures/openapi.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read OpenAPI spec: %v", err)
+	}
+
+	// Build the index
+	index, err := cache.BuildLibOpenAPISpecIndex(raw, "./fixtures/openapi.yaml")
+	if err != nil {
+		t.Fatalf("Failed to build spec index: %v", err)
+	}
+	if len(index.Endpoints) == 0 {
+		t.Fatalf("Expected endpoints in spec index")
+	}
+	if len(index.Servers) != 1 || index.Servers[0].URL != "http://api.example.com" {
+		t.Fatalf("Unexpected servers: %+v", index.Servers)
+	}
+
+	// Store the index in a fresh cache
+	store, err := cache.NewLibOpenAPIFileSystemCacheStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create cache store: %v", err)
+	}
+	if err := store.Set("fixture", index, &cache.SpecData{Raw: raw}, time.Hour); err != nil {
+		t.Fatalf("Failed to cache spec index: %v", err)
+	}
+
+	// Read it back through a second store so the file is decoded
+	reader, err := cache.NewLibOpenAPIFileSystemCacheStore(store.CacheDir)
+	if err != nil {
+		t.Fatalf("Failed to create cache store: %v", err)
+	}
+	entry, err := reader.Get("fixture")
+	if err != nil {
+		t.Fatalf("Failed to get cached spec index: %v", err)
+	}
+	if entry.Hash != cache.HashSpec(raw) {
+		t.Errorf("Hash mismatch: got %s", entry.Hash)
+	}
+
+	// The cached index must be identical to the one that was built
+	want, _ := json.Marshal(index)
+	got, _ := json.Marshal(entry.Index)
+	if string(want) != string(got) {
+		t.Errorf("Cached index differs from built index:\nwant %s\ngot  %s", want, got)
+	}
+
+	// The raw spec must be kept alongside the index
+	cachedRaw, err := reader.GetRaw("fixture")
+	if err != nil {
+		t.Fatalf("Failed to get raw spec: %v", err)
+	}
+	if string(cachedRaw) != string(raw) {
+		t.Errorf("Raw spec differs from the original")
+	}
+
+	// Entries from an older index layout are treated as misses
+	entry.Index.Version = openapi.SpecIndexVersion + 1
+	stale, _ := json.Marshal(entry)
+	if err := os.WriteFile(reader.GetCachePath("fixture"), stale, 0644); err != nil {
+		t.Fatalf("Failed to rewrite cache file: %v", err)
+	}
+	fresh, _ := cache.NewLibOpenAPIFileSystemCacheStore(store.CacheDir)
+	if _, err := fresh.Get("fixture"); err == nil {
+		t.Errorf("Expected outdated cache entry to be rejected")
+	}
+}
+
+func TestSpecRevalidation(t *testing.T) {
+	raw, err := os.ReadFile("./fixtures/openapi.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read OpenAPI spec: %v", err)
+	}
+
+	const etag = `"v1"`
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	var mu sync.Mutex
+	var requests []*nethttp.Request
+	failing := false
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		mu.Lock()
+		requests = append(requests, r)
+		fail := failing
+		mu.Unlock()
+
+		switch {
+		case fail:
+			w.WriteHeader(nethttp.StatusInternalServerError)
+		case r.Header.Get("If-None-Match") == etag:
+			w.WriteHeader(nethttp.StatusNotModified)
+		default:
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Last-Modified", lastModified)
+			_, _ = w.Write(raw)
+		}
+	}))
+	defer server.Close()
+
+	manager, err := cache.NewLibOpenAPICacheManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create cache manager: %v", err)
+	}
+	specURL := server.URL + "/openapi.yaml"
+
+	// Cache the spec already expired, so the next load revalidates it
+	index, err := manager.GetSpec(specURL, cache.SpecOptions{TTL: -time.Second})
+	if err != nil {
+		t.Fatalf("Failed to load spec: %v", err)
+	}
+
+	// The expired spec is revalidated with its validators, and a 304 reuses the cached
+	// index without parsing the spec again
+	revalidated, err := manager.GetSpec(specURL, cache.SpecOptions{TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("Failed to revalidate spec: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+	if got := requests[1].Header.Get("If-None-Match"); got != etag {
+		t.Errorf("Expected If-None-Match %s, got %q", etag, got)
+	}
+	if got := requests[1].Header.Get("If-Modified-Since"); got != lastModified {
+		t.Errorf("Expected If-Modified-Since %s, got %q", lastModified, got)
+	}
+	if revalidated != index {
+		t.Errorf("Expected the cached index to be reused after a 304")
+	}
+
+	// The 304 extended the cached spec, so it is used without a request
+	if _, err := manager.GetSpec(specURL, cache.SpecOptions{TTL: time.Hour}); err != nil {
+		t.Fatalf("Failed to load cached spec: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Errorf("Expected the extended spec to be used from the cache, got %d requests", len(requests))
+	}
+
+	// When the origin fails, an expired spec is only used with stale_if_error
+	staleURL := server.URL + "/stale.yaml"
+	if _, err := manager.GetSpec(staleURL, cache.SpecOptions{TTL: -time.Second}); err != nil {
+		t.Fatalf("Failed to load spec: %v", err)
+	}
+	mu.Lock()
+	failing = true
+	mu.Unlock()
+	if _, err := manager.GetSpec(staleURL, cache.SpecOptions{TTL: time.Hour}); err == nil {
+		t.Errorf("Expected an error when the origin fails without stale_if_error")
+	}
+	stale, err := manager.GetSpec(staleURL, cache.SpecOptions{TTL: time.Hour, StaleIfError: true})
+	if err != nil || stale == nil || len(stale.Endpoints) == 0 {
+		t.Errorf("Expected the stale spec to be used, got %v (%v)", stale, err)
+	}
+}
+
+func TestPathItemParameters(t *testing.T) {
+	raw, err := os.ReadFile("./fixtures/openapi.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read OpenAPI spec: %v", err)
+	}
+	index, err := cache.BuildLibOpenAPISpecIndex(raw, "./fixtures/openapi.yaml")
+	if err != nil {
+		t.Fatalf("Failed to build spec index: %v", err)
+	}
+
+	// The parameters of the path are inherited, and the operation's override them
+	var params []openapi.Parameter
+	for _, endpoint := range index.Endpoints {
+		if endpoint.OperationID == "listUserPosts" {
+			params = endpoint.Parameters
+		}
+	}
+	if len(params) != 2 {
+		t.Fatalf("Expected the path and operation parameters, got %+v", params)
+	}
+	if params[0].Name != "id" || params[0].In != "path" || !params[0].Required {
+		t.Errorf("Expected the path parameter to be inherited, got %+v", params[0])
+	}
+	if params[1].Name != "limit" || params[1].Schema == nil || params[1].Schema.Maximum == nil || *params[1].Schema.Maximum != 50 {
+		t.Errorf("Expected the operation's parameter to override the path's, got %+v", params[1])
+	}
+}
+
+func TestSwaggerSpecIndex(t *testing.T) {
+	raw, err := os.ReadFile("./fixtures/swagger.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read Swagger spec: %v", err)
+	}
+
+	version, err := openapi.NewVersionDetector().DetectVersionFromBytes(raw)
+	if err != nil || version != openapi.OpenAPIV20 {
+		t.Fatalf("Expected Swagger 2.0, got %q (%v)", version, err)
+	}
+	if version, err := openapi.NewVersionDetector().DetectVersionFromBytes([]byte("swagger: 2.0\n")); err != nil || version != openapi.OpenAPIV20 {
+		t.Errorf("Expected an unquoted version to be Swagger 2.0, got %q (%v)", version, err)
+	}
+
+	index, err := cache.BuildLibOpenAPISpecIndex(raw, "./fixtures/swagger.yaml")
+	if err != nil {
+		t.Fatalf("Failed to build spec index: %v", err)
+	}
+
+	// Servers come from the host, base path and schemes
+	if len(index.Servers) != 2 || index.Servers[0].URL != "https://petstore.example.com/v2" || index.Servers[1].URL != "http://petstore.example.com/v2" {
+		t.Errorf("Unexpected servers: %+v", index.Servers)
+	}
+
+	endpoints := map[string]openapi.Endpoint{}
+	for _, endpoint := range index.Endpoints {
+		endpoints[endpoint.OperationID] = endpoint
+	}
+
+	// Array parameters are serialized as their collection format describes, and path
+	// parameters are inherited from the path
+	list := endpoints["listPets"]
+	if len(list.Parameters) != 3 || *list.Parameters[0].Explode != true || list.Parameters[1].Style != "pipeDelimited" {
+		t.Errorf("Unexpected parameters: %+v", list.Parameters)
+	}
+	if header := list.Responses["200"].Headers["X-Rate-Limit"]; header == nil || header.Type != "integer" || header.Default != 100 {
+		t.Errorf("Unexpected response header: %+v", header)
+	}
+	if params := endpoints["deletePet"].Parameters; len(params) != 2 || params[0].Name != "petId" || params[0].In != "path" {
+		t.Errorf("Expected the path parameter to be inherited, got %+v", params)
+	}
+	if servers := endpoints["deletePet"].Servers; len(servers) != 1 || servers[0].URL != "http://petstore.example.com/v2" {
+		t.Errorf("Expected the operation's scheme to be its server, got %+v", servers)
+	}
+
+	// Body parameters are the request body for the consumed media types
+	body := endpoints["addPet"].RequestBody
+	if body == nil || !body.Required || len(body.Content) != 2 || body.Content["application/xml"].Schema.XMLName != "Pet" {
+		t.Fatalf("Unexpected request body: %+v", body)
+	}
+	if types := endpoints["getPet"].SuccessContentTypes(); len(types) != 2 {
+		t.Errorf("Expected the produced media types, got %v", types)
+	}
+
+	// Form parameters are a multipart form when they upload a file, and URL-encoded otherwise
+	upload := endpoints["uploadPhoto"].RequestBody
+	if upload == nil || upload.Content["multipart/form-data"] == nil || upload.Content["multipart/form-data"].Schema.Properties["file"].Format != "binary" {
+		t.Errorf("Unexpected upload body: %+v", upload)
+	}
+	if rename := endpoints["renamePet"].RequestBody; rename == nil || rename.Content["application/x-www-form-urlencoded"] == nil {
+		t.Errorf("Unexpected form body: %+v", rename)
+	}
+
+	// Security definitions become security schemes
+	if scheme := index.SecuritySchemes["basicAuth"]; scheme == nil || scheme.Type != "http" || scheme.Scheme != "basic" {
+		t.Errorf("Unexpected basic scheme: %+v", scheme)
+	}
+	oauth := index.SecuritySchemes["petstore_auth"]
+	if oauth == nil || oauth.Flows == nil || oauth.Flows.AuthorizationCode == nil || oauth.Flows.AuthorizationCode.TokenURL != "https://petstore.example.com/oauth/token" {
+		t.Errorf("Unexpected OAuth2 scheme: %+v", oauth)
+	}
+	security := endpoints["getPet"].Security
+	if len(security) != 1 {
+		t.Fatalf("Expected the document's security, got %v", security)
+	}
+	if _, ok := security[0]["api_key"]; !ok {
+		t.Errorf("Expected the api_key scheme, got %v", security)
+	}
+
+	// Without a host and base path, the API is served from the root of the spec's host
+	hostless, err := cache.BuildLibOpenAPISpecIndex([]byte("swagger: \"2.0\"\ninfo: {title: t, version: \"1\"}\npaths: {}\n"), "https://example.com/specs/swagger.yaml")
+	if err != nil {
+		t.Fatalf("Failed to build spec index: %v", err)
+	}
+	if len(hostless.Servers) != 1 || hostless.Servers[0].URL != "/" {
+		t.Fatalf("Expected the root server, got %+v", hostless.Servers)
+	}
+	if url, err := openapi.ResolveServerURL(hostless.Servers[0].URL, "https://example.com/specs/swagger.yaml"); err != nil || url != "https://example.com/" {
+		t.Errorf("Expected https://example.com/, got %q (%v)", url, err)
+	}
+}
+
+func TestSelectServer(t *testing.T) {
+	servers := []openapi.Server{
+		{
+			URL:         "https://{region}.api.example.com/{version}",
+			Description: "Production",
+			Variables: map[string]openapi.ServerVariable{
+				"region":  {Default: "us", Enum: []string{"us", "eu"}},
+				"version": {Default: "v1"},
+			},
+		},
+		{URL: "https://staging.example.com", Description: "Staging server"},
+	}
+
+	tests := []struct {
+		selector string
+		vars     map[string]string
+		expected string
+		wantErr  bool
+	}{
+		{selector: "", expected: "https://us.api.example.com/v1"},
+		{selector: "0", vars: map[string]string{"region": "eu", "version": "v2"}, expected: "https://eu.api.example.com/v2"},
+		{selector: "1", expected: "https://staging.example.com"},
+		{selector: "staging", expected: "https://staging.example.com"},
+		{selector: "production", expected: "https://us.api.example.com/v1"},
+		{selector: "2", wantErr: true},
+		{selector: "dev", wantErr: true},
+		{selector: "0", vars: map[string]string{"region": "ap"}, wantErr: true},
+		{selector: "1", vars: map[string]string{"region": "eu"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		server, err := openapi.SelectServer(servers, tt.selector)
+		var url string
+		if err == nil {
+			url, err = server.Expand(tt.vars)
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("server %q with %v: error = %v, wantErr %v", tt.selector, tt.vars, err, tt.wantErr)
+			continue
+		}
+		if url != tt.expected {
+			t.Errorf("server %q with %v: got %q, expected %q", tt.selector, tt.vars, url, tt.expected)
+		}
+	}
+
+	// Relative server URLs are resolved against a remote spec
+	url, err := openapi.ResolveServerURL("/v2", "https://example.com/specs/openapi.yaml")
+	if err != nil || url != "https://example.com/v2" {
+		t.Errorf("Expected https://example.com/v2, got %q (%v)", url, err)
+	}
+	if _, err := openapi.ResolveServerURL("/v2", "./openapi.yaml"); err == nil {
+		t.Errorf("Expected an error for a relative server URL of a local spec")
+	}
+	if url, err := openapi.ResolveServerURL("//example.com/v2", "./openapi.yaml"); err != nil || url != "https://example.com/v2" {
+		t.Errorf("Expected https://example.com/v2, got %q (%v)", url, err)
+	}
+}
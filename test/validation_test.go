@@ -0,0 +1,186 @@
+package test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/fynxlabs/ontap/internal/pkg/openapi"
+	"github.com/fynxlabs/ontap/internal/pkg/validation"
+)
+
+func TestValidateValue(t *testing.T) {
+	minLength := uint64(2)
+	maximum := 10.0
+	schema := &openapi.Schema{
+		Type:     "object",
+		Required: []string{"name", "address"},
+		Properties: map[string]*openapi.Schema{
+			"name":  {Type: "string", MinLength: &minLength},
+			"count": {Type: "integer", Maximum: &maximum},
+			"kind":  {Type: "string", Enum: []interface{}{"cat", "dog"}},
+			"nick":  {Type: "string", Nullable: true},
+			"tags":  {Type: "array", Items: &openapi.Schema{Type: "integer"}},
+			"address": {
+				Type:       "object",
+				Required:   []string{"city"},
+				Properties: map[string]*openapi.Schema{"city": {Type: "string"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "valid",
+			body: `{"name":"bob","count":3,"kind":"cat","nick":null,"tags":[1,2],"address":{"city":"Berlin"}}`,
+		},
+		{
+			name: "invalid",
+			body: `{"name":"b","count":11,"kind":"cow","tags":[1,"x"],"address":{}}`,
+			want: []string{
+				"/body/address/city",
+				"/body/count",
+				"/body/kind",
+				"/body/name",
+				"/body/tags/1",
+			},
+		},
+		{
+			name: "missing",
+			body: `{}`,
+			want: []string{"/body/name", "/body/address"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body interface{}
+			if err := json.Unmarshal([]byte(tt.body), &body); err != nil {
+				t.Fatalf("Failed to parse body: %v", err)
+			}
+
+			errs := validation.ValidateValue("/body", body, schema)
+			if len(errs) != len(tt.want) {
+				t.Fatalf("Expected %d errors, got %v", len(tt.want), errs)
+			}
+			for i, err := range errs {
+				if err.Pointer != tt.want[i] {
+					t.Errorf("Expected error at %s, got %s", tt.want[i], err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateParameter(t *testing.T) {
+	minimum := 1.0
+	param := openapi.Parameter{
+		Name:     "ids",
+		In:       "query",
+		Required: true,
+		Schema:   &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "integer", Minimum: &minimum}},
+	}
+
+	if errs := validation.ValidateParameter(param, []string{"1", "2"}); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+
+	errs := validation.ValidateParameter(param, []string{"1", "x", "0"})
+	if len(errs) != 1 || errs[0].Pointer != "/query/ids/1" {
+		t.Errorf("Expected a single error at /query/ids/1, got %v", errs)
+	}
+
+	errs = validation.ValidateParameter(param, nil)
+	if len(errs) != 1 || errs[0].Message != "missing required parameter" {
+		t.Errorf("Expected a missing parameter error, got %v", errs)
+	}
+}
+
+func TestValidatePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		values   map[string]string
+		pointers []string
+	}{
+		{path: "/items", pointers: nil},
+		{path: "/items/{id}", values: map[string]string{"id": "5"}, pointers: nil},
+		{path: "/items/{id}", pointers: []string{"/path/id"}},
+		{path: "/users/{user}/items/{id}", values: map[string]string{"user": "a"}, pointers: []string{"/path/id"}},
+		{path: "/files/{dir}/{name}.{ext}", values: map[string]string{"dir": "a"}, pointers: []string{"/path/name", "/path/ext"}},
+	}
+
+	for _, tt := range tests {
+		var pointers []string
+		for _, err := range validation.ValidatePath(tt.path, tt.values) {
+			pointers = append(pointers, err.Pointer)
+		}
+		if !reflect.DeepEqual(pointers, tt.pointers) {
+			t.Errorf("%s with %v: expected errors at %v, got %v", tt.path, tt.values, tt.pointers, pointers)
+		}
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	object := func(required string, propType string) *openapi.Schema {
+		return &openapi.Schema{
+			Type:       "object",
+			Required:   []string{required},
+			Properties: map[string]*openapi.Schema{required: {Type: propType}},
+		}
+	}
+	responses := map[string]openapi.Response{
+		"200":     {Content: map[string]*openapi.MediaType{"application/json": {Schema: object("id", "integer")}}},
+		"204":     {},
+		"404":     {Content: map[string]*openapi.MediaType{"application/json": {Schema: &openapi.Schema{Type: "array"}}}},
+		"4XX":     {Content: map[string]*openapi.MediaType{"application/problem+json": {Schema: object("title", "string")}}},
+		"default": {Content: map[string]*openapi.MediaType{"application/*": {Schema: object("code", "integer")}}},
+	}
+
+	tests := []struct {
+		name        string
+		responses   map[string]openapi.Response
+		status      int
+		contentType string
+		body        string
+		want        []string
+	}{
+		{name: "valid", status: 200, contentType: "application/json; charset=utf-8", body: `{"id":1}`},
+		{name: "invalid body", status: 200, contentType: "application/json", body: `{"id":"x"}`, want: []string{"/body/id"}},
+		{name: "invalid JSON", status: 200, contentType: "application/json", body: `{`, want: []string{"/body"}},
+		{name: "undeclared content type", status: 200, contentType: "text/html", body: `<p>`, want: []string{"/content-type"}},
+		{name: "no content", status: 204},
+		{name: "exact code before range", status: 404, contentType: "application/json", body: `[]`},
+		{name: "range", status: 400, contentType: "application/problem+json", body: `{}`, want: []string{"/body/title"}},
+		{name: "default with wildcard", status: 503, contentType: "application/vnd.error+json", body: `{}`, want: []string{"/body/code"}},
+		{name: "wildcard without JSON", status: 503, contentType: "application/xml", body: `<error/>`},
+		{name: "wildcard mismatch", status: 503, contentType: "text/plain", body: `error`, want: []string{"/content-type"}},
+		{
+			name:      "undeclared status",
+			responses: map[string]openapi.Response{"200": {}},
+			status:    302,
+			want:      []string{"/status"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.responses == nil {
+				tt.responses = responses
+			}
+
+			errs := validation.ValidateResponse(tt.responses, tt.status, tt.contentType, []byte(tt.body))
+			if len(errs) != len(tt.want) {
+				t.Fatalf("Expected %d errors, got %v", len(tt.want), errs)
+			}
+			for i, err := range errs {
+				if err.Pointer != tt.want[i] {
+					t.Errorf("Expected error at %s, got %s", tt.want[i], err)
+				}
+			}
+		})
+	}
+}